@@ -5,6 +5,7 @@ go 1.23.3
 require (
 	github.com/go-toast/toast v0.0.0-20190211030409-01e6764cf0a4
 	golang.org/x/sys v0.33.0
+	golang.org/x/text v0.22.0
 )
 
 require github.com/nu7hatch/gouuid v0.0.0-20131221200532-179d4d0c4d8d // indirect
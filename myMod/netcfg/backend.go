@@ -0,0 +1,191 @@
+package netcfg
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Backend 网卡配置后端，每个方法对应一项网卡设置
+type Backend interface {
+	// Name 后端名称，如 netsh、nmcli
+	Name() string
+	// SetAddress 设置静态 IPv4 地址（带前缀长度），第一个为主地址，gateway 为空时不设置网关
+	SetAddress(adapter string, addrs []string, gateway string) error
+	// SetDHCP 通过 DHCP 获取 IPv4 地址
+	SetDHCP(adapter string) error
+	// SetDNS 按顺序设置 DNS 服务器，servers 为空时通过 DHCP 获取
+	SetDNS(adapter string, servers []string) error
+	// SetIPv6Auto 启用 IPv6 并通过 SLAAC 或 DHCPv6（dhcpv6 为 true）获取地址，清除静态 IPv6 地址
+	SetIPv6Auto(adapter string, dhcpv6 bool) error
+	// SetIPv6Address 启用 IPv6 并设置静态地址（带前缀长度），gateway 为空时不设置网关
+	SetIPv6Address(adapter string, addrs []string, gateway string) error
+	// SetIPv6DNS 按顺序设置 IPv6 DNS 服务器，servers 为空时自动获取
+	SetIPv6DNS(adapter string, servers []string) error
+	// DisableIPv6 在网卡上禁用 IPv6
+	DisableIPv6(adapter string) error
+	// AddRoute 添加静态路由，路由已存在时替换
+	AddRoute(adapter string, r Route) error
+	DeleteRoute(adapter string, r Route) error
+	SetMTU(adapter string, mtu int) error
+	SetMetric(adapter string, metric int) error
+	// SetAutoMetric 恢复自动跃点数
+	SetAutoMetric(adapter string) error
+	FlushDNS() error
+}
+
+// 内置后端
+const (
+	KindNetsh = "netsh" // Windows netsh
+	KindNmcli = "nmcli" // Linux NetworkManager
+)
+
+// DefaultKind 返回当前系统使用的后端
+func DefaultKind() string {
+	if runtime.GOOS == "windows" {
+		return KindNetsh
+	}
+	return KindNmcli
+}
+
+// New 按名称创建后端，命令由 exec 执行
+func New(kind string, exec Executor) (Backend, error) {
+	switch kind {
+	case KindNetsh:
+		return NewNetsh(exec), nil
+	case KindNmcli:
+		return NewNmcli(exec), nil
+	}
+	return nil, fmt.Errorf("未知的网卡配置后端: %s", kind)
+}
+
+// NewDryRun 创建只记录命令不执行的后端，应用配置后从 Recorder 取得执行计划
+func NewDryRun(kind string) (Backend, *Recorder, error) {
+	rec := &Recorder{}
+	b, err := New(kind, rec)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, rec, nil
+}
+
+// Preview 返回用 kind 后端应用 p 时将执行的命令，不执行任何命令
+func Preview(kind string, p Profile) ([]Command, error) {
+	b, rec, err := NewDryRun(kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := Apply(b, p); err != nil {
+		return nil, err
+	}
+	return rec.Commands(), nil
+}
+
+// ApplyError 应用配置方案的某一步失败
+type ApplyError struct {
+	Step string // 失败的步骤，如 "配置 DNS"
+	Err  error
+}
+
+func (e *ApplyError) Error() string {
+	return fmt.Sprintf("%s失败: %v", e.Step, e.Err)
+}
+
+func (e *ApplyError) Unwrap() error { return e.Err }
+
+// Apply 检查配置方案并通过后端应用到网卡，某一步失败时返回 *ApplyError，后续步骤不再执行
+func Apply(b Backend, p Profile) error {
+	if err := p.Validate(); err != nil {
+		return &ApplyError{Step: "检查配置", Err: err}
+	}
+	step := func(name string, err error) error {
+		if err != nil {
+			return &ApplyError{Step: name, Err: err}
+		}
+		return nil
+	}
+
+	if p.DHCP {
+		if err := step("配置 DHCP", b.SetDHCP(p.Adapter)); err != nil {
+			return err
+		}
+		// DHCP 模式下 DNS 可自动获取，也可手动指定
+		if p.DNSdhcp {
+			if err := step("配置 DNS 自动获取", b.SetDNS(p.Adapter, nil)); err != nil {
+				return err
+			}
+		} else if len(p.DNS) > 0 {
+			if err := step("配置 DNS", b.SetDNS(p.Adapter, p.DNS)); err != nil {
+				return err
+			}
+		}
+	} else {
+		primary, err := CIDR(p.IP, p.Netmask)
+		if err != nil {
+			return &ApplyError{Step: "配置静态 IP", Err: err}
+		}
+		addrs := append([]string{primary}, p.Addresses...)
+		if err := step("配置静态 IP", b.SetAddress(p.Adapter, addrs, p.Gateway)); err != nil {
+			return err
+		}
+		if len(p.DNS) > 0 {
+			if err := step("配置 DNS", b.SetDNS(p.Adapter, p.DNS)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := applyIPv6(b, p); err != nil {
+		return err
+	}
+
+	for _, r := range p.Routes {
+		if err := step("添加路由 "+r.String(), b.AddRoute(p.Adapter, r)); err != nil {
+			return err
+		}
+	}
+
+	if p.MTU > 0 {
+		if err := step("配置 MTU", b.SetMTU(p.Adapter, p.MTU)); err != nil {
+			return err
+		}
+	}
+	if p.Metric > 0 {
+		if err := step("配置 Metric", b.SetMetric(p.Adapter, p.Metric)); err != nil {
+			return err
+		}
+	}
+	if p.FlushDNS {
+		if err := step("清除 DNS 缓存", b.FlushDNS()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 应用 IPv6 设置，IPv6Mode 为空时不修改
+func applyIPv6(b Backend, p Profile) error {
+	var err error
+	switch p.IPv6Mode {
+	case "":
+		return nil
+	case IPv6Disabled:
+		if err := b.DisableIPv6(p.Adapter); err != nil {
+			return &ApplyError{Step: "禁用 IPv6", Err: err}
+		}
+		return nil
+	case IPv6Static:
+		err = b.SetIPv6Address(p.Adapter, p.IPv6, p.IPv6Gateway)
+	default:
+		err = b.SetIPv6Auto(p.Adapter, p.IPv6Mode == IPv6DHCPv6)
+	}
+	if err != nil {
+		return &ApplyError{Step: "配置 IPv6 地址", Err: err}
+	}
+	// 静态模式未指定 DNS 时保留原有设置，自动模式下为空表示自动获取
+	if len(p.IPv6DNS) > 0 || p.IPv6Mode != IPv6Static {
+		if err := b.SetIPv6DNS(p.Adapter, p.IPv6DNS); err != nil {
+			return &ApplyError{Step: "配置 IPv6 DNS", Err: err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package netcfg
+
+import (
+	"errors"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+)
+
+// 命令行程序按系统 OEM 代码页输出，如中文 Windows 上为 GBK，为 nil 时不转换
+var oemEncoding encoding.Encoding = systemEncoding()
+
+// 将命令输出转换为 UTF-8，已是合法 UTF-8 或无法解码时原样返回
+func decodeOutput(s string) string {
+	if oemEncoding == nil || utf8.ValidString(s) {
+		return s
+	}
+	decoded, err := oemEncoding.NewDecoder().String(s)
+	if err != nil {
+		return s
+	}
+	return decoded
+}
+
+// 转换命令输出及错误中的命令输出
+func decodeResult(output string, err error) (string, error) {
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		cmdErr.Output = decodeOutput(cmdErr.Output)
+	}
+	return decodeOutput(output), err
+}
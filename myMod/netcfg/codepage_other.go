@@ -0,0 +1,10 @@
+//go:build !windows
+
+package netcfg
+
+import "golang.org/x/text/encoding"
+
+// 其他系统的命令输出为 UTF-8
+func systemEncoding() encoding.Encoding {
+	return nil
+}
@@ -0,0 +1,38 @@
+package netcfg
+
+import (
+	"syscall"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+var procGetOEMCP = syscall.NewLazyDLL("kernel32.dll").NewProc("GetOEMCP")
+
+// 系统 OEM 代码页对应的编码，UTF-8 或未知代码页返回 nil
+func systemEncoding() encoding.Encoding {
+	cp, _, _ := procGetOEMCP.Call()
+	switch cp {
+	case 936:
+		return simplifiedchinese.GBK
+	case 54936:
+		return simplifiedchinese.GB18030
+	case 950:
+		return traditionalchinese.Big5
+	case 932:
+		return japanese.ShiftJIS
+	case 949:
+		return korean.EUCKR
+	case 437:
+		return charmap.CodePage437
+	case 850:
+		return charmap.CodePage850
+	case 866:
+		return charmap.CodePage866
+	}
+	return nil
+}
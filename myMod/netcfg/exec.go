@@ -0,0 +1,117 @@
+package netcfg
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Command 一条系统命令
+type Command struct {
+	Name string
+	Args []string
+}
+
+func cmd(name string, args ...string) Command {
+	return Command{Name: name, Args: args}
+}
+
+// String 返回可读的命令行，含空格的参数加引号
+func (c Command) String() string {
+	parts := []string{c.Name}
+	for _, arg := range c.Args {
+		if arg == "" || strings.ContainsAny(arg, " \t\"") {
+			arg = strconv.Quote(arg)
+		}
+		parts = append(parts, arg)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Executor 执行系统命令，返回命令输出
+type Executor interface {
+	Run(c Command) (string, error)
+}
+
+// ExecRunner 直接执行系统命令
+type ExecRunner struct{}
+
+func (ExecRunner) Run(c Command) (string, error) {
+	output, err := exec.Command(c.Name, c.Args...).CombinedOutput()
+	if err != nil {
+		return string(output), &CommandError{Cmd: c, Output: string(output), Err: err}
+	}
+	return string(output), nil
+}
+
+// CommandError 命令执行失败
+type CommandError struct {
+	Cmd    Command
+	Output string // 命令输出，通常包含失败原因
+	Err    error
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("执行 %s 失败: %v", e.Cmd, e.Err)
+}
+
+func (e *CommandError) Unwrap() error { return e.Err }
+
+// Output 返回错误链中命令的输出，不是命令执行失败时返回空字符串
+func Output(err error) string {
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.Output
+	}
+	return ""
+}
+
+// Recorder 只记录命令不执行，用于预览执行计划和测试。
+// Respond 不为空时由它返回命令的输出和错误，可模拟命令失败。
+type Recorder struct {
+	Respond func(c Command) (string, error)
+
+	lock     sync.Mutex
+	commands []Command
+}
+
+func (r *Recorder) Run(c Command) (string, error) {
+	r.lock.Lock()
+	r.commands = append(r.commands, c)
+	respond := r.Respond
+	r.lock.Unlock()
+	if respond == nil {
+		return "", nil
+	}
+	output, err := respond(c)
+	if err != nil {
+		return output, &CommandError{Cmd: c, Output: output, Err: err}
+	}
+	return output, nil
+}
+
+// Commands 返回已记录的命令
+func (r *Recorder) Commands() []Command {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]Command(nil), r.commands...)
+}
+
+// Plan 返回已记录的命令，每行一条
+func (r *Recorder) Plan() string {
+	var lines []string
+	for _, c := range r.Commands() {
+		lines = append(lines, c.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Reset 清空已记录的命令
+func (r *Recorder) Reset() {
+	r.lock.Lock()
+	r.commands = nil
+	r.lock.Unlock()
+}
@@ -0,0 +1,312 @@
+package netcfg
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func plan(t *testing.T, kind string, p Profile) string {
+	t.Helper()
+	cmds, err := Preview(kind, p)
+	if err != nil {
+		t.Fatalf("Preview(%s): %v", kind, err)
+	}
+	var lines []string
+	for _, c := range cmds {
+		lines = append(lines, c.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func staticProfile() Profile {
+	return Profile{
+		Name:     "office",
+		Adapter:  "以太网 2",
+		IP:       "192.168.1.10",
+		Netmask:  "255.255.255.0",
+		Gateway:  "192.168.1.1",
+		DNS:      []string{"223.5.5.5", "114.114.114.114"},
+		MTU:      1400,
+		Metric:   10,
+		FlushDNS: true,
+	}
+}
+
+func TestNetshPlanStatic(t *testing.T) {
+	want := strings.Join([]string{
+		`netsh interface ip set address "name=以太网 2" static 192.168.1.10 255.255.255.0 192.168.1.1`,
+		`netsh interface ip set dns "name=以太网 2" static 223.5.5.5 primary`,
+		`netsh interface ip add dns "name=以太网 2" 114.114.114.114 index=2`,
+		`netsh interface ipv4 set subinterface "以太网 2" mtu=1400 store=persistent`,
+		`netsh interface ipv4 set interface "interface=以太网 2" metric=10 store=persistent`,
+		`ipconfig /flushdns`,
+	}, "\n")
+	if got := plan(t, KindNetsh, staticProfile()); got != want {
+		t.Fatalf("plan:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestNmcliPlan(t *testing.T) {
+	want := strings.Join([]string{
+		`nmcli device modify "以太网 2" ipv4.method manual ipv4.addresses 192.168.1.10/24 ipv4.gateway 192.168.1.1`,
+		`nmcli device modify "以太网 2" ipv4.dns 223.5.5.5,114.114.114.114 ipv4.ignore-auto-dns yes`,
+		`ip link set dev "以太网 2" mtu 1400`,
+		`nmcli device modify "以太网 2" ipv4.route-metric 10`,
+		`resolvectl flush-caches`,
+	}, "\n")
+	if got := plan(t, KindNmcli, staticProfile()); got != want {
+		t.Fatalf("plan:\n%s\nwant:\n%s", got, want)
+	}
+
+	p := Profile{Adapter: "eth0", DHCP: true, DNSdhcp: true}
+	want = strings.Join([]string{
+		`nmcli device modify eth0 ipv4.method auto ipv4.addresses "" ipv4.gateway ""`,
+		`nmcli device modify eth0 ipv4.dns "" ipv4.ignore-auto-dns no`,
+	}, "\n")
+	if got := plan(t, KindNmcli, p); got != want {
+		t.Fatalf("plan:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestIPv6Plan(t *testing.T) {
+	p := Profile{
+		Adapter:     "eth0",
+		DHCP:        true,
+		IPv6Mode:    IPv6Static,
+		IPv6:        []string{"2001:db8::10/64"},
+		IPv6Gateway: "fe80::1",
+		IPv6DNS:     []string{"2001:4860:4860::8888", "2400:3200::1"},
+	}
+	want := strings.Join([]string{
+		`netsh interface ip set address name=eth0 source=dhcp`,
+		`powershell -NoProfile -Command "Enable-NetAdapterBinding -Name 'eth0' -ComponentID ms_tcpip6"`,
+		`powershell -NoProfile -Command "Remove-NetIPAddress -InterfaceAlias 'eth0' -AddressFamily IPv6 -PrefixOrigin Manual -Confirm:$false -ErrorAction SilentlyContinue"`,
+		`netsh interface ipv6 set interface interface=eth0 routerdiscovery=disabled managedaddress=disabled otherstateful=disabled store=persistent`,
+		`netsh interface ipv6 add address interface=eth0 address=2001:db8::10/64 store=persistent`,
+		`netsh interface ipv6 delete route ::/0 interface=eth0`,
+		`netsh interface ipv6 add route ::/0 interface=eth0 nexthop=fe80::1 store=persistent`,
+		`netsh interface ipv6 set dnsservers name=eth0 static 2001:4860:4860::8888 primary`,
+		`netsh interface ipv6 add dnsservers name=eth0 2400:3200::1 index=2`,
+	}, "\n")
+	if got := plan(t, KindNetsh, p); got != want {
+		t.Fatalf("plan:\n%s\nwant:\n%s", got, want)
+	}
+
+	want = strings.Join([]string{
+		`nmcli device modify eth0 ipv4.method auto ipv4.addresses "" ipv4.gateway ""`,
+		`nmcli device modify eth0 ipv6.method manual ipv6.addresses 2001:db8::10/64 ipv6.gateway fe80::1`,
+		`nmcli device modify eth0 ipv6.dns 2001:4860:4860::8888,2400:3200::1 ipv6.ignore-auto-dns yes`,
+	}, "\n")
+	if got := plan(t, KindNmcli, p); got != want {
+		t.Fatalf("plan:\n%s\nwant:\n%s", got, want)
+	}
+
+	// 自动模式未指定 DNS 时自动获取
+	p = Profile{Adapter: "eth0", DHCP: true, IPv6Mode: IPv6DHCPv6}
+	want = strings.Join([]string{
+		`nmcli device modify eth0 ipv4.method auto ipv4.addresses "" ipv4.gateway ""`,
+		`nmcli device modify eth0 ipv6.method dhcp ipv6.addresses "" ipv6.gateway ""`,
+		`nmcli device modify eth0 ipv6.dns "" ipv6.ignore-auto-dns no`,
+	}, "\n")
+	if got := plan(t, KindNmcli, p); got != want {
+		t.Fatalf("plan:\n%s\nwant:\n%s", got, want)
+	}
+
+	p = Profile{Adapter: "eth0", DHCP: true, IPv6Mode: IPv6Disabled}
+	want = strings.Join([]string{
+		`netsh interface ip set address name=eth0 source=dhcp`,
+		`powershell -NoProfile -Command "Disable-NetAdapterBinding -Name 'eth0' -ComponentID ms_tcpip6"`,
+	}, "\n")
+	if got := plan(t, KindNetsh, p); got != want {
+		t.Fatalf("plan:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestAddressesAndRoutesPlan(t *testing.T) {
+	p := Profile{
+		Adapter:   "eth0",
+		IP:        "192.168.1.10",
+		Netmask:   "255.255.255.0",
+		Gateway:   "192.168.1.1",
+		Addresses: []string{"10.10.0.5/24"},
+		Routes: []Route{
+			{Dest: "10.0.0.0/8", Gateway: "10.10.0.1", Metric: 5},
+			{Dest: "172.16.0.0/12"},
+		},
+	}
+	want := strings.Join([]string{
+		`netsh interface ip set address name=eth0 static 192.168.1.10 255.255.255.0 192.168.1.1`,
+		`netsh interface ip add address name=eth0 10.10.0.5 255.255.255.0`,
+		`netsh interface ipv4 delete route prefix=10.0.0.0/8 interface=eth0 nexthop=10.10.0.1`,
+		`netsh interface ipv4 add route prefix=10.0.0.0/8 interface=eth0 nexthop=10.10.0.1 metric=5 store=persistent`,
+		`netsh interface ipv4 delete route prefix=172.16.0.0/12 interface=eth0`,
+		`netsh interface ipv4 add route prefix=172.16.0.0/12 interface=eth0 store=persistent`,
+	}, "\n")
+	if got := plan(t, KindNetsh, p); got != want {
+		t.Fatalf("plan:\n%s\nwant:\n%s", got, want)
+	}
+
+	want = strings.Join([]string{
+		`nmcli device modify eth0 ipv4.method manual ipv4.addresses 192.168.1.10/24,10.10.0.5/24 ipv4.gateway 192.168.1.1`,
+		`nmcli device modify eth0 +ipv4.routes "10.0.0.0/8 10.10.0.1 5"`,
+		`nmcli device modify eth0 +ipv4.routes "172.16.0.0/12 0.0.0.0"`,
+	}, "\n")
+	if got := plan(t, KindNmcli, p); got != want {
+		t.Fatalf("plan:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// 切换配置方案时删除上一个方案添加、新方案中没有的路由
+func TestSwitchRemovesStaleRoutes(t *testing.T) {
+	state := NewRouteState(filepath.Join(t.TempDir(), "state", "routes.json"))
+	vpn := Route{Dest: "10.0.0.0/8", Gateway: "192.168.1.254", Metric: 10}
+	lab := Route{Dest: "172.16.0.0/12", Gateway: "192.168.1.253"}
+	office := Profile{Adapter: "eth0", DHCP: true, Routes: []Route{vpn, lab}}
+	home := Profile{Adapter: "eth0", DHCP: true, Routes: []Route{lab}}
+
+	b, rec, _ := NewDryRun(KindNmcli)
+	if err := Switch(b, office, state); err != nil {
+		t.Fatal(err)
+	}
+	if routes, _ := state.Routes("eth0"); len(routes) != 2 {
+		t.Fatalf("recorded routes = %v", routes)
+	}
+
+	rec.Reset()
+	if err := Switch(b, home, state); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		`nmcli device modify eth0 -ipv4.routes "10.0.0.0/8 192.168.1.254 10"`,
+		`nmcli device modify eth0 ipv4.method auto ipv4.addresses "" ipv4.gateway ""`,
+		`nmcli device modify eth0 +ipv4.routes "172.16.0.0/12 192.168.1.253"`,
+	}, "\n")
+	if got := rec.Plan(); got != want {
+		t.Fatalf("plan:\n%s\nwant:\n%s", got, want)
+	}
+
+	rec.Reset()
+	if err := Switch(b, Profile{Adapter: "eth0", DHCP: true}, state); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(rec.Plan(), `nmcli device modify eth0 -ipv4.routes "172.16.0.0/12 192.168.1.253"`) {
+		t.Fatalf("plan:\n%s", rec.Plan())
+	}
+	if routes, _ := state.Routes("eth0"); len(routes) != 0 {
+		t.Fatalf("recorded routes = %v, want none", routes)
+	}
+}
+
+// 网卡已启用 DHCP 时 netsh 报错，改为禁用再启用网卡
+func TestNetshDHCPAlreadyEnabled(t *testing.T) {
+	rec := &Recorder{Respond: func(c Command) (string, error) {
+		if strings.Contains(c.String(), "source=dhcp") && c.Args[2] == "set" && c.Args[3] == "address" {
+			return "DHCP is already enabled on this interface.", errors.New("exit status 1")
+		}
+		return "", nil
+	}}
+	if err := Apply(NewNetsh(rec), Profile{Adapter: "WLAN", DHCP: true, DNSdhcp: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		`netsh interface ip set address name=WLAN source=dhcp`,
+		`netsh interface set interface name=WLAN admin=disable`,
+		`netsh interface set interface name=WLAN admin=enable`,
+		`netsh interface ip set dns name=WLAN source=dhcp`,
+	}, "\n")
+	if got := rec.Plan(); got != want {
+		t.Fatalf("plan:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// 中文 Windows 上 netsh 按 GBK 输出，转换为 UTF-8 后匹配提示信息，返回的命令输出也已转换
+func TestNetshDHCPAlreadyEnabledGBK(t *testing.T) {
+	old := oemEncoding
+	defer func() { oemEncoding = old }()
+	oemEncoding = simplifiedchinese.GBK
+	enabled, err := simplifiedchinese.GBK.NewEncoder().String("此接口上已启用 DHCP。")
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalid, err := simplifiedchinese.GBK.NewEncoder().String("DNS 服务器无效。")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := &Recorder{Respond: func(c Command) (string, error) {
+		switch {
+		case c.Args[2] == "set" && c.Args[3] == "address":
+			return enabled, errors.New("exit status 1")
+		case c.Args[2] == "set" && c.Args[3] == "dns":
+			return invalid, errors.New("exit status 1")
+		}
+		return "", nil
+	}}
+	err = Apply(NewNetsh(rec), Profile{Adapter: "WLAN", DHCP: true, DNS: []string{"223.5.5.5"}})
+	var applyErr *ApplyError
+	if !errors.As(err, &applyErr) || applyErr.Step != "配置 DNS" {
+		t.Fatalf("err = %v, want failure at the DNS step after re-enabling the adapter", err)
+	}
+	if got := Output(err); got != "DNS 服务器无效。" {
+		t.Fatalf("output = %q, want decoded message", got)
+	}
+	if plan := rec.Plan(); !strings.Contains(plan, "admin=disable") || !strings.Contains(plan, "admin=enable") {
+		t.Fatalf("adapter not re-enabled:\n%s", plan)
+	}
+}
+
+// 某一步失败时返回失败步骤和命令输出，后续步骤不再执行
+func TestApplyStopsAtFailedStep(t *testing.T) {
+	rec := &Recorder{Respond: func(c Command) (string, error) {
+		if c.Args[2] == "set" && c.Args[3] == "dns" {
+			return "The DNS server is invalid.", errors.New("exit status 1")
+		}
+		return "", nil
+	}}
+	err := Apply(NewNetsh(rec), staticProfile())
+	var applyErr *ApplyError
+	if !errors.As(err, &applyErr) || applyErr.Step != "配置 DNS" {
+		t.Fatalf("err = %v, want 配置 DNS failure", err)
+	}
+	if out := Output(err); out != "The DNS server is invalid." {
+		t.Fatalf("Output = %q", out)
+	}
+	if n := len(rec.Commands()); n != 2 {
+		t.Fatalf("ran %d commands, want 2", n)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	bad := map[string]Profile{
+		"no adapter":   {IP: "10.0.0.2", Netmask: "255.0.0.0"},
+		"bad ip":       {Adapter: "eth0", IP: "10.0.0", Netmask: "255.0.0.0"},
+		"bad netmask":  {Adapter: "eth0", IP: "10.0.0.2", Netmask: "255.0.255.0"},
+		"static dhcp":  {Adapter: "eth0", IP: "10.0.0.2", Netmask: "255.0.0.0", DNSdhcp: true},
+		"bad dns":      {Adapter: "eth0", DHCP: true, DNS: []string{"dns.example"}},
+		"mtu too high": {Adapter: "eth0", DHCP: true, MTU: 65536},
+		"ipv6 mode":    {Adapter: "eth0", DHCP: true, IPv6Mode: "auto"},
+		"ipv6 no mode": {Adapter: "eth0", DHCP: true, IPv6DNS: []string{"2400:3200::1"}},
+		"ipv6 prefix":  {Adapter: "eth0", DHCP: true, IPv6Mode: IPv6Static, IPv6: []string{"2001:db8::10"}},
+		"ipv6 v4 addr": {Adapter: "eth0", DHCP: true, IPv6Mode: IPv6Static, IPv6: []string{"10.0.0.1/8"}},
+		"ipv6 v4 dns":  {Adapter: "eth0", DHCP: true, IPv6Mode: IPv6SLAAC, IPv6DNS: []string{"8.8.8.8"}},
+		"slaac addr":   {Adapter: "eth0", DHCP: true, IPv6Mode: IPv6SLAAC, IPv6: []string{"2001:db8::10/64"}},
+		"dhcp alias":   {Adapter: "eth0", DHCP: true, Addresses: []string{"10.10.0.5/24"}},
+		"bad alias":    {Adapter: "eth0", IP: "10.0.0.2", Netmask: "255.0.0.0", Addresses: []string{"10.10.0.5"}},
+		"bad route":    {Adapter: "eth0", DHCP: true, Routes: []Route{{Dest: "10.0.0.0"}}},
+		"route family": {Adapter: "eth0", DHCP: true, Routes: []Route{{Dest: "10.0.0.0/8", Gateway: "fe80::1"}}},
+	}
+	for name, p := range bad {
+		if err := p.Validate(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if err := staticProfile().Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Preview(KindNetsh, bad["bad ip"]); err == nil {
+		t.Fatal("Preview should validate the profile")
+	}
+}
@@ -0,0 +1,210 @@
+package netcfg
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Netsh 通过 netsh、ipconfig 配置网卡的 Windows 后端，设置均持久保存
+type Netsh struct {
+	exec Executor
+}
+
+func NewNetsh(exec Executor) *Netsh {
+	return &Netsh{exec: exec}
+}
+
+func (n *Netsh) Name() string { return KindNetsh }
+
+// netsh 按系统 OEM 代码页输出，转换为 UTF-8 后再匹配提示信息和返回给调用方
+func (n *Netsh) run(c Command) (string, error) {
+	return decodeResult(n.exec.Run(c))
+}
+
+func (n *Netsh) SetAddress(adapter string, addrs []string, gateway string) error {
+	// set address 会替换网卡上原有的全部地址，其余地址再逐个添加
+	for i, addr := range addrs {
+		ip, netmask, err := splitCIDR(addr)
+		if err != nil {
+			return err
+		}
+		args := []string{"interface", "ip", "add", "address", "name=" + adapter, ip, netmask}
+		if i == 0 {
+			args = []string{"interface", "ip", "set", "address", "name=" + adapter, "static", ip, netmask}
+			if gateway != "" {
+				args = append(args, gateway)
+			}
+		}
+		if _, err := n.run(cmd("netsh", args...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// netsh 在已启用 DHCP 的网卡上再次设置 DHCP 会报错，此时禁用再启用网卡以重新获取地址
+var dhcpEnabledMessages = []string{
+	"DHCP is already enabled on this interface",
+	"此接口上已启用 DHCP",
+}
+
+func (n *Netsh) SetDHCP(adapter string) error {
+	output, err := n.run(cmd("netsh", "interface", "ip", "set", "address", "name="+adapter, "source=dhcp"))
+	if err == nil {
+		return nil
+	}
+	if !containsAny(output, dhcpEnabledMessages) {
+		return err
+	}
+	if _, err := n.run(cmd("netsh", "interface", "set", "interface", "name="+adapter, "admin=disable")); err != nil {
+		return err
+	}
+	_, err = n.run(cmd("netsh", "interface", "set", "interface", "name="+adapter, "admin=enable"))
+	return err
+}
+
+func (n *Netsh) SetDNS(adapter string, servers []string) error {
+	if len(servers) == 0 {
+		_, err := n.run(cmd("netsh", "interface", "ip", "set", "dns", "name="+adapter, "source=dhcp"))
+		return err
+	}
+	// 第一个为首选 DNS，其余按顺序追加
+	if _, err := n.run(cmd("netsh", "interface", "ip", "set", "dns", "name="+adapter, "static", servers[0], "primary")); err != nil {
+		return err
+	}
+	for i := 1; i < len(servers); i++ {
+		if _, err := n.run(cmd("netsh", "interface", "ip", "add", "dns", "name="+adapter, servers[i], "index="+strconv.Itoa(i+1))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 通过 PowerShell 执行网卡相关的 cmdlet，网卡名用单引号括起
+func (n *Netsh) powershell(script, adapter string) (string, error) {
+	quoted := "'" + strings.ReplaceAll(adapter, "'", "''") + "'"
+	return n.run(cmd("powershell", "-NoProfile", "-Command", strings.ReplaceAll(script, "$adapter", quoted)))
+}
+
+// 启用网卡的 IPv6 协议绑定，并删除手动配置的 IPv6 地址
+func (n *Netsh) resetIPv6(adapter string) error {
+	if _, err := n.powershell("Enable-NetAdapterBinding -Name $adapter -ComponentID ms_tcpip6", adapter); err != nil {
+		return err
+	}
+	_, err := n.powershell("Remove-NetIPAddress -InterfaceAlias $adapter -AddressFamily IPv6 -PrefixOrigin Manual -Confirm:$false -ErrorAction SilentlyContinue", adapter)
+	return err
+}
+
+func (n *Netsh) SetIPv6Auto(adapter string, dhcpv6 bool) error {
+	if err := n.resetIPv6(adapter); err != nil {
+		return err
+	}
+	managed := "disabled"
+	if dhcpv6 {
+		managed = "enabled"
+	}
+	_, err := n.run(cmd("netsh", "interface", "ipv6", "set", "interface", "interface="+adapter,
+		"routerdiscovery=enabled", "managedaddress="+managed, "otherstateful="+managed, "store=persistent"))
+	return err
+}
+
+func (n *Netsh) SetIPv6Address(adapter string, addrs []string, gateway string) error {
+	if err := n.resetIPv6(adapter); err != nil {
+		return err
+	}
+	if _, err := n.run(cmd("netsh", "interface", "ipv6", "set", "interface", "interface="+adapter,
+		"routerdiscovery=disabled", "managedaddress=disabled", "otherstateful=disabled", "store=persistent")); err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if _, err := n.run(cmd("netsh", "interface", "ipv6", "add", "address", "interface="+adapter, "address="+addr, "store=persistent")); err != nil {
+			return err
+		}
+	}
+	if gateway == "" {
+		return nil
+	}
+	// 默认路由不存在时删除会失败，忽略该错误
+	n.run(cmd("netsh", "interface", "ipv6", "delete", "route", "::/0", "interface="+adapter))
+	_, err := n.run(cmd("netsh", "interface", "ipv6", "add", "route", "::/0", "interface="+adapter, "nexthop="+gateway, "store=persistent"))
+	return err
+}
+
+func (n *Netsh) SetIPv6DNS(adapter string, servers []string) error {
+	if len(servers) == 0 {
+		_, err := n.run(cmd("netsh", "interface", "ipv6", "set", "dnsservers", "name="+adapter, "source=dhcp"))
+		return err
+	}
+	if _, err := n.run(cmd("netsh", "interface", "ipv6", "set", "dnsservers", "name="+adapter, "static", servers[0], "primary")); err != nil {
+		return err
+	}
+	for i := 1; i < len(servers); i++ {
+		if _, err := n.run(cmd("netsh", "interface", "ipv6", "add", "dnsservers", "name="+adapter, servers[i], "index="+strconv.Itoa(i+1))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *Netsh) DisableIPv6(adapter string) error {
+	_, err := n.powershell("Disable-NetAdapterBinding -Name $adapter -ComponentID ms_tcpip6", adapter)
+	return err
+}
+
+// 路由命令的公共参数
+func routeArgs(action, adapter string, r Route) []string {
+	family := "ipv4"
+	if r.IPv6() {
+		family = "ipv6"
+	}
+	args := []string{"interface", family, action, "route", "prefix=" + r.Dest, "interface=" + adapter}
+	if r.Gateway != "" {
+		args = append(args, "nexthop="+r.Gateway)
+	}
+	return args
+}
+
+func (n *Netsh) AddRoute(adapter string, r Route) error {
+	// 路由已存在时 add 会失败，先删除，删除失败（路由不存在）时忽略
+	n.run(cmd("netsh", routeArgs("delete", adapter, r)...))
+	args := routeArgs("add", adapter, r)
+	if r.Metric > 0 {
+		args = append(args, "metric="+strconv.Itoa(r.Metric))
+	}
+	_, err := n.run(cmd("netsh", append(args, "store=persistent")...))
+	return err
+}
+
+func (n *Netsh) DeleteRoute(adapter string, r Route) error {
+	_, err := n.run(cmd("netsh", routeArgs("delete", adapter, r)...))
+	return err
+}
+
+func (n *Netsh) SetMTU(adapter string, mtu int) error {
+	_, err := n.run(cmd("netsh", "interface", "ipv4", "set", "subinterface", adapter, "mtu="+strconv.Itoa(mtu), "store=persistent"))
+	return err
+}
+
+func (n *Netsh) SetMetric(adapter string, metric int) error {
+	_, err := n.run(cmd("netsh", "interface", "ipv4", "set", "interface", "interface="+adapter, "metric="+strconv.Itoa(metric), "store=persistent"))
+	return err
+}
+
+func (n *Netsh) SetAutoMetric(adapter string) error {
+	_, err := n.powershell("Set-NetIPInterface -InterfaceAlias $adapter -AddressFamily IPv4 -AutomaticMetric Enabled", adapter)
+	return err
+}
+
+func (n *Netsh) FlushDNS() error {
+	_, err := n.run(cmd("ipconfig", "/flushdns"))
+	return err
+}
+
+func containsAny(s string, subs []string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
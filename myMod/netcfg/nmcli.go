@@ -0,0 +1,120 @@
+package netcfg
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Nmcli 通过 NetworkManager（nmcli）配置网卡的 Linux 后端。
+// 设置通过 nmcli device modify 作用于网卡当前激活的连接，立即生效但不写入连接配置，
+// MTU 通过 ip link 设置，DNS 缓存通过 resolvectl 清除（systemd-resolved）。
+type Nmcli struct {
+	exec Executor
+}
+
+func NewNmcli(exec Executor) *Nmcli {
+	return &Nmcli{exec: exec}
+}
+
+func (n *Nmcli) Name() string { return KindNmcli }
+
+func (n *Nmcli) modify(adapter string, settings ...string) error {
+	args := append([]string{"device", "modify", adapter}, settings...)
+	_, err := n.exec.Run(cmd("nmcli", args...))
+	return err
+}
+
+func (n *Nmcli) SetAddress(adapter string, addrs []string, gateway string) error {
+	return n.modify(adapter,
+		"ipv4.method", "manual",
+		"ipv4.addresses", strings.Join(addrs, ","),
+		"ipv4.gateway", gateway)
+}
+
+func (n *Nmcli) SetDHCP(adapter string) error {
+	return n.modify(adapter,
+		"ipv4.method", "auto",
+		"ipv4.addresses", "",
+		"ipv4.gateway", "")
+}
+
+func (n *Nmcli) SetDNS(adapter string, servers []string) error {
+	if len(servers) == 0 {
+		return n.modify(adapter, "ipv4.dns", "", "ipv4.ignore-auto-dns", "no")
+	}
+	return n.modify(adapter, "ipv4.dns", strings.Join(servers, ","), "ipv4.ignore-auto-dns", "yes")
+}
+
+func (n *Nmcli) SetIPv6Auto(adapter string, dhcpv6 bool) error {
+	method := "auto"
+	if dhcpv6 {
+		method = "dhcp"
+	}
+	return n.modify(adapter,
+		"ipv6.method", method,
+		"ipv6.addresses", "",
+		"ipv6.gateway", "")
+}
+
+func (n *Nmcli) SetIPv6Address(adapter string, addrs []string, gateway string) error {
+	return n.modify(adapter,
+		"ipv6.method", "manual",
+		"ipv6.addresses", strings.Join(addrs, ","),
+		"ipv6.gateway", gateway)
+}
+
+func (n *Nmcli) SetIPv6DNS(adapter string, servers []string) error {
+	if len(servers) == 0 {
+		return n.modify(adapter, "ipv6.dns", "", "ipv6.ignore-auto-dns", "no")
+	}
+	return n.modify(adapter, "ipv6.dns", strings.Join(servers, ","), "ipv6.ignore-auto-dns", "yes")
+}
+
+func (n *Nmcli) DisableIPv6(adapter string) error {
+	return n.modify(adapter, "ipv6.method", "disabled")
+}
+
+// nmcli 的路由格式为 "目标网段 下一跳 跃点数"，直连路由的下一跳写作 0.0.0.0 或 ::
+func nmcliRoute(r Route) (setting, value string) {
+	setting, gateway := "ipv4.routes", "0.0.0.0"
+	if r.IPv6() {
+		setting, gateway = "ipv6.routes", "::"
+	}
+	if r.Gateway != "" {
+		gateway = r.Gateway
+	}
+	value = r.Dest + " " + gateway
+	if r.Metric > 0 {
+		value += " " + strconv.Itoa(r.Metric)
+	}
+	return setting, value
+}
+
+func (n *Nmcli) AddRoute(adapter string, r Route) error {
+	setting, value := nmcliRoute(r)
+	return n.modify(adapter, "+"+setting, value)
+}
+
+func (n *Nmcli) DeleteRoute(adapter string, r Route) error {
+	setting, value := nmcliRoute(r)
+	return n.modify(adapter, "-"+setting, value)
+}
+
+func (n *Nmcli) SetMTU(adapter string, mtu int) error {
+	_, err := n.exec.Run(cmd("ip", "link", "set", "dev", adapter, "mtu", strconv.Itoa(mtu)))
+	return err
+}
+
+func (n *Nmcli) SetMetric(adapter string, metric int) error {
+	return n.modify(adapter, "ipv4.route-metric", strconv.Itoa(metric))
+}
+
+// 跃点数为 -1 时由 NetworkManager 按网卡类型选择默认值
+func (n *Nmcli) SetAutoMetric(adapter string) error {
+	return n.modify(adapter, "ipv4.route-metric", "-1")
+}
+
+func (n *Nmcli) FlushDNS() error {
+	_, err := n.exec.Run(cmd("resolvectl", "flush-caches"))
+	return err
+}
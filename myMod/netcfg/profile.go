@@ -0,0 +1,221 @@
+// Package netcfg 网卡配置方案及其应用。
+//
+// 配置方案（Profile）由 xyrTools 的网络配置模块和 netSetService 共用，
+// 通过 Backend 应用到网卡，Backend 只负责生成并执行系统命令：
+//
+//	b := netcfg.NewNetsh(netcfg.ExecRunner{})
+//	err := netcfg.Apply(b, profile)
+//
+// 用 Recorder 代替 ExecRunner 时只记录命令不执行，可在应用前预览执行计划，也便于在任意系统上测试。
+package netcfg
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Profile 网卡配置方案，JSON 字段名即字段名，与网卡配置服务的管道协议一致
+type Profile struct {
+	Name    string `yaml:"name"`    // 配置名称 自定义
+	Desc    string `yaml:"desc"`    // 配置描述 自定义
+	Adapter string `yaml:"adapter"` // 网卡名称
+	DHCP    bool   `yaml:"dhcp"`    // 是否使用DHCP
+	DNSdhcp bool   `yaml:"dnsdhcp"` // 是否使用DHCP获取DNS
+	IP      string `yaml:"ip"`      // IP地址
+	Netmask string `yaml:"netmask"` // 子网掩码
+	Gateway string `yaml:"gateway"` // 网关
+
+	DNS      []string `yaml:"dns"`      // DNS服务器
+	MTU      int      `yaml:"mtu"`      // MTU大小，0 表示不修改
+	Metric   int      `yaml:"metric"`   // 跃点数，0 表示不修改
+	FlushDNS bool     `yaml:"flushDNS"` // 是否刷新DNS缓存
+
+	IPv6Mode    string   `yaml:"ipv6Mode"`    // IPv6 模式：slaac、dhcpv6、static、disabled，为空时不修改 IPv6 设置
+	IPv6        []string `yaml:"ipv6"`        // 静态 IPv6 地址，带前缀长度，如 2001:db8::10/64
+	IPv6Gateway string   `yaml:"ipv6Gateway"` // IPv6 网关
+	IPv6DNS     []string `yaml:"ipv6DNS"`     // IPv6 DNS服务器，slaac、dhcpv6 模式下为空时自动获取
+
+	Addresses []string `yaml:"addresses"` // 静态模式下的额外 IPv4 地址，带前缀长度，如 10.10.0.5/24
+	Routes    []Route  `yaml:"routes"`    // 静态路由，切换到其他配置方案时删除
+
+	Checks Checks `yaml:"checks"` // 应用后的连通性检查，由网卡配置服务执行，失败时恢复原配置
+}
+
+// Route 静态路由
+type Route struct {
+	Dest    string `yaml:"dest"`    // 目标网段，如 10.0.0.0/8、2001:db8:1::/48
+	Gateway string `yaml:"gateway"` // 下一跳，为空时为直连路由
+	Metric  int    `yaml:"metric"`  // 跃点数，0 表示使用默认值
+}
+
+func (r Route) String() string {
+	s := r.Dest
+	if r.Gateway != "" {
+		s += " via " + r.Gateway
+	}
+	if r.Metric > 0 {
+		s += fmt.Sprintf(" metric %d", r.Metric)
+	}
+	return s
+}
+
+// IPv6 目标网段是否为 IPv6
+func (r Route) IPv6() bool {
+	ip, _, err := net.ParseCIDR(r.Dest)
+	return err == nil && ip.To4() == nil
+}
+
+// IPv6 模式
+const (
+	IPv6SLAAC    = "slaac"    // 无状态自动配置
+	IPv6DHCPv6   = "dhcpv6"   // 有状态 DHCPv6
+	IPv6Static   = "static"   // 静态地址
+	IPv6Disabled = "disabled" // 在网卡上禁用 IPv6
+)
+
+// MTU 的合理范围
+const (
+	MinMTU = 576
+	MaxMTU = 9000
+)
+
+// Validate 检查配置方案的合法性，不检查网卡是否存在
+func (p Profile) Validate() error {
+	if p.Adapter == "" {
+		return errors.New("未指定网卡")
+	}
+	if !p.DHCP {
+		if p.DNSdhcp {
+			return errors.New("非法配置：静态 IP 模式下不能使用 DNS DHCP")
+		}
+		if ip := net.ParseIP(p.IP); ip == nil || ip.To4() == nil {
+			return fmt.Errorf("无效 IP 地址: %s", p.IP)
+		}
+		if _, err := PrefixLen(p.Netmask); err != nil {
+			return err
+		}
+		if p.Gateway != "" && net.ParseIP(p.Gateway) == nil {
+			return fmt.Errorf("无效网关: %s", p.Gateway)
+		}
+		for _, addr := range p.Addresses {
+			ip, _, err := net.ParseCIDR(addr)
+			if err != nil || ip.To4() == nil {
+				return fmt.Errorf("无效 IP 地址: %s（需带前缀长度，如 10.10.0.5/24）", addr)
+			}
+		}
+	} else if len(p.Addresses) > 0 {
+		return errors.New("非法配置：DHCP 模式下不能配置额外的 IP 地址")
+	}
+	for _, r := range p.Routes {
+		if err := r.validate(); err != nil {
+			return err
+		}
+	}
+	for _, dns := range p.DNS {
+		if net.ParseIP(dns) == nil {
+			return fmt.Errorf("无效 DNS: %s", dns)
+		}
+	}
+	if err := p.validateIPv6(); err != nil {
+		return err
+	}
+	if err := p.Checks.validate(p); err != nil {
+		return err
+	}
+	if p.MTU != 0 && (p.MTU < MinMTU || p.MTU > MaxMTU) {
+		return fmt.Errorf("MTU 不在合理范围: %d", p.MTU)
+	}
+	if p.Metric < 0 {
+		return fmt.Errorf("无效跃点数: %d", p.Metric)
+	}
+	return nil
+}
+
+func (p Profile) validateIPv6() error {
+	switch p.IPv6Mode {
+	case "", IPv6Disabled:
+		if len(p.IPv6) > 0 || p.IPv6Gateway != "" || len(p.IPv6DNS) > 0 {
+			return fmt.Errorf("IPv6 模式为 %q 时不能配置 IPv6 地址、网关或 DNS", p.IPv6Mode)
+		}
+		return nil
+	case IPv6SLAAC, IPv6DHCPv6:
+		if len(p.IPv6) > 0 || p.IPv6Gateway != "" {
+			return errors.New("只有 static 模式可以配置 IPv6 地址和网关")
+		}
+	case IPv6Static:
+		if len(p.IPv6) == 0 {
+			return errors.New("static 模式至少需要一个 IPv6 地址")
+		}
+		for _, addr := range p.IPv6 {
+			ip, _, err := net.ParseCIDR(addr)
+			if err != nil || ip.To4() != nil {
+				return fmt.Errorf("无效 IPv6 地址: %s（需带前缀长度，如 2001:db8::10/64）", addr)
+			}
+		}
+		if p.IPv6Gateway != "" && !isIPv6(p.IPv6Gateway) {
+			return fmt.Errorf("无效 IPv6 网关: %s", p.IPv6Gateway)
+		}
+	default:
+		return fmt.Errorf("未知的 IPv6 模式: %s", p.IPv6Mode)
+	}
+	for _, dns := range p.IPv6DNS {
+		if !isIPv6(dns) {
+			return fmt.Errorf("无效 IPv6 DNS: %s", dns)
+		}
+	}
+	return nil
+}
+
+func (r Route) validate() error {
+	ip, _, err := net.ParseCIDR(r.Dest)
+	if err != nil {
+		return fmt.Errorf("无效路由目标: %s（需带前缀长度，如 10.0.0.0/8）", r.Dest)
+	}
+	if r.Gateway != "" {
+		gw := net.ParseIP(r.Gateway)
+		if gw == nil || (gw.To4() == nil) != (ip.To4() == nil) {
+			return fmt.Errorf("无效路由网关: %s", r.Gateway)
+		}
+	}
+	if r.Metric < 0 {
+		return fmt.Errorf("无效路由跃点数: %d", r.Metric)
+	}
+	return nil
+}
+
+func isIPv6(s string) bool {
+	ip := net.ParseIP(s)
+	return ip != nil && ip.To4() == nil
+}
+
+// CIDR 将 IP 地址和子网掩码转换为带前缀长度的形式，如 192.168.1.10/24
+func CIDR(ip, netmask string) (string, error) {
+	prefix, err := PrefixLen(netmask)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%d", ip, prefix), nil
+}
+
+// 将带前缀长度的 IPv4 地址拆分为 IP 地址和点分十进制子网掩码
+func splitCIDR(addr string) (ip, netmask string, err error) {
+	parsed, ipnet, err := net.ParseCIDR(addr)
+	if err != nil || parsed.To4() == nil {
+		return "", "", fmt.Errorf("无效 IP 地址: %s", addr)
+	}
+	return parsed.String(), net.IP(ipnet.Mask).String(), nil
+}
+
+// PrefixLen 将点分十进制子网掩码转换为前缀长度，如 255.255.255.0 转换为 24
+func PrefixLen(netmask string) (int, error) {
+	ip := net.ParseIP(netmask).To4()
+	if ip == nil {
+		return 0, fmt.Errorf("无效子网掩码: %s", netmask)
+	}
+	ones, bits := net.IPMask(ip).Size()
+	if bits == 0 {
+		return 0, fmt.Errorf("无效子网掩码: %s", netmask)
+	}
+	return ones, nil
+}
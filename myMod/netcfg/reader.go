@@ -0,0 +1,286 @@
+package netcfg
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Reader 读取网卡当前配置，返回的配置方案填写网卡、IPv4 和 IPv6 相关字段（不含路由和检查），Name 由调用方设置
+type Reader interface {
+	Read(adapter string) (Profile, error)
+}
+
+// NewReader 按后端名称创建读取当前配置的 Reader，命令由 exec 执行
+func NewReader(kind string, exec Executor) (Reader, error) {
+	switch kind {
+	case KindNetsh:
+		return NewPowerShellReader(exec), nil
+	case KindNmcli:
+		return NewLinuxReader(exec), nil
+	}
+	return nil, fmt.Errorf("未知的网卡配置后端: %s", kind)
+}
+
+// FakeReader 按网卡名返回预先准备好的配置，用于测试
+type FakeReader map[string]Profile
+
+func (f FakeReader) Read(adapter string) (Profile, error) {
+	p, ok := f[adapter]
+	if !ok {
+		return Profile{}, fmt.Errorf("网卡 %s 不存在", adapter)
+	}
+	p.Adapter = adapter
+	return p, nil
+}
+
+// 将按顺序排列的 IPv4 地址（带前缀长度）填入配置方案，第一个为主地址
+func setAddresses(p *Profile, addrs []string) error {
+	for i, addr := range addrs {
+		if i > 0 {
+			p.Addresses = append(p.Addresses, addr)
+			continue
+		}
+		ip, netmask, err := splitCIDR(addr)
+		if err != nil {
+			return err
+		}
+		p.IP, p.Netmask = ip, netmask
+	}
+	return nil
+}
+
+// LinuxReader 通过 ip -j 读取地址、网关、MTU 和跃点数，通过 nmcli 读取 DNS。
+// 地址为动态分配（DHCP）时视为 DNS 也通过 DHCP 获取，DNS 列表仍记录当前生效的服务器。
+// 网卡没有 IPv6 地址时视为禁用 IPv6；有手动配置的全局 IPv6 地址时为 static，否则为 slaac
+// （NetworkManager 的自动配置，按路由通告决定是否使用 DHCPv6），自动配置时 IPv6 DNS 自动获取。
+type LinuxReader struct {
+	exec Executor
+}
+
+func NewLinuxReader(exec Executor) *LinuxReader {
+	return &LinuxReader{exec: exec}
+}
+
+// ip -j addr show 的输出
+type ipLink struct {
+	MTU      int `json:"mtu"`
+	AddrInfo []struct {
+		Family    string `json:"family"`
+		Local     string `json:"local"`
+		PrefixLen int    `json:"prefixlen"`
+		Scope     string `json:"scope"`
+		Dynamic   bool   `json:"dynamic"`
+	} `json:"addr_info"`
+}
+
+// ip -j route show default 的输出
+type ipRoute struct {
+	Gateway string `json:"gateway"`
+	Metric  int    `json:"metric"`
+}
+
+func (r *LinuxReader) Read(adapter string) (Profile, error) {
+	p := Profile{Adapter: adapter}
+	output, err := r.exec.Run(cmd("ip", "-j", "addr", "show", "dev", adapter))
+	if err != nil {
+		return p, err
+	}
+	var links []ipLink
+	if err := json.Unmarshal([]byte(output), &links); err != nil || len(links) == 0 {
+		return p, fmt.Errorf("解析网卡 %s 的地址失败: %v", adapter, err)
+	}
+	p.MTU = links[0].MTU
+	var addrs, addrs6 []string
+	hasIPv6 := false
+	for _, info := range links[0].AddrInfo {
+		if info.Family == "inet6" {
+			hasIPv6 = true
+			if info.Scope == "global" && !info.Dynamic {
+				addrs6 = append(addrs6, fmt.Sprintf("%s/%d", info.Local, info.PrefixLen))
+			}
+			continue
+		}
+		if info.Family != "inet" {
+			continue
+		}
+		if info.Dynamic {
+			p.DHCP = true
+		}
+		addrs = append(addrs, fmt.Sprintf("%s/%d", info.Local, info.PrefixLen))
+	}
+
+	output, err = r.exec.Run(cmd("ip", "-j", "route", "show", "default", "dev", adapter))
+	if err != nil {
+		return p, err
+	}
+	var routes []ipRoute
+	if err := json.Unmarshal([]byte(output), &routes); err != nil {
+		return p, fmt.Errorf("解析网卡 %s 的路由失败: %v", adapter, err)
+	}
+	if len(routes) > 0 {
+		p.Metric = routes[0].Metric
+		if !p.DHCP {
+			p.Gateway = routes[0].Gateway
+		}
+	}
+	if p.DHCP {
+		p.DNSdhcp = true
+	} else if err := setAddresses(&p, addrs); err != nil {
+		return p, err
+	}
+
+	if p.DNS, err = r.dns(adapter, "IP4.DNS"); err != nil {
+		return p, err
+	}
+
+	switch {
+	case !hasIPv6:
+		p.IPv6Mode = IPv6Disabled
+		return p, nil
+	case len(addrs6) == 0:
+		p.IPv6Mode = IPv6SLAAC
+		return p, nil
+	}
+	p.IPv6Mode, p.IPv6 = IPv6Static, addrs6
+	output, err = r.exec.Run(cmd("ip", "-j", "-6", "route", "show", "default", "dev", adapter))
+	if err != nil {
+		return p, err
+	}
+	routes = nil
+	if err := json.Unmarshal([]byte(output), &routes); err != nil {
+		return p, fmt.Errorf("解析网卡 %s 的 IPv6 路由失败: %v", adapter, err)
+	}
+	if len(routes) > 0 {
+		p.IPv6Gateway = routes[0].Gateway
+	}
+	p.IPv6DNS, err = r.dns(adapter, "IP6.DNS")
+	return p, err
+}
+
+// 通过 nmcli 读取网卡当前生效的 DNS，field 为 IP4.DNS 或 IP6.DNS
+func (r *LinuxReader) dns(adapter, field string) ([]string, error) {
+	output, err := r.exec.Run(cmd("nmcli", "-g", field, "device", "show", adapter))
+	if err != nil {
+		return nil, err
+	}
+	// nmcli -g 输出的多个值以 " | " 分隔
+	var servers []string
+	for _, dns := range strings.Split(output, "|") {
+		if dns = strings.TrimSpace(dns); dns != "" {
+			servers = append(servers, dns)
+		}
+	}
+	return servers, nil
+}
+
+// PowerShellReader 通过 PowerShell 的 NetTCPIP、DnsClient cmdlet 读取 Windows 网卡配置，结果以 JSON 输出
+type PowerShellReader struct {
+	exec Executor
+}
+
+func NewPowerShellReader(exec Executor) *PowerShellReader {
+	return &PowerShellReader{exec: exec}
+}
+
+// 读取网卡配置的 PowerShell 脚本，$adapter 替换为网卡名。
+// 网卡注册表项（IPv6 为 Tcpip6 下的项）的 NameServer 为空表示 DNS 通过 DHCP 获取。
+const readAdapterScript = `$a = $adapter
+$ip = Get-NetIPInterface -InterfaceAlias $a -AddressFamily IPv4 -ErrorAction Stop
+$guid = (Get-NetAdapter -Name $a).InterfaceGuid
+[pscustomobject]@{
+  Dhcp = "$($ip.Dhcp)"
+  Mtu = [int]$ip.NlMtu
+  Metric = [int]$ip.InterfaceMetric
+  AutomaticMetric = "$($ip.AutomaticMetric)"
+  Addresses = @(Get-NetIPAddress -InterfaceAlias $a -AddressFamily IPv4 | Sort-Object SkipAsSource | ForEach-Object { "$($_.IPAddress)/$($_.PrefixLength)" })
+  Gateways = @(Get-NetRoute -InterfaceAlias $a -DestinationPrefix 0.0.0.0/0 -ErrorAction SilentlyContinue | ForEach-Object { $_.NextHop })
+  Dns = @((Get-DnsClientServerAddress -InterfaceAlias $a -AddressFamily IPv4).ServerAddresses)
+  StaticDns = "$((Get-ItemProperty "HKLM:\SYSTEM\CurrentControlSet\Services\Tcpip\Parameters\Interfaces\$guid").NameServer)"
+  Ipv6 = "$((Get-NetAdapterBinding -Name $a -ComponentID ms_tcpip6).Enabled)"
+  Ipv6Managed = "$((Get-NetIPInterface -InterfaceAlias $a -AddressFamily IPv6 -ErrorAction SilentlyContinue).ManagedAddressConfiguration)"
+  Ipv6Addresses = @(Get-NetIPAddress -InterfaceAlias $a -AddressFamily IPv6 -PrefixOrigin Manual -ErrorAction SilentlyContinue | ForEach-Object { "$($_.IPAddress)/$($_.PrefixLength)" })
+  Ipv6Gateways = @(Get-NetRoute -InterfaceAlias $a -DestinationPrefix ::/0 -ErrorAction SilentlyContinue | ForEach-Object { $_.NextHop })
+  Ipv6Dns = @((Get-DnsClientServerAddress -InterfaceAlias $a -AddressFamily IPv6).ServerAddresses)
+  StaticIpv6Dns = "$((Get-ItemProperty "HKLM:\SYSTEM\CurrentControlSet\Services\Tcpip6\Parameters\Interfaces\$guid" -ErrorAction SilentlyContinue).NameServer)"
+} | ConvertTo-Json -Compress`
+
+// readAdapterScript 的输出
+type psAdapter struct {
+	Dhcp            string
+	Mtu             int
+	Metric          int
+	AutomaticMetric string
+	Addresses       []string
+	Gateways        []string
+	Dns             []string
+	StaticDns       string
+	Ipv6            string
+	Ipv6Managed     string
+	Ipv6Addresses   []string
+	Ipv6Gateways    []string
+	Ipv6Dns         []string
+	StaticIpv6Dns   string
+}
+
+func (r *PowerShellReader) Read(adapter string) (Profile, error) {
+	p := Profile{Adapter: adapter}
+	quoted := "'" + strings.ReplaceAll(adapter, "'", "''") + "'"
+	script := strings.ReplaceAll(readAdapterScript, "$adapter", quoted)
+	output, err := r.exec.Run(cmd("powershell", "-NoProfile", "-Command", script))
+	if err != nil {
+		return p, err
+	}
+	var info psAdapter
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &info); err != nil {
+		return p, fmt.Errorf("解析网卡 %s 的配置失败: %v", adapter, err)
+	}
+	p.DHCP = info.Dhcp == "Enabled"
+	p.DNSdhcp = p.DHCP && strings.TrimSpace(info.StaticDns) == ""
+	p.MTU = info.Mtu
+	if info.AutomaticMetric != "Enabled" {
+		p.Metric = info.Metric
+	}
+	for _, dns := range info.Dns {
+		if net.ParseIP(dns) != nil {
+			p.DNS = append(p.DNS, dns)
+		}
+	}
+	setPowerShellIPv6(&p, info)
+	if p.DHCP {
+		return p, nil
+	}
+	if len(info.Gateways) > 0 {
+		p.Gateway = info.Gateways[0]
+	}
+	return p, setAddresses(&p, info.Addresses)
+}
+
+// 按 readAdapterScript 的输出填写 IPv6 设置：未绑定 IPv6 协议时为 disabled，有手动配置的地址时为 static，
+// 否则按是否启用托管地址配置区分 dhcpv6 和 slaac。未手动配置 IPv6 DNS 时留空，表示自动获取
+func setPowerShellIPv6(p *Profile, info psAdapter) {
+	if info.Ipv6 != "True" {
+		p.IPv6Mode = IPv6Disabled
+		return
+	}
+	switch {
+	case len(info.Ipv6Addresses) > 0:
+		p.IPv6Mode, p.IPv6 = IPv6Static, info.Ipv6Addresses
+		if len(info.Ipv6Gateways) > 0 {
+			p.IPv6Gateway = info.Ipv6Gateways[0]
+		}
+	case info.Ipv6Managed == "Enabled":
+		p.IPv6Mode = IPv6DHCPv6
+	default:
+		p.IPv6Mode = IPv6SLAAC
+	}
+	if strings.TrimSpace(info.StaticIpv6Dns) == "" {
+		return
+	}
+	for _, dns := range info.Ipv6Dns {
+		if isIPv6(dns) {
+			p.IPv6DNS = append(p.IPv6DNS, dns)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package netcfg
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// 按命令名返回 testdata 中的命令输出
+func fixtureExec(t *testing.T, files map[string]string) *Recorder {
+	return &Recorder{Respond: func(c Command) (string, error) {
+		key := c.Name
+		if c.Name == "ip" || c.Name == "nmcli" {
+			key += " " + c.Args[1]
+		}
+		name, ok := files[key]
+		if !ok {
+			t.Fatalf("unexpected command: %s", c)
+		}
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data), nil
+	}}
+}
+
+func TestLinuxReader(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  Profile
+	}{
+		{
+			name: "static",
+			files: map[string]string{
+				"ip addr":       "ip_addr_static.json",
+				"ip route":      "ip_route_static.json",
+				"ip -6":         "ip_route6_static.json",
+				"nmcli IP4.DNS": "nmcli_dns_static.txt",
+				"nmcli IP6.DNS": "nmcli_dns6_static.txt",
+			},
+			want: Profile{
+				Adapter:   "eth0",
+				IP:        "192.168.1.10",
+				Netmask:   "255.255.255.0",
+				Gateway:   "192.168.1.1",
+				Addresses: []string{"10.10.0.5/16"},
+				DNS:       []string{"223.5.5.5", "114.114.114.114"},
+				MTU:       1400,
+				Metric:    20,
+
+				IPv6Mode:    IPv6Static,
+				IPv6:        []string{"2001:db8::10/64"},
+				IPv6Gateway: "fe80::1",
+				IPv6DNS:     []string{"2001:4860:4860::8888"},
+			},
+		},
+		{
+			name: "dhcp",
+			files: map[string]string{
+				"ip addr":       "ip_addr_dhcp.json",
+				"ip route":      "ip_route_dhcp.json",
+				"nmcli IP4.DNS": "nmcli_dns_dhcp.txt",
+			},
+			// 网卡上没有 IPv6 地址，视为禁用 IPv6
+			want: Profile{
+				Adapter:  "eth0",
+				DHCP:     true,
+				DNSdhcp:  true,
+				DNS:      []string{"192.168.31.1"},
+				MTU:      1500,
+				Metric:   600,
+				IPv6Mode: IPv6Disabled,
+			},
+		},
+	}
+	for _, tt := range tests {
+		reader, _ := NewReader(KindNmcli, fixtureExec(t, tt.files))
+		got, err := reader.Read("eth0")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v\nwant %+v", tt.name, got, tt.want)
+		}
+		if err := got.Validate(); err != nil {
+			t.Errorf("%s: captured profile is invalid: %v", tt.name, err)
+		}
+	}
+}
+
+func TestPowerShellReader(t *testing.T) {
+	reader, _ := NewReader(KindNetsh, fixtureExec(t, map[string]string{"powershell": "powershell_static.json"}))
+	got, err := reader.Read("以太网")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Profile{
+		Adapter:   "以太网",
+		IP:        "192.168.0.10",
+		Netmask:   "255.255.255.0",
+		Gateway:   "192.168.0.1",
+		Addresses: []string{"10.10.0.5/24"},
+		DNS:       []string{"8.8.8.8", "114.114.114.114"},
+		MTU:       1500,
+		Metric:    10,
+
+		IPv6Mode:    IPv6Static,
+		IPv6:        []string{"2001:db8::10/64"},
+		IPv6Gateway: "fe80::1",
+		IPv6DNS:     []string{"2001:4860:4860::8888"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v\nwant %+v", got, want)
+	}
+
+	reader, _ = NewReader(KindNetsh, fixtureExec(t, map[string]string{"powershell": "powershell_dhcp.json"}))
+	got, err = reader.Read("WLAN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 启用托管地址配置，未手动配置 IPv6 DNS，站点本地的默认 DNS 不记录
+	want = Profile{Adapter: "WLAN", DHCP: true, DNSdhcp: true, DNS: []string{"192.168.31.1"}, MTU: 1500, IPv6Mode: IPv6DHCPv6}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestFakeReader(t *testing.T) {
+	reader := FakeReader{"eth0": {DHCP: true, DNSdhcp: true}}
+	got, err := reader.Read("eth0")
+	if err != nil || got.Adapter != "eth0" || !got.DHCP {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+	if _, err := reader.Read("eth1"); err == nil {
+		t.Fatal("expected error for unknown adapter")
+	}
+}
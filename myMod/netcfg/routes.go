@@ -0,0 +1,105 @@
+package netcfg
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// RouteState 记录每个网卡上由配置方案添加的静态路由，保存在 JSON 文件中，
+// 切换配置方案时据此删除上一个方案添加的路由
+type RouteState struct {
+	path string
+	lock sync.Mutex
+}
+
+func NewRouteState(path string) *RouteState {
+	return &RouteState{path: path}
+}
+
+// 读取全部记录，文件不存在时返回空记录
+func (s *RouteState) load() (map[string][]Route, error) {
+	routes := make(map[string][]Route)
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return routes, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &routes); err != nil {
+		return nil, err
+	}
+	return routes, nil
+}
+
+// Routes 返回网卡上由配置方案添加的路由
+func (s *RouteState) Routes(adapter string) ([]Route, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	routes, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return routes[adapter], nil
+}
+
+// SetRoutes 记录网卡上由配置方案添加的路由
+func (s *RouteState) SetRoutes(adapter string, list []Route) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	routes, err := s.load()
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		delete(routes, adapter)
+	} else {
+		routes[adapter] = list
+	}
+	data, err := json.MarshalIndent(routes, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
+
+// Switch 切换到配置方案 p：先删除 state 中记录的、该网卡上由之前的方案添加而 p 中没有的路由，
+// 再应用 p 并记录 p 的路由。state 为 nil 时等同于 Apply。
+// 删除旧路由失败（如路由已被手动删除）时忽略，不影响新方案的应用。
+func Switch(b Backend, p Profile, state *RouteState) error {
+	if state == nil {
+		return Apply(b, p)
+	}
+	if err := p.Validate(); err != nil {
+		return &ApplyError{Step: "检查配置", Err: err}
+	}
+	prev, err := state.Routes(p.Adapter)
+	if err != nil {
+		return &ApplyError{Step: "读取路由记录", Err: err}
+	}
+	for _, r := range prev {
+		if !containsRoute(p.Routes, r) {
+			b.DeleteRoute(p.Adapter, r)
+		}
+	}
+	// 应用失败时新方案的部分路由可能已添加，同样记录下来，下次切换时删除
+	if err := state.SetRoutes(p.Adapter, p.Routes); err != nil {
+		return &ApplyError{Step: "保存路由记录", Err: err}
+	}
+	return Apply(b, p)
+}
+
+func containsRoute(routes []Route, r Route) bool {
+	for _, route := range routes {
+		if route == r {
+			return true
+		}
+	}
+	return false
+}
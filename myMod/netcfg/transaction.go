@@ -0,0 +1,287 @@
+package netcfg
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrNoPending 没有等待确认的配置，或确认时已超时恢复原配置
+var ErrNoPending = errors.New("没有等待确认的配置，可能已超时恢复原配置")
+
+// TxError 事务方式应用配置方案失败
+type TxError struct {
+	Err      error // 应用或检查失败的原因
+	Rollback error // 恢复原配置的结果，nil 表示已恢复
+}
+
+func (e *TxError) Error() string {
+	if e.Rollback != nil {
+		return fmt.Sprintf("%v，恢复原配置失败: %v", e.Err, e.Rollback)
+	}
+	return fmt.Sprintf("%v，已恢复原配置", e.Err)
+}
+
+func (e *TxError) Unwrap() error { return e.Err }
+
+// Applier 以事务方式应用配置方案：
+//  1. 读取网卡当前配置作为快照（连同 State 中记录的路由）；
+//  2. 应用配置方案；
+//  3. 执行配置方案中的连通性检查；
+//  4. 应用或检查失败时立即恢复快照；Checks.Confirm 大于 0 时等待确认，超时未确认同样恢复快照。
+//
+// 快照包含 Reader 能读取的设置（IPv4、IPv6 地址和 DNS、MTU、跃点数）和 State 中记录的路由，
+// 快照不合法（无法用于恢复）时不修改任何设置。
+// 设置 Pending 后，修改设置前先把快照写入文件，进程在应用或等待确认期间退出后由 Recover 恢复。
+type Applier struct {
+	Backend  Backend
+	Reader   Reader
+	Verifier *Verifier
+	State    *RouteState // 可为空，为空时不删除旧方案的路由
+	Pending  *TxState    // 可为空，为空时等待确认的配置只保存在内存中
+
+	// OnTimeout 超时未确认、恢复原配置后调用，err 为恢复的结果
+	OnTimeout func(p Profile, err error)
+
+	lock    sync.Mutex // 串行化应用、确认和恢复
+	pending *pendingApply
+}
+
+// 等待确认的配置
+type pendingApply struct {
+	id       string
+	profile  Profile
+	snapshot Profile
+	timer    *time.Timer
+}
+
+// Apply 以事务方式应用 p。需要确认时返回事务ID，在 p.Checks.Confirm 秒内调用 Confirm 后才保留新配置。
+// 失败时返回 *TxError，其中包含恢复原配置的结果；快照读取失败或不合法、保存快照失败时不修改任何设置。
+// 上一次的配置仍在等待确认时视为已确认。
+func (a *Applier) Apply(ctx context.Context, p Profile) (string, error) {
+	if err := p.Validate(); err != nil {
+		return "", &ApplyError{Step: "检查配置", Err: err}
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if err := a.commitLocked(); err != nil {
+		return "", &ApplyError{Step: "确认上一次的配置", Err: err}
+	}
+
+	snapshot, err := a.snapshot(p.Adapter)
+	if err != nil {
+		return "", &ApplyError{Step: "读取当前配置", Err: err}
+	}
+	if err := snapshot.Validate(); err != nil {
+		return "", &ApplyError{Step: "检查当前配置", Err: fmt.Errorf("当前配置无法用于恢复: %w", err)}
+	}
+	// 截止时间为零表示正在应用，进程此时退出同样需要恢复
+	record := txRecord{ID: newTxID(), Profile: p, Snapshot: snapshot}
+	if err := a.Pending.save(&record); err != nil {
+		return "", &ApplyError{Step: "保存原配置", Err: err}
+	}
+	if err := Switch(a.Backend, p, a.State); err != nil {
+		return "", &TxError{Err: err, Rollback: a.restoreLocked(p, snapshot)}
+	}
+	if a.Verifier != nil {
+		if err := a.Verifier.Verify(ctx, p); err != nil {
+			return "", &TxError{Err: err, Rollback: a.restoreLocked(p, snapshot)}
+		}
+	}
+	if p.Checks.Confirm <= 0 {
+		if err := a.Pending.save(nil); err != nil {
+			return "", &ApplyError{Step: "清除原配置记录", Err: err}
+		}
+		return "", nil
+	}
+
+	confirm := time.Duration(p.Checks.Confirm) * time.Second
+	record.Deadline = time.Now().Add(confirm)
+	if err := a.Pending.save(&record); err != nil {
+		return "", &TxError{Err: &ApplyError{Step: "保存原配置", Err: err}, Rollback: a.restoreLocked(p, snapshot)}
+	}
+	a.waitConfirmLocked(record, confirm)
+	return record.ID, nil
+}
+
+// Recover 处理 Pending 中记录的、进程上次退出时尚未结束的事务：
+// 仍在确认时间内时继续等待确认（事务ID不变），已超时或应用过程中退出时立即恢复原配置。
+// 应在进程启动后、应用配置之前调用，没有记录时返回 nil，否则返回恢复原配置的结果。
+func (a *Applier) Recover() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	record, err := a.Pending.load()
+	if err != nil || record == nil {
+		return err
+	}
+	if remaining := time.Until(record.Deadline); !record.Deadline.IsZero() && remaining > 0 {
+		a.waitConfirmLocked(*record, remaining)
+		return nil
+	}
+	return a.restoreLocked(record.Profile, record.Snapshot)
+}
+
+// 等待确认，超时后恢复原配置，调用方持有 a.lock
+func (a *Applier) waitConfirmLocked(record txRecord, d time.Duration) {
+	pending := &pendingApply{id: record.ID, profile: record.Profile, snapshot: record.Snapshot}
+	pending.timer = time.AfterFunc(d, func() {
+		a.timeout(pending)
+	})
+	a.pending = pending
+}
+
+// Confirm 确认保留等待确认的配置
+func (a *Applier) Confirm(id string) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.pending == nil || a.pending.id != id {
+		return ErrNoPending
+	}
+	return a.commitLocked()
+}
+
+// Rollback 放弃等待确认的配置，立即恢复原配置
+func (a *Applier) Rollback(id string) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.pending == nil || a.pending.id != id {
+		return ErrNoPending
+	}
+	pending := a.pending
+	a.pending.timer.Stop()
+	a.pending = nil
+	return a.restoreLocked(pending.profile, pending.snapshot)
+}
+
+// 确认超时，恢复原配置
+func (a *Applier) timeout(pending *pendingApply) {
+	a.lock.Lock()
+	if a.pending != pending {
+		// 已确认或已被新的配置取代
+		a.lock.Unlock()
+		return
+	}
+	a.pending = nil
+	err := a.restoreLocked(pending.profile, pending.snapshot)
+	onTimeout := a.OnTimeout
+	a.lock.Unlock()
+	if onTimeout != nil {
+		onTimeout(pending.profile, err)
+	}
+}
+
+// 保留等待确认的配置并清除原配置记录，调用方持有 a.lock
+func (a *Applier) commitLocked() error {
+	if a.pending == nil {
+		return nil
+	}
+	a.pending.timer.Stop()
+	a.pending = nil
+	return a.Pending.save(nil)
+}
+
+func (a *Applier) snapshot(adapter string) (Profile, error) {
+	snapshot, err := a.Reader.Read(adapter)
+	if err != nil {
+		return snapshot, err
+	}
+	snapshot.Name = "原配置"
+	if a.State != nil {
+		if snapshot.Routes, err = a.State.Routes(adapter); err != nil {
+			return snapshot, err
+		}
+	}
+	return snapshot, nil
+}
+
+// 恢复快照，新方案添加的路由由 Switch 根据路由记录删除。
+// Apply 不修改值为 0 的跃点数和静态 IP 模式下为空的 DNS，p 修改过而快照中没有的这两项在恢复时显式重置：
+// 快照中跃点数为 0 表示原来使用自动跃点数，静态 IP 模式下 DNS 为空表示原来没有 DNS。
+// 恢复成功后清除原配置记录；恢复失败时保留，下次 Recover 时重试。调用方持有 a.lock
+func (a *Applier) restoreLocked(p, snapshot Profile) error {
+	if err := Switch(a.Backend, snapshot, a.State); err != nil {
+		return err
+	}
+	if len(p.DNS) > 0 && !snapshot.DHCP && len(snapshot.DNS) == 0 {
+		if err := a.Backend.SetDNS(snapshot.Adapter, nil); err != nil {
+			return &ApplyError{Step: "清除 DNS", Err: err}
+		}
+	}
+	if p.Metric > 0 && snapshot.Metric == 0 {
+		if err := a.Backend.SetAutoMetric(snapshot.Adapter); err != nil {
+			return &ApplyError{Step: "恢复自动跃点数", Err: err}
+		}
+	}
+	return a.Pending.save(nil)
+}
+
+// TxState 把尚未结束的事务（应用中或等待确认的配置方案、原配置快照和确认截止时间）保存在 JSON 文件中，
+// 进程崩溃或重启后由 Applier.Recover 据此恢复原配置
+type TxState struct {
+	path string
+}
+
+func NewTxState(path string) *TxState {
+	return &TxState{path: path}
+}
+
+// 尚未结束的事务，Deadline 为零表示正在应用
+type txRecord struct {
+	ID       string    `json:"id"`
+	Profile  Profile   `json:"profile"`
+	Snapshot Profile   `json:"snapshot"`
+	Deadline time.Time `json:"deadline"`
+}
+
+// 读取记录，s 为 nil 或文件不存在时返回 nil
+func (s *TxState) load() (*txRecord, error) {
+	if s == nil {
+		return nil, nil
+	}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var record txRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// 保存记录，record 为 nil 时删除文件；s 为 nil 时不做任何事
+func (s *TxState) save(record *txRecord) error {
+	if s == nil {
+		return nil
+	}
+	if record == nil {
+		if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
+
+func newTxID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
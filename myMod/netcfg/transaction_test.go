@@ -0,0 +1,247 @@
+package netcfg
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 当前为 DHCP 的网卡，切换到带路由的静态配置
+func newTestApplier(t *testing.T, dialErr error) (*Applier, *Recorder) {
+	b, rec, _ := NewDryRun(KindNmcli)
+	dir := t.TempDir()
+	return &Applier{
+		Backend: b,
+		Reader:  FakeReader{"eth0": {DHCP: true, DNSdhcp: true, MTU: 1500}},
+		Verifier: &Verifier{Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			if dialErr != nil {
+				return nil, dialErr
+			}
+			c1, c2 := net.Pipe()
+			c2.Close()
+			return c1, nil
+		}},
+		State:   NewRouteState(filepath.Join(dir, "routes.json")),
+		Pending: NewTxState(filepath.Join(dir, "pending.json")),
+	}, rec
+}
+
+// 模拟进程重启：使用同样的后端和记录文件创建新的 Applier
+func restarted(a *Applier) *Applier {
+	return &Applier{Backend: a.Backend, Reader: a.Reader, State: a.State, Pending: a.Pending}
+}
+
+func pendingExists(t *testing.T, a *Applier) bool {
+	t.Helper()
+	_, err := os.Stat(a.Pending.path)
+	return err == nil
+}
+
+func txProfile(checks Checks) Profile {
+	return Profile{
+		Adapter: "eth0",
+		IP:      "192.168.1.10",
+		Netmask: "255.255.255.0",
+		Gateway: "192.168.1.1",
+		Routes:  []Route{{Dest: "10.0.0.0/8", Gateway: "192.168.1.254"}},
+		Checks:  checks,
+	}
+}
+
+// 恢复原配置的命令：删除新方案的路由，重新设置为 DHCP
+const restorePlan = `nmcli device modify eth0 -ipv4.routes "10.0.0.0/8 192.168.1.254"
+nmcli device modify eth0 ipv4.method auto ipv4.addresses "" ipv4.gateway ""
+nmcli device modify eth0 ipv4.dns "" ipv4.ignore-auto-dns no
+ip link set dev eth0 mtu 1500`
+
+func TestApplierRollsBackFailedCheck(t *testing.T) {
+	a, rec := newTestApplier(t, errors.New("connection refused"))
+	_, err := a.Apply(context.Background(), txProfile(Checks{TCP: "10.0.0.1:443", Timeout: 1}))
+	var txErr *TxError
+	if !errors.As(err, &txErr) || txErr.Rollback != nil {
+		t.Fatalf("err = %v, want rolled back TxError", err)
+	}
+	if !strings.Contains(err.Error(), "已恢复原配置") {
+		t.Fatalf("err = %v", err)
+	}
+	if plan := rec.Plan(); !strings.HasSuffix(plan, restorePlan) {
+		t.Fatalf("plan does not restore the snapshot:\n%s", plan)
+	}
+	if routes, _ := a.State.Routes("eth0"); len(routes) != 0 {
+		t.Fatalf("route state = %v, want none after rollback", routes)
+	}
+}
+
+// 原配置使用自动跃点数、静态 IP 没有 DNS，恢复时清除新方案设置的 DNS 并恢复自动跃点数
+func TestApplierRestoresAutoMetricAndEmptyDNS(t *testing.T) {
+	a, rec := newTestApplier(t, errors.New("connection refused"))
+	a.Reader = FakeReader{"eth0": {IP: "192.168.1.20", Netmask: "255.255.255.0", MTU: 1500}}
+	p := txProfile(Checks{TCP: "10.0.0.1:443", Timeout: 1})
+	p.DNS, p.Metric = []string{"223.5.5.5"}, 10
+	_, err := a.Apply(context.Background(), p)
+	var txErr *TxError
+	if !errors.As(err, &txErr) || txErr.Rollback != nil {
+		t.Fatalf("err = %v, want rolled back TxError", err)
+	}
+	want := `nmcli device modify eth0 -ipv4.routes "10.0.0.0/8 192.168.1.254"
+nmcli device modify eth0 ipv4.method manual ipv4.addresses 192.168.1.20/24 ipv4.gateway ""
+ip link set dev eth0 mtu 1500
+nmcli device modify eth0 ipv4.dns "" ipv4.ignore-auto-dns no
+nmcli device modify eth0 ipv4.route-metric -1`
+	if plan := rec.Plan(); !strings.HasSuffix(plan, want) {
+		t.Fatalf("plan does not reset DNS and metric:\n%s\nwant suffix:\n%s", plan, want)
+	}
+}
+
+func TestApplierRollsBackFailedApply(t *testing.T) {
+	a, rec := newTestApplier(t, nil)
+	rec.Respond = func(c Command) (string, error) {
+		if strings.Contains(c.String(), "ipv4.method manual") {
+			return "Error: invalid gateway", errors.New("exit status 1")
+		}
+		return "", nil
+	}
+	_, err := a.Apply(context.Background(), txProfile(Checks{}))
+	var txErr *TxError
+	if !errors.As(err, &txErr) || txErr.Rollback != nil {
+		t.Fatalf("err = %v, want rolled back TxError", err)
+	}
+	if Output(err) != "Error: invalid gateway" {
+		t.Fatalf("Output = %q", Output(err))
+	}
+	if !strings.Contains(rec.Plan(), "ipv4.method auto") {
+		t.Fatalf("plan does not restore DHCP:\n%s", rec.Plan())
+	}
+}
+
+func TestApplierConfirm(t *testing.T) {
+	a, rec := newTestApplier(t, nil)
+	id, err := a.Apply(context.Background(), txProfile(Checks{TCP: "10.0.0.1:443", Confirm: 1}))
+	if err != nil || id == "" {
+		t.Fatalf("Apply = %q, %v", id, err)
+	}
+	if err := a.Confirm("other"); !errors.Is(err, ErrNoPending) {
+		t.Fatalf("Confirm(other) = %v", err)
+	}
+	if err := a.Confirm(id); err != nil {
+		t.Fatal(err)
+	}
+	n := len(rec.Commands())
+	time.Sleep(1500 * time.Millisecond)
+	if len(rec.Commands()) != n {
+		t.Fatalf("confirmed profile was rolled back:\n%s", rec.Plan())
+	}
+	if err := a.Rollback(id); !errors.Is(err, ErrNoPending) {
+		t.Fatalf("Rollback after confirm = %v", err)
+	}
+}
+
+func TestApplierConfirmTimeout(t *testing.T) {
+	a, rec := newTestApplier(t, nil)
+	done := make(chan error, 1)
+	a.OnTimeout = func(p Profile, err error) { done <- err }
+	id, err := a.Apply(context.Background(), txProfile(Checks{Confirm: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.Reset()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("restore: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("profile was not rolled back after the confirm timeout")
+	}
+	if rec.Plan() != restorePlan {
+		t.Fatalf("plan:\n%s", rec.Plan())
+	}
+	if err := a.Confirm(id); !errors.Is(err, ErrNoPending) {
+		t.Fatalf("Confirm after timeout = %v", err)
+	}
+}
+
+// 快照无法用于恢复时不修改任何设置
+func TestApplierRejectsInvalidSnapshot(t *testing.T) {
+	tests := []struct {
+		name    string
+		current Profile
+	}{
+		{"mtu", Profile{DHCP: true, DNSdhcp: true, MTU: 100}},
+		{"static without ip", Profile{Netmask: "255.255.255.0", DNS: []string{"223.5.5.5"}}},
+	}
+	for _, tt := range tests {
+		a, rec := newTestApplier(t, nil)
+		a.Reader = FakeReader{"eth0": tt.current}
+		_, err := a.Apply(context.Background(), txProfile(Checks{}))
+		var applyErr *ApplyError
+		if !errors.As(err, &applyErr) || applyErr.Step != "检查当前配置" {
+			t.Fatalf("%s: err = %v, want snapshot check error", tt.name, err)
+		}
+		if len(rec.Commands()) != 0 {
+			t.Fatalf("%s: settings changed:\n%s", tt.name, rec.Plan())
+		}
+		if pendingExists(t, a) {
+			t.Fatalf("%s: pending record saved", tt.name)
+		}
+	}
+}
+
+// 等待确认期间进程重启，重启后仍可用原事务ID确认
+func TestApplierRecoverPending(t *testing.T) {
+	a, rec := newTestApplier(t, nil)
+	id, err := a.Apply(context.Background(), txProfile(Checks{Confirm: 60}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pendingExists(t, a) {
+		t.Fatal("pending record not saved")
+	}
+	a.pending.timer.Stop() // 模拟进程退出：计时器不再触发，记录文件保留
+
+	b := restarted(a)
+	rec.Reset()
+	if err := b.Recover(); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.Commands()) != 0 {
+		t.Fatalf("pending profile restored before the deadline:\n%s", rec.Plan())
+	}
+	if err := b.Confirm(id); err != nil {
+		t.Fatal(err)
+	}
+	if pendingExists(t, b) {
+		t.Fatal("pending record kept after confirm")
+	}
+}
+
+// 确认已超时或应用过程中进程退出时，重启后立即恢复原配置
+func TestApplierRecoverRestores(t *testing.T) {
+	for _, deadline := range []time.Time{{}, time.Now().Add(-time.Second)} {
+		a, rec := newTestApplier(t, nil)
+		if _, err := a.Apply(context.Background(), txProfile(Checks{})); err != nil {
+			t.Fatal(err)
+		}
+		a.Pending.save(&txRecord{ID: "x", Profile: txProfile(Checks{}), Snapshot: Profile{Adapter: "eth0", DHCP: true, DNSdhcp: true, MTU: 1500}, Deadline: deadline})
+
+		b := restarted(a)
+		rec.Reset()
+		if err := b.Recover(); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Plan() != restorePlan {
+			t.Fatalf("deadline %v: plan:\n%s", deadline, rec.Plan())
+		}
+		if pendingExists(t, b) {
+			t.Fatal("pending record kept after restore")
+		}
+		if err := b.Recover(); err != nil {
+			t.Fatalf("second Recover = %v", err)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package netcfg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Checks 应用配置方案后的连通性检查，未配置的检查项跳过
+type Checks struct {
+	PingGateway bool   `yaml:"pingGateway"` // ping 网关（仅静态 IP 模式）
+	Resolve     string `yaml:"resolve"`     // 解析该域名，如 www.baidu.com
+	TCP         string `yaml:"tcp"`         // 连接该 TCP 地址，如 10.0.0.1:443
+	Timeout     int    `yaml:"timeout"`     // 每项检查的超时时间（秒），期间失败会重试，0 表示 DefaultCheckTimeout
+	Confirm     int    `yaml:"confirm"`     // 检查通过后等待用户确认的时间（秒），超时未确认则恢复原配置，0 表示不需要确认
+}
+
+// 每项检查的默认超时时间
+const DefaultCheckTimeout = 10 * time.Second
+
+// 检查失败后的重试间隔
+const checkRetryInterval = time.Second
+
+// Empty 是否没有配置任何检查项
+func (c Checks) Empty() bool {
+	return !c.PingGateway && c.Resolve == "" && c.TCP == ""
+}
+
+func (c Checks) validate(p Profile) error {
+	if c.PingGateway && (p.DHCP || p.Gateway == "") {
+		return errors.New("pingGateway 只能用于配置了网关的静态 IP 模式")
+	}
+	if c.TCP != "" {
+		if _, _, err := net.SplitHostPort(c.TCP); err != nil {
+			return fmt.Errorf("无效 TCP 检查地址: %s", c.TCP)
+		}
+	}
+	if c.Timeout < 0 || c.Confirm < 0 {
+		return errors.New("检查超时和确认时间不能为负数")
+	}
+	return nil
+}
+
+func (c Checks) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return time.Duration(c.Timeout) * time.Second
+	}
+	return DefaultCheckTimeout
+}
+
+// Verifier 执行连通性检查，ping 通过 Exec 执行系统命令，字段为空时使用标准库的实现
+type Verifier struct {
+	Kind   string // 决定 ping 的参数格式，为空时为 DefaultKind()
+	Exec   Executor
+	Lookup func(ctx context.Context, host string) ([]string, error)
+	Dial   func(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// Verify 依次执行 p.Checks 中的检查，返回第一个失败的检查
+func (v *Verifier) Verify(ctx context.Context, p Profile) error {
+	c := p.Checks
+	if c.PingGateway {
+		if err := retry(ctx, c.timeout(), func(ctx context.Context) error { return v.ping(p.Gateway) }); err != nil {
+			return fmt.Errorf("ping 网关 %s 失败: %w", p.Gateway, err)
+		}
+	}
+	if c.Resolve != "" {
+		if err := retry(ctx, c.timeout(), func(ctx context.Context) error { return v.resolve(ctx, c.Resolve) }); err != nil {
+			return fmt.Errorf("解析域名 %s 失败: %w", c.Resolve, err)
+		}
+	}
+	if c.TCP != "" {
+		if err := retry(ctx, c.timeout(), func(ctx context.Context) error { return v.dial(ctx, c.TCP) }); err != nil {
+			return fmt.Errorf("连接 %s 失败: %w", c.TCP, err)
+		}
+	}
+	return nil
+}
+
+// 在 timeout 内重试 check 直到成功，刚修改配置时网卡可能尚未就绪
+func retry(ctx context.Context, timeout time.Duration, check func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		err := check(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(checkRetryInterval):
+		}
+	}
+}
+
+func (v *Verifier) ping(host string) error {
+	exec := v.Exec
+	if exec == nil {
+		exec = ExecRunner{}
+	}
+	kind := v.Kind
+	if kind == "" {
+		kind = DefaultKind()
+	}
+	c := cmd("ping", "-c", "1", "-W", "1", host)
+	if kind == KindNetsh {
+		c = cmd("ping", "-n", "1", "-w", "1000", host)
+	}
+	output, err := exec.Run(c)
+	if err != nil {
+		return err
+	}
+	// Windows 下目标不可达时 ping 仍可能返回 0，以输出中的 TTL 判断是否收到回复
+	if kind == KindNetsh && !strings.Contains(strings.ToUpper(output), "TTL=") {
+		return errors.New("没有收到回复")
+	}
+	return nil
+}
+
+func (v *Verifier) resolve(ctx context.Context, host string) error {
+	lookup := v.Lookup
+	if lookup == nil {
+		lookup = net.DefaultResolver.LookupHost
+	}
+	addrs, err := lookup(ctx, host)
+	if err == nil && len(addrs) == 0 {
+		err = errors.New("没有解析结果")
+	}
+	return err
+}
+
+func (v *Verifier) dial(ctx context.Context, address string) error {
+	dial := v.Dial
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	conn, err := dial(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,114 @@
+package xlog
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 切分设置
+type RotateOptions struct {
+	MaxSize    int64         // 单个文件最大字节数，0 表示不按大小切分
+	Daily      bool          // 是否按天切分
+	MaxBackups int           // 保留的历史文件数，0 表示不限
+	MaxAge     time.Duration // 历史文件保留时间，0 表示不限
+}
+
+// RotatingFile 可切分的追加写文件，切分规则与日志文件相同，供事件日志等其他记录文件复用。
+// 每次 Write 的数据不会被拆到两个文件中，可安全地并发调用。
+type RotatingFile struct {
+	lock sync.Mutex
+	file *rotatingFile
+}
+
+// OpenRotatingFile 以追加方式打开文件，目录不存在时自动创建
+func OpenRotatingFile(path string, opts RotateOptions) (*RotatingFile, error) {
+	file, err := openRotatingFile(path, opts.MaxSize, opts.Daily, opts.MaxBackups, opts.MaxAge)
+	if err != nil {
+		return nil, err
+	}
+	return &RotatingFile{file: file}, nil
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if err := f.file.write(time.Now(), p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sync 将已写入的数据刷到磁盘
+func (f *RotatingFile) Sync() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.file.file.Sync()
+}
+
+func (f *RotatingFile) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.file.Close()
+}
+
+// RotatedFiles 返回 path 的历史文件和当前文件（存在时），按时间从旧到新排列
+func RotatedFiles(path string) ([]string, error) {
+	f := &rotatingFile{path: path}
+	dir, prefix, ext := f.parts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	// 历史文件名含切分时间（name-20060102-150405[.N].ext），按文件名排序即按时间排序
+	sort.Slice(files, func(i, j int) bool { return backupLess(files[i], files[j]) })
+	if _, err := os.Stat(path); err == nil {
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+// 同一秒内切分的文件带序号，无序号的在前
+func backupLess(a, b string) bool {
+	ka, kb := backupKey(a), backupKey(b)
+	if ka.stamp != kb.stamp {
+		return ka.stamp < kb.stamp
+	}
+	return ka.seq < kb.seq
+}
+
+type backupSortKey struct {
+	stamp string
+	seq   int
+}
+
+func backupKey(path string) backupSortKey {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	// name-20060102-150405 或 name-20060102-150405.N
+	const stampLen = len("20060102-150405")
+	key := backupSortKey{stamp: name}
+	if i := strings.LastIndexByte(name, '.'); i > 0 && i >= stampLen {
+		key.stamp = name[:i]
+		for _, c := range name[i+1:] {
+			if c < '0' || c > '9' {
+				return backupSortKey{stamp: name}
+			}
+			key.seq = key.seq*10 + int(c-'0')
+		}
+	}
+	return key
+}
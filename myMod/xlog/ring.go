@@ -0,0 +1,73 @@
+package xlog
+
+// 内存环形缓冲区，保留最近的日志并推送给订阅者。调用方需持有 root 的锁。
+type ring struct {
+	entries   []Entry
+	next      int // 下一条写入位置
+	full      bool
+	followers map[int]chan Entry
+	nextID    int
+}
+
+func newRing(size int) *ring {
+	return &ring{entries: make([]Entry, size), followers: make(map[int]chan Entry)}
+}
+
+// 写入一条日志，满了覆盖最旧的一条
+func (r *ring) add(e Entry) {
+	r.entries[r.next] = e
+	r.next++
+	if r.next == len(r.entries) {
+		r.next = 0
+		r.full = true
+	}
+	for _, ch := range r.followers {
+		select {
+		case ch <- e:
+		default:
+			// 订阅者读取不及时，丢弃
+		}
+	}
+}
+
+// 最近的 n 条日志，按时间先后
+func (r *ring) tail(n int) []Entry {
+	count := r.next
+	if r.full {
+		count = len(r.entries)
+	}
+	if n <= 0 || n > count {
+		n = count
+	}
+	out := make([]Entry, n)
+	start := r.next - n
+	if start < 0 {
+		start += len(r.entries)
+	}
+	for i := 0; i < n; i++ {
+		out[i] = r.entries[(start+i)%len(r.entries)]
+	}
+	return out
+}
+
+// 修改容量，保留最近的日志
+func (r *ring) resize(size int) {
+	if size == len(r.entries) {
+		return
+	}
+	kept := r.tail(size)
+	r.entries = make([]Entry, size)
+	copy(r.entries, kept)
+	r.next = len(kept) % size
+	r.full = len(kept) == size
+}
+
+func (r *ring) follow(ch chan Entry) int {
+	r.nextID++
+	r.followers[r.nextID] = ch
+	return r.nextID
+}
+
+func (r *ring) unfollow(id int) {
+	delete(r.followers, id)
+}
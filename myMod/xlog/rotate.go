@@ -0,0 +1,165 @@
+package xlog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 可切分的日志文件。当前文件为 path，切分后的历史文件为 name-20060102-150405.ext。
+// 调用方需持有 root 的锁。
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	daily      bool
+	maxBackups int
+	maxAge     time.Duration
+
+	file   *os.File
+	size   int64
+	opened time.Time // 当前文件的创建日期，用于按天切分
+}
+
+func openRotatingFile(path string, maxSize int64, daily bool, maxBackups int, maxAge time.Duration) (*rotatingFile, error) {
+	f := &rotatingFile{path: path, maxSize: maxSize, daily: daily, maxBackups: maxBackups, maxAge: maxAge}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("xlog: create log dir: %w", err)
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	f.cleanup()
+	return f, nil
+}
+
+// 以追加方式打开当前文件，重启程序不会清空已有日志
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("xlog: open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("xlog: stat log file: %w", err)
+	}
+	f.file = file
+	f.size = info.Size()
+	f.opened = info.ModTime()
+	if f.size == 0 {
+		f.opened = time.Now()
+	}
+	return nil
+}
+
+func (f *rotatingFile) write(now time.Time, data []byte) error {
+	if f.needRotate(now, int64(len(data))) {
+		if err := f.rotate(now); err != nil {
+			return err
+		}
+	}
+	n, err := f.file.Write(data)
+	f.size += int64(n)
+	return err
+}
+
+// 超过大小或跨天时需要切分，空文件不切分
+func (f *rotatingFile) needRotate(now time.Time, n int64) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.maxSize > 0 && f.size+n > f.maxSize {
+		return true
+	}
+	if f.daily {
+		y1, m1, d1 := f.opened.Date()
+		y2, m2, d2 := now.Date()
+		return y1 != y2 || m1 != m2 || d1 != d2
+	}
+	return false
+}
+
+// 将当前文件重命名为历史文件，再新建当前文件
+func (f *rotatingFile) rotate(now time.Time) error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	backup := f.backupName(now)
+	if err := os.Rename(f.path, backup); err != nil {
+		// 重命名失败（如文件被占用）时继续写原文件
+		if openErr := f.open(); openErr != nil {
+			return openErr
+		}
+		return fmt.Errorf("xlog: rotate log file: %w", err)
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	f.opened = now
+	f.cleanup()
+	return nil
+}
+
+// 历史文件名，同一秒内多次切分时追加序号
+func (f *rotatingFile) backupName(now time.Time) string {
+	dir, prefix, ext := f.parts()
+	base := filepath.Join(dir, prefix+now.Format("20060102-150405"))
+	name := base + ext
+	for i := 1; ; i++ {
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			return name
+		}
+		name = fmt.Sprintf("%s.%d%s", base, i, ext)
+	}
+}
+
+// 目录、历史文件名前缀（name-）、扩展名
+func (f *rotatingFile) parts() (dir, prefix, ext string) {
+	dir = filepath.Dir(f.path)
+	ext = filepath.Ext(f.path)
+	prefix = strings.TrimSuffix(filepath.Base(f.path), ext) + "-"
+	return dir, prefix, ext
+}
+
+// 按数量和时间清理历史文件
+func (f *rotatingFile) cleanup() {
+	if f.maxBackups <= 0 && f.maxAge <= 0 {
+		return
+	}
+	dir, prefix, ext := f.parts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	type backup struct {
+		path    string
+		modTime time.Time
+	}
+	var backups []backup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: filepath.Join(dir, name), modTime: info.ModTime()})
+	}
+	// 新的在前
+	sort.Slice(backups, func(i, j int) bool { return backups[i].modTime.After(backups[j].modTime) })
+	for i, b := range backups {
+		expired := f.maxAge > 0 && time.Since(b.modTime) > f.maxAge
+		if (f.maxBackups > 0 && i >= f.maxBackups) || expired {
+			_ = os.Remove(b.path)
+		}
+	}
+}
+
+func (f *rotatingFile) Close() error {
+	return f.file.Close()
+}
@@ -0,0 +1,405 @@
+// Package xlog 分级、结构化、可切分的日志。
+//
+// 同一个根日志对象派生出的 Logger 共用输出（控制台、切分文件、回调函数）和内存环形缓冲区，
+// 每个 Logger 可以带模块ID和固定字段，并可按模块单独设置最低日志级别：
+//
+//	root, _ := xlog.New(xlog.Options{File: "logs/app.log", MaxSize: 10 << 20, MaxBackups: 7})
+//	log := root.Module("memopt").With("pid", os.Getpid())
+//	log.Info("memory optimized", "freed", n)
+//
+// Logger 的方法对 nil 接收者安全，未注入日志的模块调用时直接忽略。
+package xlog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 日志级别
+type Level int8
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal
+)
+
+var levelNames = [...]string{"debug", "info", "warn", "error", "fatal"}
+
+func (l Level) String() string {
+	if l >= LevelDebug && l <= LevelFatal {
+		return levelNames[l]
+	}
+	return fmt.Sprintf("level(%d)", int8(l))
+}
+
+// ParseLevel 解析日志级别名称（不区分大小写），warning 等同于 warn
+func ParseLevel(s string) (Level, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, true
+	case "info":
+		return LevelInfo, true
+	case "warn", "warning":
+		return LevelWarn, true
+	case "error":
+		return LevelError, true
+	case "fatal":
+		return LevelFatal, true
+	}
+	return LevelInfo, false
+}
+
+// 结构化字段
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// 一条日志
+type Entry struct {
+	Time   time.Time
+	Level  Level
+	Module string
+	Msg    string
+	Fields []Field
+}
+
+// 文本格式：2006-01-02 15:04:05.000 [info] [module] msg key=value ...
+func (e Entry) String() string {
+	var b strings.Builder
+	b.WriteString(e.Time.Format("2006-01-02 15:04:05.000"))
+	b.WriteString(" [")
+	b.WriteString(e.Level.String())
+	b.WriteString("] ")
+	b.WriteString(e.line())
+	return b.String()
+}
+
+// 不含时间和级别的部分
+func (e Entry) line() string {
+	var b strings.Builder
+	if e.Module != "" {
+		b.WriteString("[")
+		b.WriteString(e.Module)
+		b.WriteString("] ")
+	}
+	b.WriteString(e.Msg)
+	for _, f := range e.Fields {
+		b.WriteString(" ")
+		b.WriteString(f.Key)
+		b.WriteString("=")
+		b.WriteString(formatValue(f.Value))
+	}
+	return b.String()
+}
+
+// 字段值含空格等字符时加引号
+func formatValue(v interface{}) string {
+	var s string
+	switch val := v.(type) {
+	case error:
+		s = val.Error()
+	case time.Duration:
+		s = val.String()
+	default:
+		s = fmt.Sprint(val)
+	}
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"=") {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
+
+// 根日志配置
+type Options struct {
+	Level      Level         // 默认最低级别
+	Console    bool          // 同时输出到标准输出
+	File       string        // 日志文件路径，为空时不写文件
+	MaxSize    int64         // 单个日志文件最大字节数，超过后切分，0 表示不按大小切分
+	Daily      bool          // 跨天时切分
+	MaxBackups int           // 最多保留的历史文件数，0 表示不限制
+	MaxAge     time.Duration // 历史文件最长保留时间，0 表示不限制
+	RingSize   int           // 内存环形缓冲区保留的条数，0 时使用默认值 1000
+}
+
+// 默认环形缓冲区大小
+const defaultRingSize = 1000
+
+// 根日志对象的共享状态
+type root struct {
+	lock    sync.Mutex
+	level   Level
+	modules map[string]Level // 按模块设置的最低级别
+	console io.Writer
+	file    *rotatingFile
+	sink    func(level, msg string) // FromFunc 的回调输出
+	ring    *ring
+}
+
+// Logger 日志对象，可派生带模块ID或字段的子日志对象，并发安全
+type Logger struct {
+	root   *root
+	module string
+	fields []Field
+}
+
+// New 按配置创建根日志对象
+func New(opts Options) (*Logger, error) {
+	r := &root{modules: make(map[string]Level), ring: newRing(defaultRingSize)}
+	l := &Logger{root: r}
+	if err := l.Configure(opts); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// FromFunc 创建输出到回调函数的日志对象，兼容旧的 func(level, msg string) 日志函数
+func FromFunc(fn func(level, msg string)) *Logger {
+	r := &root{modules: make(map[string]Level), ring: newRing(defaultRingSize), level: LevelDebug, sink: fn}
+	return &Logger{root: r}
+}
+
+// Configure 修改根日志配置（级别、控制台、文件切分、缓冲区大小），可在运行中调用。
+// 按模块设置的级别和回调输出保持不变。
+func (l *Logger) Configure(opts Options) error {
+	if l == nil {
+		return nil
+	}
+	var file *rotatingFile
+	if opts.File != "" {
+		var err error
+		file, err = openRotatingFile(opts.File, opts.MaxSize, opts.Daily, opts.MaxBackups, opts.MaxAge)
+		if err != nil {
+			return err
+		}
+	}
+	size := opts.RingSize
+	if size <= 0 {
+		size = defaultRingSize
+	}
+
+	r := l.root
+	r.lock.Lock()
+	old := r.file
+	r.level = opts.Level
+	r.file = file
+	r.console = nil
+	if opts.Console {
+		r.console = os.Stdout
+	}
+	r.ring.resize(size)
+	r.lock.Unlock()
+
+	if old != nil {
+		return old.Close()
+	}
+	return nil
+}
+
+// Close 关闭日志文件
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	r := l.root
+	r.lock.Lock()
+	file := r.file
+	r.file = nil
+	r.lock.Unlock()
+	if file != nil {
+		return file.Close()
+	}
+	return nil
+}
+
+// Module 派生带模块ID的日志对象
+func (l *Logger) Module(id string) *Logger {
+	if l == nil {
+		return nil
+	}
+	return &Logger{root: l.root, module: id, fields: l.fields}
+}
+
+// With 派生带固定字段的日志对象，kv 为交替的键和值
+func (l *Logger) With(kv ...interface{}) *Logger {
+	if l == nil {
+		return nil
+	}
+	fields := make([]Field, 0, len(l.fields)+len(kv)/2)
+	fields = append(fields, l.fields...)
+	fields = append(fields, toFields(kv)...)
+	return &Logger{root: l.root, module: l.module, fields: fields}
+}
+
+// SetLevel 设置默认最低级别
+func (l *Logger) SetLevel(level Level) {
+	if l == nil {
+		return
+	}
+	l.root.lock.Lock()
+	l.root.level = level
+	l.root.lock.Unlock()
+}
+
+// SetModuleLevel 设置指定模块的最低级别
+func (l *Logger) SetModuleLevel(module string, level Level) {
+	if l == nil {
+		return
+	}
+	l.root.lock.Lock()
+	l.root.modules[module] = level
+	l.root.lock.Unlock()
+}
+
+// ResetModuleLevel 清除指定模块的级别设置，恢复使用默认级别
+func (l *Logger) ResetModuleLevel(module string) {
+	if l == nil {
+		return
+	}
+	l.root.lock.Lock()
+	delete(l.root.modules, module)
+	l.root.lock.Unlock()
+}
+
+// Enabled 判断该级别的日志是否会被输出
+func (l *Logger) Enabled(level Level) bool {
+	if l == nil {
+		return false
+	}
+	l.root.lock.Lock()
+	defer l.root.lock.Unlock()
+	return level >= l.root.minLevel(l.module)
+}
+
+// 模块的最低级别，调用方需持有锁
+func (r *root) minLevel(module string) Level {
+	if level, ok := r.modules[module]; ok {
+		return level
+	}
+	return r.level
+}
+
+func (l *Logger) Debug(msg string, kv ...interface{}) { l.Log(LevelDebug, msg, kv...) }
+func (l *Logger) Info(msg string, kv ...interface{})  { l.Log(LevelInfo, msg, kv...) }
+func (l *Logger) Warn(msg string, kv ...interface{})  { l.Log(LevelWarn, msg, kv...) }
+func (l *Logger) Error(msg string, kv ...interface{}) { l.Log(LevelError, msg, kv...) }
+
+// Fatal 记录致命错误，不会退出进程
+func (l *Logger) Fatal(msg string, kv ...interface{}) { l.Log(LevelFatal, msg, kv...) }
+
+// Log 记录一条日志，kv 为交替的键和值
+func (l *Logger) Log(level Level, msg string, kv ...interface{}) {
+	if l == nil {
+		return
+	}
+	fields := l.fields
+	if len(kv) > 0 {
+		fields = append(append([]Field(nil), l.fields...), toFields(kv)...)
+	}
+	entry := Entry{Time: time.Now(), Level: level, Module: l.module, Msg: msg, Fields: fields}
+
+	r := l.root
+	r.lock.Lock()
+	if level < r.minLevel(l.module) {
+		r.lock.Unlock()
+		return
+	}
+	r.ring.add(entry)
+	line := entry.String() + "\n"
+	if r.console != nil {
+		_, _ = io.WriteString(r.console, line)
+	}
+	if r.file != nil {
+		if err := r.file.write(entry.Time, []byte(line)); err != nil {
+			fmt.Fprintf(os.Stderr, "xlog: write log file failed: %v\n", err)
+		}
+	}
+	sink := r.sink
+	r.lock.Unlock()
+
+	if sink != nil {
+		sink(level.String(), entry.line())
+	}
+}
+
+// Func 返回 func(level, msg string) 形式的日志函数，用于兼容旧接口。
+// 无法识别的级别按 info 记录。
+func (l *Logger) Func() func(level, msg string) {
+	return func(level, msg string) {
+		lv, ok := ParseLevel(level)
+		if !ok {
+			l.Log(LevelInfo, msg, "level", level)
+			return
+		}
+		l.Log(lv, msg)
+	}
+}
+
+// Tail 返回内存缓冲区中最近的 n 条日志（按时间先后），n <= 0 时返回全部
+func (l *Logger) Tail(n int) []Entry {
+	if l == nil {
+		return nil
+	}
+	l.root.lock.Lock()
+	defer l.root.lock.Unlock()
+	return l.root.ring.tail(n)
+}
+
+// Follow 订阅之后写入的日志，buffer 为通道缓冲大小，读取不及时的日志会被丢弃。
+// 调用返回的函数取消订阅并关闭通道。
+func (l *Logger) Follow(buffer int) (<-chan Entry, func()) {
+	ch := make(chan Entry, buffer)
+	if l == nil {
+		close(ch)
+		return ch, func() {}
+	}
+	r := l.root
+	r.lock.Lock()
+	id := r.ring.follow(ch)
+	r.lock.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			r.lock.Lock()
+			r.ring.unfollow(id)
+			r.lock.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// ModuleLevels 返回按模块设置的级别，用于展示
+func (l *Logger) ModuleLevels() map[string]Level {
+	if l == nil {
+		return nil
+	}
+	l.root.lock.Lock()
+	defer l.root.lock.Unlock()
+	levels := make(map[string]Level, len(l.root.modules))
+	for k, v := range l.root.modules {
+		levels[k] = v
+	}
+	return levels
+}
+
+// 将交替的键值转换为字段，键不是字符串时转为字符串，缺少值时记为 (MISSING)
+func toFields(kv []interface{}) []Field {
+	fields := make([]Field, 0, (len(kv)+1)/2)
+	for i := 0; i < len(kv); i += 2 {
+		key := fmt.Sprint(kv[i])
+		if i+1 >= len(kv) {
+			fields = append(fields, Field{Key: key, Value: "(MISSING)"})
+			break
+		}
+		fields = append(fields, Field{Key: key, Value: kv[i+1]})
+	}
+	return fields
+}
@@ -0,0 +1,212 @@
+package xlog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readLines(t *testing.T, files []string) []string {
+	t.Helper()
+	var lines []string
+	for _, name := range files {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")...)
+	}
+	return lines
+}
+
+// 超过大小时切分，每行完整写入同一个文件，历史文件按时间排列
+func TestSizeRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+	log, err := New(Options{Level: LevelInfo, File: path, MaxSize: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		log.Info(fmt.Sprintf("message %02d", i), "padding", strings.Repeat("x", 20))
+	}
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := RotatedFiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) < 3 || files[len(files)-1] != path {
+		t.Fatalf("files = %v, want several backups followed by the current file", files)
+	}
+	for _, name := range files {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() > 200 {
+			t.Errorf("%s is %d bytes, larger than MaxSize", name, info.Size())
+		}
+	}
+	lines := readLines(t, files)
+	if len(lines) != 10 {
+		t.Fatalf("got %d lines across files, want 10:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+	for i, line := range lines {
+		if !strings.Contains(line, fmt.Sprintf("message %02d", i)) {
+			t.Fatalf("line %d = %q, lines out of order", i, line)
+		}
+	}
+}
+
+// 重新打开时追加写入，不清空已有日志
+func TestReopenAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	for i := 0; i < 2; i++ {
+		log, err := New(Options{Level: LevelInfo, File: path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		log.Info("run", "n", i)
+		log.Close()
+	}
+	if lines := readLines(t, []string{path}); len(lines) != 2 {
+		t.Fatalf("lines = %q, want both runs", lines)
+	}
+}
+
+// 按数量和时间清理历史文件
+func TestBackupRetention(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	// 过期的历史文件在打开时删除
+	old := filepath.Join(dir, "app-20200101-000000.log")
+	if err := os.WriteFile(old, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(old, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	log, err := New(Options{Level: LevelInfo, File: path, MaxSize: 100, MaxBackups: 2, MaxAge: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Fatalf("expired backup kept: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		log.Info(fmt.Sprintf("message %02d", i), "padding", strings.Repeat("x", 40))
+	}
+	log.Close()
+
+	files, err := RotatedFiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("files = %v, want 2 backups and the current file", files)
+	}
+	if lines := readLines(t, []string{path}); !strings.Contains(lines[len(lines)-1], "message 09") {
+		t.Fatalf("current file does not end with the last message: %q", lines)
+	}
+}
+
+// 环形缓冲区只保留最近的日志，修改容量时保留最近的日志
+func TestRingBuffer(t *testing.T) {
+	log, err := New(Options{Level: LevelInfo, RingSize: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Debug("filtered")
+	for i := 0; i < 5; i++ {
+		log.Module("m").Info(fmt.Sprint(i))
+	}
+	msgs := func(entries []Entry) string {
+		var s []string
+		for _, e := range entries {
+			s = append(s, e.Msg)
+		}
+		return strings.Join(s, " ")
+	}
+	if got := msgs(log.Tail(0)); got != "2 3 4" {
+		t.Fatalf("Tail(0) = %q, want 2 3 4", got)
+	}
+	if got := msgs(log.Tail(2)); got != "3 4" {
+		t.Fatalf("Tail(2) = %q, want 3 4", got)
+	}
+	if e := log.Tail(1)[0]; e.Module != "m" || e.Level != LevelInfo {
+		t.Fatalf("entry = %+v", e)
+	}
+
+	if err := log.Configure(Options{Level: LevelInfo, RingSize: 5}); err != nil {
+		t.Fatal(err)
+	}
+	log.Info("5")
+	if got := msgs(log.Tail(0)); got != "2 3 4 5" {
+		t.Fatalf("after growing Tail(0) = %q, want 2 3 4 5", got)
+	}
+	if err := log.Configure(Options{Level: LevelInfo, RingSize: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if got := msgs(log.Tail(0)); got != "4 5" {
+		t.Fatalf("after shrinking Tail(0) = %q, want 4 5", got)
+	}
+}
+
+// 订阅之后写入的日志，读取不及时时丢弃而不阻塞写日志，取消后关闭通道
+func TestFollow(t *testing.T) {
+	log, err := New(Options{Level: LevelInfo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Info("before")
+	ch, cancel := log.Follow(2)
+	slow, cancelSlow := log.Follow(1)
+	defer cancelSlow()
+
+	done := make(chan struct{})
+	go func() {
+		log.Info("a")
+		log.Debug("filtered")
+		log.Warn("b")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("logging blocked on a slow follower")
+	}
+	for _, want := range []string{"a", "b"} {
+		select {
+		case e := <-ch:
+			if e.Msg != want {
+				t.Fatalf("got %q, want %q", e.Msg, want)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("did not receive %q", want)
+		}
+	}
+	if e := <-slow; e.Msg != "a" || len(slow) != 0 {
+		t.Fatalf("slow follower got %q and %d more, want only a", e.Msg, len(slow))
+	}
+
+	cancel()
+	cancel()
+	log.Info("after")
+	if _, ok := <-ch; ok {
+		t.Fatal("channel not closed after cancel")
+	}
+
+	var nilLog *Logger
+	nilCh, nilCancel := nilLog.Follow(1)
+	nilCancel()
+	if _, ok := <-nilCh; ok {
+		t.Fatal("nil logger Follow returned an open channel")
+	}
+}
@@ -1,37 +1,32 @@
 package main
 
 import (
+	"fmt"
 	"net"
 	"os"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"gopkg.in/yaml.v3"
 
+	"myMod/netcfg"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 )
 
-type NetConfig struct {
-	Name     string   `yaml:"name"`
-	Desc     string   `yaml:"desc"`
-	Adapter  string   `yaml:"adapter"`
-	DHCP     bool     `yaml:"dhcp"`
-	DNSDHCP  bool     `yaml:"dnsdhcp"`
-	IP       string   `yaml:"ip"`
-	Netmask  string   `yaml:"netmask"`
-	Gateway  string   `yaml:"gateway"`
-	DNS      []string `yaml:"dns"`
-	MTU      int      `yaml:"mtu"`
-	Metric   int      `yaml:"metric"`
-	FlushDNS bool     `yaml:"flushDNS"`
-}
+// IPv6 模式选项，第一项表示不修改 IPv6 设置
+var ipv6Modes = []string{"不修改", "slaac", "dhcpv6", "static", "disabled"}
 
+// 配置文件，配置方案与网卡配置服务共用 netcfg.Profile
 type ConfigFile struct {
-	Configs []NetConfig `yaml:"configs"`
+	Configs []netcfg.Profile `yaml:"configs"`
 }
 
 // 配置表单控件结构体
@@ -48,11 +43,25 @@ type ConfigForm struct {
 	MtuEntry      *widget.Entry
 	MetricEntry   *widget.Entry
 	FlushCheck    *widget.Check
+
+	Ipv6ModeSelect *widget.Select
+	Ipv6Entry      *widget.Entry
+	Ipv6GwEntry    *widget.Entry
+	Ipv6DnsEntry   *widget.Entry
+
+	AddrEntry  *widget.Entry
+	RouteEntry *widget.Entry
+
+	PingCheck    *widget.Check
+	ResolveEntry *widget.Entry
+	TcpEntry     *widget.Entry
+	TimeoutEntry *widget.Entry
+	ConfirmEntry *widget.Entry
 }
 
-var selected *NetConfig   // 选中的配置
-var selectedIndex int = 0 // 选中的配置索引
-var path string           // 配置文件路径
+var selected *netcfg.Profile // 选中的配置
+var selectedIndex int = 0    // 选中的配置索引
+var path string              // 配置文件路径
 
 func main() {
 	// 获取当前项目路径
@@ -100,10 +109,21 @@ func main() {
 		widget.NewLabel("IP 地址："), cfgDetailsForm.IpEntry,
 		widget.NewLabel("子网掩码："), cfgDetailsForm.MaskEntry,
 		widget.NewLabel("网关："), cfgDetailsForm.GwEntry,
+		widget.NewLabel("额外 IP 地址（带前缀长度，如 10.10.0.5/24，逗号分隔）："), cfgDetailsForm.AddrEntry,
 		widget.NewLabel("DNS（逗号分隔）："), cfgDetailsForm.DnsEntry,
 		widget.NewLabel("MTU："), cfgDetailsForm.MtuEntry,
 		widget.NewLabel("Metric："), cfgDetailsForm.MetricEntry,
 		cfgDetailsForm.FlushCheck,
+		widget.NewLabel("IPv6 模式："), cfgDetailsForm.Ipv6ModeSelect,
+		widget.NewLabel("IPv6 地址（带前缀长度，逗号分隔）："), cfgDetailsForm.Ipv6Entry,
+		widget.NewLabel("IPv6 网关："), cfgDetailsForm.Ipv6GwEntry,
+		widget.NewLabel("IPv6 DNS（逗号分隔）："), cfgDetailsForm.Ipv6DnsEntry,
+		widget.NewLabel("静态路由（每行一条：目标网段 网关 跃点数，网关和跃点数可省略）："), cfgDetailsForm.RouteEntry,
+		widget.NewLabel("应用后检查（失败时自动恢复原配置）："), cfgDetailsForm.PingCheck,
+		widget.NewLabel("解析域名："), cfgDetailsForm.ResolveEntry,
+		widget.NewLabel("连接 TCP 地址（主机:端口）："), cfgDetailsForm.TcpEntry,
+		widget.NewLabel("检查超时（秒，0 为默认）："), cfgDetailsForm.TimeoutEntry,
+		widget.NewLabel("确认时间（秒，超时未确认恢复原配置，0 为不需要确认）："), cfgDetailsForm.ConfirmEntry,
 	)
 
 	//######################################################################
@@ -139,10 +159,12 @@ func main() {
 			cfgDetailsForm.IpEntry.Disable()
 			cfgDetailsForm.MaskEntry.Disable()
 			cfgDetailsForm.GwEntry.Disable()
+			cfgDetailsForm.AddrEntry.Disable()
 		} else {
 			cfgDetailsForm.IpEntry.Enable()
 			cfgDetailsForm.MaskEntry.Enable()
 			cfgDetailsForm.GwEntry.Enable()
+			cfgDetailsForm.AddrEntry.Enable()
 		}
 
 	}
@@ -156,6 +178,22 @@ func main() {
 		}
 	}
 
+	// 只有静态模式可填写 IPv6 地址和网关，禁用或不修改时 DNS 也不可填写
+	cfgDetailsForm.Ipv6ModeSelect.OnChanged = func(mode string) {
+		if mode == "static" {
+			cfgDetailsForm.Ipv6Entry.Enable()
+			cfgDetailsForm.Ipv6GwEntry.Enable()
+		} else {
+			cfgDetailsForm.Ipv6Entry.Disable()
+			cfgDetailsForm.Ipv6GwEntry.Disable()
+		}
+		if mode == "slaac" || mode == "dhcpv6" || mode == "static" {
+			cfgDetailsForm.Ipv6DnsEntry.Enable()
+		} else {
+			cfgDetailsForm.Ipv6DnsEntry.Disable()
+		}
+	}
+
 	// 右侧配置区域容器
 	cfgDetails := container.NewBorder(
 		widget.NewLabel("配置详情"), nil, nil, nil,
@@ -165,14 +203,18 @@ func main() {
 
 	cfgManageBtnContainer := container.NewHBox(
 		widget.NewButton("增加", func() { addCfgBtnClick(cfg, cfgNameList, cfgDetailsForm) }),
-		widget.NewButton("删除", func() { delCfgBtnClick(cfg, cfgNameList, cfgDetailsForm) }),
+		widget.NewButton("删除", func() { delCfgBtnClick(cfg, cfgNameList, cfgDetailsForm, myWin) }),
 		widget.NewButton("向前插入", func() { addCfgBtnBeforeClick(cfg, cfgNameList, cfgDetailsForm) }),
 	)
 	fixedArea := container.NewVBox(cfgManageBtnContainer)
 	// 右侧配置按钮区域
 	cfgDetailsBtnContainer := container.NewHBox(
 		layout.NewSpacer(),
-		widget.NewButton("保存", func() { saveCfgBtnClick(cfg, path) }),
+		widget.NewButton("保存当前配置", func() { captureCfgBtnClick(cfg, cfgNameList, cfgDetailsForm, myWin) }),
+		widget.NewButton("保存", func() {
+			applyChanges(cfgDetailsForm)
+			saveCfgBtnClick(cfg, path, myWin)
+		}),
 		//widget.NewButton("取消", cancelCfgBtnClick),
 	)
 	// 左侧组合容器
@@ -213,6 +255,20 @@ func NewConfigForm() *ConfigForm {
 		MtuEntry:      widget.NewEntry(),                    // MTU 输入框
 		MetricEntry:   widget.NewEntry(),                    // Metric 输入框
 		FlushCheck:    widget.NewCheck("Flush DNS", nil),    // Flush DNS 复选框
+
+		Ipv6ModeSelect: widget.NewSelect(ipv6Modes, nil), // IPv6 模式选择框
+		Ipv6Entry:      widget.NewEntry(),                // IPv6 地址输入框（逗号分隔）
+		Ipv6GwEntry:    widget.NewEntry(),                // IPv6 网关输入框
+		Ipv6DnsEntry:   widget.NewEntry(),                // IPv6 DNS 输入框（逗号分隔）
+
+		AddrEntry:  widget.NewEntry(),          // 额外 IP 地址输入框（逗号分隔）
+		RouteEntry: widget.NewMultiLineEntry(), // 静态路由输入框（每行一条）
+
+		PingCheck:    widget.NewCheck("ping 网关", nil), // ping 网关复选框
+		ResolveEntry: widget.NewEntry(),               // 解析域名输入框
+		TcpEntry:     widget.NewEntry(),               // TCP 地址输入框
+		TimeoutEntry: widget.NewEntry(),               // 检查超时输入框
+		ConfirmEntry: widget.NewEntry(),               // 确认时间输入框
 	}
 }
 
@@ -229,6 +285,49 @@ func parseDNS(dnsStr string) []string {
 	return res
 }
 
+// 解析地址列表，逗号、空格或换行分隔，保留原文，由 Validate 检查是否带前缀长度
+func parseAddresses(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == '，' || unicode.IsSpace(r)
+	})
+}
+
+// 解析静态路由，每行一条：目标网段 [网关] [跃点数]，无法识别的字段作为网关，由 Validate 报告
+func parseRoutes(text string) []netcfg.Route {
+	var routes []netcfg.Route
+	for _, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		r := netcfg.Route{Dest: fields[0]}
+		for _, f := range fields[1:] {
+			if metric, err := strconv.Atoi(f); err == nil {
+				r.Metric = metric
+			} else {
+				r.Gateway = f
+			}
+		}
+		routes = append(routes, r)
+	}
+	return routes
+}
+
+func routesToString(routes []netcfg.Route) string {
+	var lines []string
+	for _, r := range routes {
+		line := r.Dest
+		if r.Gateway != "" {
+			line += " " + r.Gateway
+		}
+		if r.Metric > 0 {
+			line += " " + strconv.Itoa(r.Metric)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
 // 获取网卡列表
 func getInterfaces() []string {
 	// 获取网卡列表
@@ -267,7 +366,7 @@ func applyChanges(cfgDetailsForm *ConfigForm) {
 	selected.Desc = cfgDetailsForm.DescEntry.Text
 	selected.Adapter = cfgDetailsForm.AdapterSelect.Selected
 	selected.DHCP = cfgDetailsForm.DhcpCheck.Checked
-	selected.DNSDHCP = cfgDetailsForm.DnsdhcpCheck.Checked
+	selected.DNSdhcp = cfgDetailsForm.DnsdhcpCheck.Checked
 	selected.IP = cfgDetailsForm.IpEntry.Text
 	selected.Netmask = cfgDetailsForm.MaskEntry.Text
 	selected.Gateway = cfgDetailsForm.GwEntry.Text
@@ -275,16 +374,41 @@ func applyChanges(cfgDetailsForm *ConfigForm) {
 	selected.MTU = parseInt(cfgDetailsForm.MtuEntry.Text, 1500)    // 默认值 1500
 	selected.Metric = parseInt(cfgDetailsForm.MetricEntry.Text, 0) // 默认值 0
 	selected.FlushDNS = cfgDetailsForm.FlushCheck.Checked
+	selected.Addresses = nil
+	if !selected.DHCP {
+		selected.Addresses = parseAddresses(cfgDetailsForm.AddrEntry.Text)
+	}
+	selected.Routes = parseRoutes(cfgDetailsForm.RouteEntry.Text)
+	selected.Checks = netcfg.Checks{
+		PingGateway: cfgDetailsForm.PingCheck.Checked,
+		Resolve:     strings.TrimSpace(cfgDetailsForm.ResolveEntry.Text),
+		TCP:         strings.TrimSpace(cfgDetailsForm.TcpEntry.Text),
+		Timeout:     parseInt(cfgDetailsForm.TimeoutEntry.Text, 0),
+		Confirm:     parseInt(cfgDetailsForm.ConfirmEntry.Text, 0),
+	}
+	selected.IPv6Mode = ""
+	if cfgDetailsForm.Ipv6ModeSelect.SelectedIndex() > 0 {
+		selected.IPv6Mode = cfgDetailsForm.Ipv6ModeSelect.Selected
+	}
+	// 只保存当前模式可用的字段
+	selected.IPv6, selected.IPv6Gateway, selected.IPv6DNS = nil, "", nil
+	if selected.IPv6Mode == "static" {
+		selected.IPv6 = parseAddresses(cfgDetailsForm.Ipv6Entry.Text)
+		selected.IPv6Gateway = strings.TrimSpace(cfgDetailsForm.Ipv6GwEntry.Text)
+	}
+	if selected.IPv6Mode != "" && selected.IPv6Mode != "disabled" {
+		selected.IPv6DNS = parseDNS(cfgDetailsForm.Ipv6DnsEntry.Text)
+	}
 }
 
 // 更新表单数据函数
-func updateForm(c *NetConfig, cfgDetailsForm *ConfigForm) {
+func updateForm(c *netcfg.Profile, cfgDetailsForm *ConfigForm) {
 	selected = c
 	cfgDetailsForm.CfgName.SetText(c.Name)
 	cfgDetailsForm.DescEntry.SetText(c.Desc)
 	cfgDetailsForm.AdapterSelect.SetSelected(c.Adapter)
 	cfgDetailsForm.DhcpCheck.SetChecked(c.DHCP)
-	cfgDetailsForm.DnsdhcpCheck.SetChecked(c.DNSDHCP)
+	cfgDetailsForm.DnsdhcpCheck.SetChecked(c.DNSdhcp)
 	cfgDetailsForm.IpEntry.SetText(c.IP)
 	cfgDetailsForm.MaskEntry.SetText(c.Netmask)
 	cfgDetailsForm.GwEntry.SetText(c.Gateway)
@@ -292,6 +416,21 @@ func updateForm(c *NetConfig, cfgDetailsForm *ConfigForm) {
 	cfgDetailsForm.MtuEntry.SetText(strconv.Itoa(c.MTU))
 	cfgDetailsForm.MetricEntry.SetText(strconv.Itoa(c.Metric))
 	cfgDetailsForm.FlushCheck.SetChecked(c.FlushDNS)
+	if c.IPv6Mode == "" {
+		cfgDetailsForm.Ipv6ModeSelect.SetSelectedIndex(0)
+	} else {
+		cfgDetailsForm.Ipv6ModeSelect.SetSelected(c.IPv6Mode)
+	}
+	cfgDetailsForm.Ipv6Entry.SetText(strings.Join(c.IPv6, ", "))
+	cfgDetailsForm.Ipv6GwEntry.SetText(c.IPv6Gateway)
+	cfgDetailsForm.Ipv6DnsEntry.SetText(dnsListToString(c.IPv6DNS))
+	cfgDetailsForm.AddrEntry.SetText(strings.Join(c.Addresses, ", "))
+	cfgDetailsForm.RouteEntry.SetText(routesToString(c.Routes))
+	cfgDetailsForm.PingCheck.SetChecked(c.Checks.PingGateway)
+	cfgDetailsForm.ResolveEntry.SetText(c.Checks.Resolve)
+	cfgDetailsForm.TcpEntry.SetText(c.Checks.TCP)
+	cfgDetailsForm.TimeoutEntry.SetText(strconv.Itoa(c.Checks.Timeout))
+	cfgDetailsForm.ConfirmEntry.SetText(strconv.Itoa(c.Checks.Confirm))
 }
 
 // 清空表单字段
@@ -308,6 +447,17 @@ func clearForm(cfgDetailsForm *ConfigForm) {
 	cfgDetailsForm.MtuEntry.SetText("")
 	cfgDetailsForm.MetricEntry.SetText("")
 	cfgDetailsForm.FlushCheck.SetChecked(false)
+	cfgDetailsForm.Ipv6ModeSelect.SetSelectedIndex(0)
+	cfgDetailsForm.Ipv6Entry.SetText("")
+	cfgDetailsForm.Ipv6GwEntry.SetText("")
+	cfgDetailsForm.Ipv6DnsEntry.SetText("")
+	cfgDetailsForm.AddrEntry.SetText("")
+	cfgDetailsForm.RouteEntry.SetText("")
+	cfgDetailsForm.PingCheck.SetChecked(false)
+	cfgDetailsForm.ResolveEntry.SetText("")
+	cfgDetailsForm.TcpEntry.SetText("")
+	cfgDetailsForm.TimeoutEntry.SetText("")
+	cfgDetailsForm.ConfirmEntry.SetText("")
 }
 
 // 在指定索引前插入一个元素
@@ -346,12 +496,12 @@ func addCfgBtnClick(cfg *ConfigFile, cfgNameList *widget.List, cfgDetailsForm *C
 	// 清空表单字段
 
 	// 创建一个新的空配置
-	newCfg := NetConfig{
+	newCfg := netcfg.Profile{
 		Name:     "新配置",
 		Desc:     "",
 		Adapter:  "",
 		DHCP:     false,
-		DNSDHCP:  false,
+		DNSdhcp:  false,
 		IP:       "",
 		Netmask:  "",
 		Gateway:  "",
@@ -382,12 +532,12 @@ func addCfgBtnBeforeClick(cfg *ConfigFile, cfgNameList *widget.List, cfgDetailsF
 	// 清空表单字段
 
 	// 创建一个新的空配置
-	newCfg := NetConfig{
+	newCfg := netcfg.Profile{
 		Name:     "新配置",
 		Desc:     "",
 		Adapter:  "",
 		DHCP:     false,
-		DNSDHCP:  false,
+		DNSdhcp:  false,
 		IP:       "",
 		Netmask:  "",
 		Gateway:  "",
@@ -407,8 +557,45 @@ func addCfgBtnBeforeClick(cfg *ConfigFile, cfgNameList *widget.List, cfgDetailsF
 	cfgNameList.Select(selectedIndex)
 }
 
+// 保存当前配置按钮事件处理函数，读取所选网卡的当前配置，作为新配置添加到选中配置之后
+func captureCfgBtnClick(cfg *ConfigFile, cfgNameList *widget.List, cfgDetailsForm *ConfigForm, win fyne.Window) {
+	adapter := cfgDetailsForm.AdapterSelect.Selected
+	if adapter == "" {
+		dialog.ShowInformation("提示", "请先选择网卡", win)
+		return
+	}
+	reader, err := netcfg.NewReader(netcfg.DefaultKind(), netcfg.ExecRunner{})
+	if err != nil {
+		dialog.ShowError(err, win)
+		return
+	}
+	p, err := reader.Read(adapter)
+	if err != nil {
+		dialog.ShowError(err, win)
+		return
+	}
+	// 读取到的配置方案原样保存，包括 IPv6 设置；Reader 不读取静态路由，新配置不含路由
+	newCfg := p
+	newCfg.Name = adapter + " 当前配置"
+	newCfg.Desc = time.Now().Format("2006-01-02 15:04") + " 读取"
+	if err := newCfg.Validate(); err != nil {
+		dialog.ShowError(fmt.Errorf("网卡 %s 的当前配置不合法，未保存: %w", adapter, err), win)
+		return
+	}
+	applyChanges(cfgDetailsForm)
+	if selectedIndex < 0 || selectedIndex >= len(cfg.Configs) {
+		selectedIndex = -1
+	}
+	InsertAfter(&cfg.Configs, selectedIndex, newCfg)
+	selectedIndex += 1
+	cfgNameList.Refresh()
+	cfgNameList.Select(selectedIndex)
+	// 只检查读取到的配置，其他配置未填写完整时不影响保存
+	writeConfig(cfg, path, win)
+}
+
 // 删除按钮事件处理函数
-func delCfgBtnClick(cfg *ConfigFile, cfgNameList *widget.List, cfgDetailsForm *ConfigForm) {
+func delCfgBtnClick(cfg *ConfigFile, cfgNameList *widget.List, cfgDetailsForm *ConfigForm, win fyne.Window) {
 	//fmt.Println("删除配置")
 
 	// 边界检查
@@ -434,18 +621,28 @@ func delCfgBtnClick(cfg *ConfigFile, cfgNameList *widget.List, cfgDetailsForm *C
 		updateForm(selected, cfgDetailsForm)
 		cfgNameList.Select(selectedIndex)
 	}
-	saveCfgBtnClick(cfg, path)
+	// 删除不修改其他配置，直接保存
+	writeConfig(cfg, path, win)
 	cfgNameList.Refresh()
 }
 
-// 保存按钮事件处理函数
-func saveCfgBtnClick(cfg *ConfigFile, path string) {
-	err := saveConfig(path, cfg)
-	if err != nil {
-		//fmt.Println("保存配置失败:", err)
-		return
+// 保存按钮事件处理函数，只检查正在编辑的配置，不合法时不保存；
+// 其他配置（如新增后尚未填写的）不影响保存，网卡配置服务应用时会拒绝不合法的配置
+func saveCfgBtnClick(cfg *ConfigFile, path string, win fyne.Window) {
+	if selected != nil {
+		if err := selected.Validate(); err != nil {
+			dialog.ShowError(fmt.Errorf("配置 %s 不合法，未保存: %w", selected.Name, err), win)
+			return
+		}
+	}
+	writeConfig(cfg, path, win)
+}
+
+// 写入配置文件，失败时提示
+func writeConfig(cfg *ConfigFile, path string, win fyne.Window) {
+	if err := saveConfig(path, cfg); err != nil {
+		dialog.ShowError(fmt.Errorf("保存配置失败: %w", err), win)
 	}
-	//fmt.Println("保存配置")
 }
 
 // 取消按钮事件处理函数
@@ -1,148 +1,121 @@
 package config
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os/exec"
-	"strings"
+
+	"myMod/netcfg"
 )
 
 // NetworkConfig 用于解析传入的网络配置
-type NetworkConfig struct {
-	Name     string   `json:"Name"`
-	Adapter  string   `json:"Adapter"`
-	DHCP     bool     `json:"DHCP"`
-	DNSdhcp  bool     `json:"DNSdhcp"`
-	IP       string   `json:"IP"`
-	Netmask  string   `json:"Netmask"`
-	Gateway  string   `json:"Gateway"`
-	DNS      []string `json:"DNS"`
-	MTU      int      `json:"MTU"`
-	Metric   int      `json:"Metric"`
-	FlushDNS bool     `json:"FlushDNS"`
+type NetworkConfig = netcfg.Profile
+
+// ConfirmRequest 确认或放弃等待确认的配置
+type ConfirmRequest struct {
+	Action string `json:"Action"` // confirm 保留新配置，rollback 恢复原配置
+	ID     string `json:"ID"`     // 应用配置时返回的 Pending
 }
 
 // ExecutionResult 封装结果信息
 type ResultMessage struct {
-	Success bool   `json:"Success"` //成功失败标识
-	Details string `json:"Details"` //详细信息
-	Other   string `json:"Other"`   //其他信息
+	Success bool   `json:"Success"`           //成功失败标识
+	Details string `json:"Details"`           //详细信息
+	Other   string `json:"Other"`             //其他信息
+	Pending string `json:"Pending,omitempty"` //等待确认的配置ID，需在 Timeout 秒内发送 ConfirmRequest
+	Timeout int    `json:"Timeout,omitempty"` //等待确认的时间（秒）
 }
 
-func runCommand(cmd *exec.Cmd) ResultMessage {
-	// 设置命令行字符集为 UTF-8
-	cmd = exec.Command("cmd", "/C", "chcp 65001 && "+strings.Join(cmd.Args, " "))
-	// 执行命令并获取输出
-	output, err := cmd.CombinedOutput()
-	// 如果执行失败，标记为失败
-	if err != nil {
-		return ResultMessage{Success: false, Details: err.Error(), Other: string(output)}
-	}
-	// 如果执行成功，标记为成功
-	return ResultMessage{Success: true, Details: "命令执行成功", Other: string(output)}
+// 以事务方式应用配置：服务运行在 Windows 上，使用 netsh 修改、PowerShell 读取网卡配置，
+// 应用前保存快照，应用或连通性检查失败、超时未确认时由服务恢复原配置，不依赖客户端存活
+var applier = &netcfg.Applier{
+	Backend:  netcfg.NewNetsh(netcfg.ExecRunner{}),
+	Reader:   netcfg.NewPowerShellReader(netcfg.ExecRunner{}),
+	Verifier: &netcfg.Verifier{Kind: netcfg.KindNetsh, Exec: netcfg.ExecRunner{}},
+	State:    netcfg.NewRouteState("netRoutes.json"),
+	Pending:  netcfg.NewTxState("netPending.json"),
 }
 
-func ConfigureNetwork(config NetworkConfig) ResultMessage {
-	// 网卡名称
-	interfaceName := config.Adapter
-	// TODO:检查网卡是否存在
+// SetRouteStateFile 设置路由记录文件，切换配置时删除上一个配置添加的路由
+func SetRouteStateFile(path string) {
+	applier.State = netcfg.NewRouteState(path)
+}
 
-	// 配置 DHCP
-	if config.DHCP {
-		// dhcp模式
-		cmd := exec.Command("netsh", "interface", "ip", "set", "address", "name="+interfaceName, "source=dhcp")
-		// 命令配置为dhcp
-		result := runCommand(cmd)
-		if !result.Success {
-			// 配置dhcp异常
-			// 检查 Other 字段是否包含 "DHCP is already enabled on this interface"，已配置过dhcp，重新启用网卡
-			if strings.Contains(result.Other, "DHCP is already enabled on this interface") {
-				// 禁用再启用网卡
-				cmd := exec.Command("netsh", "interface", "set", "interface", "name="+interfaceName, "admin=disable")
-				result := runCommand(cmd)
-				if !result.Success {
-					// 禁用网卡失败
-					return ResultMessage{Success: false, Details: "禁用网卡失败，请手动检查！", Other: result.Other}
-				}
-
-				// 启用网卡
-				cmd = exec.Command("netsh", "interface", "set", "interface", "name="+interfaceName, "admin=enable")
-				result = runCommand(cmd)
-				if !result.Success {
-					return ResultMessage{Success: false, Details: "启用网卡失败，请手动检查！", Other: result.Other}
-				}
-
-				// dhcp配置成功，检查dns配置模式，手动/自动获取
-				if !config.DNSdhcp {
-					// 手动配置 DNS
-					for _, dns := range config.DNS {
-						cmd = exec.Command("netsh", "interface", "ip", "set", "dns", "name="+interfaceName, "static", dns, "primary")
-						result := runCommand(cmd)
-						if !result.Success {
-							return ResultMessage{Success: false, Details: "配置 DNS 失败", Other: result.Other}
-						}
-					}
-				} else {
-					//配置dns自动获取
-					cmd = exec.Command("netsh", "interface", "ip", "set", "dns", "name="+interfaceName, "source=dhcp")
-					result := runCommand(cmd)
-					if !result.Success {
-						return ResultMessage{Success: false, Details: "配置 DNS 自动获取失败", Other: result.Other}
-					}
-				}
-			} else {
-				// 其他失败原因，返回失败信息
-				return ResultMessage{Success: false, Details: "配置 DHCP 失败", Other: result.Other}
-			}
-		}
+// SetPendingFile 设置未结束事务的记录文件，服务在应用或等待确认期间退出后据此恢复原配置
+func SetPendingFile(path string) {
+	applier.Pending = netcfg.NewTxState(path)
+}
 
-	} else {
-		// 设置静态 IP 地址
-		cmd := exec.Command("netsh", "interface", "ip", "set", "address", "name="+interfaceName, "static", config.IP, config.Netmask, config.Gateway)
-		result := runCommand(cmd)
-		if !result.Success {
-			return ResultMessage{Success: false, Details: "配置静态 IP 失败", Other: result.Other}
-		}
+// RecoverPending 处理服务上次退出时未结束的事务，应在开始接受配置请求之前调用
+func RecoverPending() error {
+	return applier.Recover()
+}
+
+// SetTimeoutHandler 设置超时未确认、恢复原配置后的回调
+func SetTimeoutHandler(fn func(config NetworkConfig, err error)) {
+	applier.OnTimeout = fn
+}
 
-		// 配置 DNS
-		if !config.DNSdhcp {
-			for _, dns := range config.DNS {
-				cmd = exec.Command("netsh", "interface", "ip", "set", "dns", "name="+interfaceName, "static", dns, "primary")
-				result := runCommand(cmd)
-				if !result.Success {
-					return ResultMessage{Success: false, Details: "配置 DNS 失败", Other: result.Other}
-				}
-			}
+func ConfigureNetwork(config NetworkConfig) ResultMessage {
+	// TODO:检查网卡是否存在
+	id, err := applier.Apply(context.Background(), config)
+	if err != nil {
+		return failure(err)
+	}
+	if id != "" {
+		return ResultMessage{
+			Success: true,
+			Details: fmt.Sprintf("配置成功，请在 %d 秒内确认，否则将恢复原配置", config.Checks.Confirm),
+			Pending: id,
+			Timeout: config.Checks.Confirm,
 		}
 	}
+	return ResultMessage{Success: true, Details: "配置成功"}
+}
 
-	// 配置 MTU
-	cmd := exec.Command("netsh", "interface", "ipv4", "set", "subinterface", interfaceName, "mtu="+fmt.Sprint(config.MTU), "store=persistent")
-	result := runCommand(cmd)
-	if !result.Success {
-		return ResultMessage{Success: false, Details: "配置 MTU 失败", Other: result.Other}
+// 失败时返回失败的步骤和命令输出
+func failure(err error) ResultMessage {
+	other := netcfg.Output(err)
+	var applyErr *netcfg.ApplyError
+	if errors.As(err, &applyErr) && other == "" {
+		other = applyErr.Err.Error()
 	}
-
-	// 配置 Metric
-	cmd = exec.Command("netsh", "interface", "ipv4", "set", "subinterface", interfaceName, "metric="+fmt.Sprint(config.Metric), "store=persistent")
-	result = runCommand(cmd)
-	if !result.Success {
-		return ResultMessage{Success: false, Details: "配置 Metric 失败", Other: result.Other}
+	var txErr *netcfg.TxError
+	switch {
+	case errors.As(err, &txErr):
+		// 应用或检查失败，已尝试恢复原配置
+		return ResultMessage{Success: false, Details: txErr.Error(), Other: other}
+	case applyErr != nil:
+		return ResultMessage{Success: false, Details: applyErr.Step + "失败", Other: other}
 	}
+	return ResultMessage{Success: false, Details: "配置失败", Other: err.Error()}
+}
 
-	// 清除 DNS 缓存
-	if config.FlushDNS {
-		cmd = exec.Command("ipconfig", "/flushdns")
-		result := runCommand(cmd)
-		if !result.Success {
-			return ResultMessage{Success: false, Details: "清除 DNS 缓存失败", Other: result.Other}
+// 确认或放弃等待确认的配置
+func confirm(req ConfirmRequest) ResultMessage {
+	switch req.Action {
+	case "confirm":
+		if err := applier.Confirm(req.ID); err != nil {
+			return ResultMessage{Success: false, Details: "确认配置失败", Other: err.Error()}
+		}
+		return ResultMessage{Success: true, Details: "已保留新配置"}
+	case "rollback":
+		if err := applier.Rollback(req.ID); err != nil {
+			return ResultMessage{Success: false, Details: "恢复原配置失败", Other: err.Error()}
 		}
+		return ResultMessage{Success: true, Details: "已恢复原配置"}
 	}
-
-	return ResultMessage{Success: true, Details: "配置成功"}
+	return ResultMessage{Success: false, Details: "未知操作", Other: req.Action}
 }
 
 func ParseConfigAndConfigure(jsonStr string) ResultMessage {
+	// 确认请求带 Action 字段，否则为网络配置
+	var req ConfirmRequest
+	if err := json.Unmarshal([]byte(jsonStr), &req); err == nil && req.Action != "" {
+		return confirm(req)
+	}
+
 	// 解析 JSON 配置
 	var config NetworkConfig
 	err := json.Unmarshal([]byte(jsonStr), &config)
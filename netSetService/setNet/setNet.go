@@ -2,25 +2,46 @@ package setnet
 
 import (
 	"encoding/json"
-	"log"
 	"os"
 
 	"xyrTools/netSetService/config"
 	"xyrTools/netSetService/handledata"
 
 	"myMod/notify"
+	"myMod/xlog"
 
 	"github.com/Microsoft/go-winio"
 )
 
 func SetNet() {
 	dir, _ := os.Getwd()
-	logFile, err := os.OpenFile(dir+"/xiaoyulog.txt", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	// 追加写日志，超过 5MB 切分，保留最近 5 个历史文件
+	log, err := xlog.New(xlog.Options{
+		Level:      xlog.LevelInfo,
+		File:       dir + "/xiaoyulog.txt",
+		MaxSize:    5 << 20,
+		MaxBackups: 5,
+	})
 	if err != nil {
 		notify.NotifyError(err, "打开日志文件失败")
+		// 日志文件不可用时改为输出到控制台，服务照常运行
+		log, _ = xlog.New(xlog.Options{Level: xlog.LevelInfo, Console: true})
+	}
+	defer log.Close()
+	log = log.Module("netSetService")
+	config.SetRouteStateFile(dir + "/netRoutes.json")
+	config.SetPendingFile(dir + "/netPending.json")
+	config.SetTimeoutHandler(func(cfg config.NetworkConfig, err error) {
+		if err != nil {
+			log.Error("Confirm timeout, failed to restore previous config", "config", cfg.Name, "err", err)
+			return
+		}
+		log.Warn("Confirm timeout, previous config restored", "config", cfg.Name)
+	})
+	// 上次退出时仍在应用或等待确认的配置：未超时则继续等待确认，否则恢复原配置
+	if err := config.RecoverPending(); err != nil {
+		log.Error("Failed to restore config left pending by the previous run", "err", err)
 	}
-	defer logFile.Close()
-	log.SetOutput(logFile)
 	// 管道描述符
 	securityDescriptor := "D:P(A;;GA;;;S-1-5-32-544)(A;;GRGW;;;S-1-5-32-545)"
 	// 配置命名管道
@@ -30,49 +51,50 @@ func SetNet() {
 	pipePath := `\\.\pipe\netCfgPipe`
 	ln, err := winio.ListenPipe(pipePath, netCfgPipeCfg)
 	if err != nil {
-		log.Println("Error listening on pipe:", err)
+		log.Error("Error listening on pipe", "pipe", pipePath, "err", err)
 		os.Exit(1)
 	}
 	defer ln.Close()
-	log.Println("Waiting for connection on", pipePath)
+	log.Info("Waiting for connection", "pipe", pipePath)
 	// 循环等待连接
 	for {
 		conn, err := ln.Accept()
 		if err != nil {
-			log.Println("Error accepting connection:", err)
+			log.Error("Error accepting connection", "err", err)
 			continue
 		}
-		// 读取数据
-		buf := make([]byte, 1024)
+		// 读取数据，含 IPv6 等设置的配置可能超过 1KB
+		buf := make([]byte, 8192)
 		n, err := conn.Read(buf)
 		if err != nil {
-			log.Println("Error reading from connection:", err)
+			log.Error("Error reading from connection", "err", err)
 			continue
 		}
-		log.Printf("Received %d bytes: %s\n", n, string(buf[:n]))
+		log.Debug("Received data", "bytes", n, "data", string(buf[:n]))
 		// 解析数据
 		unpackateData, isUnpackage, unpackageErr := handledata.UnpackageData(buf[:n], "#")
 		if !isUnpackage {
-			log.Println("Error unpackage data:", unpackageErr)
+			log.Error("Error unpackage data", "err", unpackageErr)
 			continue
 		}
-		log.Printf("Data: %s\n", unpackateData)
+		log.Info("Received config", "data", unpackateData)
 		result := config.ParseConfigAndConfigure(unpackateData)
+		log.Info("Config result", "success", result.Success, "details", result.Details, "pending", result.Pending)
 		// 将结果序列化为 JSON 并发送回客户端
 		resultJSON, err := json.Marshal(result)
 		if err != nil {
-			log.Println("Error marshalling result:", err)
+			log.Error("Error marshalling result", "err", err)
 			continue
 		}
 		//打包结果
 		resultStr, packageErr := handledata.PackageData(string(resultJSON), "#")
 		if packageErr != nil {
-			log.Println("Error package data:", packageErr)
+			log.Error("Error package data", "err", packageErr)
 			continue
 		}
 		_, err = conn.Write(resultStr)
 		if err != nil {
-			log.Println("Error writing result to connection:", err)
+			log.Error("Error writing result to connection", "err", err)
 			continue
 		}
 
@@ -0,0 +1,182 @@
+package core
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"xyrTools/xyrTools/modInterfaces"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v2"
+)
+
+// 配置文件变动后等待的稳定时间
+const configDebounce = 300 * time.Millisecond
+
+// WatchConfig 监听 LoadConfig 加载的配置文件，文件内容变化时热加载模块配置。
+// 只有配置发生变化的模块会被重新加载；enabled 开关变化时启动或停止对应模块。
+func (e *CoreEngine) WatchConfig() error {
+	e.lock.Lock()
+	path := e.configPath
+	if path == "" {
+		e.lock.Unlock()
+		return fmt.Errorf("config not loaded")
+	}
+	if e.watchStop != nil {
+		e.lock.Unlock()
+		return fmt.Errorf("config watcher already running")
+	}
+	stop := make(chan struct{})
+	e.watchStop = stop
+	e.lock.Unlock()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	// 监听配置文件所在目录，编辑器保存时常以重命名方式替换文件
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+		// 编辑器保存文件时会连续产生多个事件，等待文件稳定后再读取，避免读到写了一半的内容
+		debounce := time.NewTimer(time.Hour)
+		debounce.Stop()
+		defer debounce.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-debounce.C:
+				e.reloadConfigFile(path)
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+					continue
+				}
+				if filepath.Clean(event.Name) != filepath.Clean(path) {
+					continue
+				}
+				debounce.Reset(configDebounce)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				e.log("error", fmt.Sprintf("Config watcher error: %v", err))
+			}
+		}
+	}()
+	e.log("info", fmt.Sprintf("Watching config file %s", path))
+	return nil
+}
+
+// StopWatchConfig 停止配置文件监听
+func (e *CoreEngine) StopWatchConfig() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.watchStop != nil {
+		close(e.watchStop)
+		e.watchStop = nil
+	}
+}
+
+// 重新读取配置文件，内容未变化（hash 相同）时忽略，格式错误时保留上一次的有效配置
+func (e *CoreEngine) reloadConfigFile(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		e.log("error", fmt.Sprintf("Failed to read config %s: %v", path, err))
+		return
+	}
+	hash := sha256.Sum256(data)
+	e.lock.Lock()
+	unchanged := hash == e.cfgHash
+	e.lock.Unlock()
+	if unchanged {
+		return
+	}
+
+	cfg := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		e.log("error", fmt.Sprintf("Invalid config %s, keeping last good config: %v", path, err))
+		return
+	}
+	if len(cfg) == 0 {
+		e.log("error", fmt.Sprintf("Config %s is empty, keeping last good config", path))
+		return
+	}
+	e.log("info", fmt.Sprintf("Config file %s changed, reloading modules", path))
+
+	e.lock.Lock()
+	e.globalCfg = cfg
+	e.cfgHash = hash
+	e.lock.Unlock()
+	e.applyLogConfig(cfg)
+	e.applyShutdownConfig(cfg)
+	e.applyEventBusConfig(cfg)
+	e.applyConfig(cfg)
+}
+
+// 对比每个模块的新旧配置，只处理配置有变化的模块
+func (e *CoreEngine) applyConfig(cfg map[string]interface{}) {
+	type change struct {
+		id   string
+		inst *modInterfaces.ModuleInstance
+		ctx  modInterfaces.Context
+	}
+	var changes []change
+	for _, id := range e.startOrder() {
+		inst, err := e.instance(id)
+		if err != nil {
+			continue
+		}
+		rawMap, ok := cfg[id].(map[interface{}]interface{})
+		if !ok {
+			e.log("warn", fmt.Sprintf("Module %s config missing or invalid after reload, keeping old config", id))
+			continue
+		}
+		newCfg := convertMap(rawMap)
+		e.applyModuleLogLevel(id, newCfg)
+		inst.Mutex.Lock()
+		ctx := inst.Ctx
+		inst.Mutex.Unlock()
+		if reflect.DeepEqual(ctx.Config, newCfg) {
+			continue
+		}
+		ctx.Config = newCfg
+		changes = append(changes, change{id: id, inst: inst, ctx: ctx})
+	}
+
+	// 先按逆序停止被关闭的模块，保证依赖方先于被依赖方停止
+	for i := len(changes) - 1; i >= 0; i-- {
+		c := changes[i]
+		enabled, _ := c.ctx.Config["enabled"].(bool)
+		if !enabled && statusOf(c.inst).Running {
+			if err := e.StopModule(c.id); err != nil {
+				e.log("error", fmt.Sprintf("Failed to stop disabled module %s: %v", c.id, err))
+			}
+		}
+	}
+
+	// 再按启动顺序注入新配置、重启或启动模块
+	for _, c := range changes {
+		enabled, _ := c.ctx.Config["enabled"].(bool)
+		if err := e.reloadInstance(c.id, c.inst, c.ctx); err != nil {
+			e.log("error", err.Error())
+			continue
+		}
+		if enabled && !statusOf(c.inst).Running {
+			if err := e.StartModule(c.id); err != nil {
+				e.log("error", fmt.Sprintf("Failed to start enabled module %s: %v", c.id, err))
+			}
+		}
+	}
+}
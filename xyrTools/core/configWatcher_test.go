@@ -0,0 +1,112 @@
+package core
+
+import (
+	"os"
+	"testing"
+)
+
+const watcherConfig = `a:
+  enabled: true
+  interval: 1
+b:
+  enabled: true
+  interval: 1
+c:
+  enabled: true
+d:
+  enabled: false
+`
+
+func writeConfig(t *testing.T, path, config string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 只有配置节变化的模块被重新加载；enabled 变化时停止或启动模块
+func TestReloadOnlyChangedModules(t *testing.T) {
+	e, path := newTestEngine(t, watcherConfig)
+	a, b, c, d := &fakeModule{id: "a"}, &fakeModule{id: "b"}, &fakeModule{id: "c"}, &fakeModule{id: "d"}
+	mustRegister(t, e, a, b, c, d)
+	e.StartAll()
+	defer e.StopAll()
+
+	writeConfig(t, path, `a:
+  enabled: true
+  interval: 1
+b:
+  enabled: true
+  interval: 2
+c:
+  enabled: false
+d:
+  enabled: true
+`)
+	e.reloadConfigFile(path)
+
+	if n := a.reloadCount(); n != 0 {
+		t.Fatalf("unchanged module a reloaded %d times", n)
+	}
+	if n := b.reloadCount(); n != 1 {
+		t.Fatalf("changed module b reloaded %d times, want 1", n)
+	}
+	if got := b.ctx.Config["interval"]; got != 2 {
+		t.Fatalf("b interval = %v, want 2", got)
+	}
+	if moduleStatus(t, e, "c").Running {
+		t.Fatal("disabled module c still running")
+	}
+	if !moduleStatus(t, e, "d").Running || d.startCount() != 1 {
+		t.Fatal("enabled module d not started")
+	}
+	if a.startCount() != 1 || b.startCount() != 1 {
+		t.Fatalf("modules restarted by the engine: a=%d b=%d", a.startCount(), b.startCount())
+	}
+}
+
+// 内容未变化或格式错误时不重新加载任何模块
+func TestReloadIgnoresUnchangedAndInvalidConfig(t *testing.T) {
+	e, path := newTestEngine(t, watcherConfig)
+	a := &fakeModule{id: "a"}
+	mustRegister(t, e, a)
+	e.StartAll()
+	defer e.StopAll()
+
+	e.reloadConfigFile(path)
+	writeConfig(t, path, "a: [unclosed\n")
+	e.reloadConfigFile(path)
+	if n := a.reloadCount(); n != 0 {
+		t.Fatalf("module reloaded %d times", n)
+	}
+	if got := e.GetConfig()["a"]; got == nil {
+		t.Fatal("last good config was replaced")
+	}
+}
+
+// 监听配置文件，写入后经过防抖时间自动重新加载
+func TestWatchConfig(t *testing.T) {
+	e, path := newTestEngine(t, watcherConfig)
+	a, b := &fakeModule{id: "a"}, &fakeModule{id: "b"}
+	mustRegister(t, e, a, b)
+	e.StartAll()
+	defer e.StopAll()
+	if err := e.WatchConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.WatchConfig(); err == nil {
+		t.Fatal("second WatchConfig succeeded")
+	}
+
+	writeConfig(t, path, `a:
+  enabled: true
+  interval: 5
+b:
+  enabled: true
+  interval: 1
+`)
+	waitFor(t, "reload of a", func() bool { return a.reloadCount() == 1 })
+	if n := b.reloadCount(); n != 0 {
+		t.Fatalf("unchanged module b reloaded %d times", n)
+	}
+}
@@ -2,31 +2,70 @@
 package core
 
 import (
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"xyrTools/xyrTools/modInterfaces"
 
+	"myMod/xlog"
+
 	"gopkg.in/yaml.v2"
 )
 
 type CoreEngine struct {
-	modules   map[string]*modInterfaces.ModuleInstance
-	eventBus  modInterfaces.EventBus
-	globalCfg map[string]interface{}
-	log       func(string, string)
-	lock      sync.Mutex
+	modules      map[string]*modInterfaces.ModuleInstance
+	order        []string            // 模块注册顺序，拓扑排序时作为同级模块的先后依据
+	deps         map[string][]string // 模块依赖关系，键为模块ID，值为其依赖的模块ID
+	eventBus     modInterfaces.ScopedEventBus
+	eventMetrics *modInterfaces.EventMetrics // metrics 拦截器的事件统计，重新加载配置后保留
+	globalCfg    map[string]interface{}
+	log          func(string, string) // 引擎自身的日志
+	logger       *xlog.Logger         // 根日志对象，模块日志由此派生
+	logFixed     bool                 // 日志输出由调用方决定，忽略配置文件的 log 节
+	lock         sync.Mutex
+
+	restarts     map[string]*restartState // 监管器的模块重启状态
+	shuttingDown bool                     // 正在退出，不再自动重启模块
+
+	stopTimeout  time.Duration // 模块停止的默认超时时间
+	shutdownCh   chan struct{} // 收到退出请求时关闭
+	shutdownOnce sync.Once
+
+	configPath string        // 配置文件路径，热加载时使用
+	cfgHash    [32]byte      // 最近一次成功加载的配置文件 hash
+	watchStop  chan struct{} // 停止配置文件监听
 }
 
+// NewCoreEngine 使用日志函数创建引擎，配置文件的 log 节不生效，模块的 logLevel 仍然有效
 func NewCoreEngine(logFunc func(string, string)) *CoreEngine {
-	return &CoreEngine{
-		modules:   make(map[string]*modInterfaces.ModuleInstance),
-		eventBus:  modInterfaces.NewEventBus(),
-		log:       logFunc,
-		globalCfg: make(map[string]interface{}),
+	e := NewCoreEngineWithLogger(xlog.FromFunc(logFunc))
+	e.logFixed = true
+	return e
+}
+
+// NewCoreEngineWithLogger 使用结构化日志创建引擎，配置文件的 log 节和模块的 logLevel 对其生效
+func NewCoreEngineWithLogger(logger *xlog.Logger) *CoreEngine {
+	e := &CoreEngine{
+		modules:      make(map[string]*modInterfaces.ModuleInstance),
+		deps:         make(map[string][]string),
+		restarts:     make(map[string]*restartState),
+		eventBus:     modInterfaces.NewEventBus(),
+		eventMetrics: modInterfaces.NewEventMetrics(),
+		log:          logger.Module("core").Func(),
+		logger:       logger,
+		globalCfg:    make(map[string]interface{}),
+		stopTimeout:  defaultStopTimeout,
+		shutdownCh:   make(chan struct{}),
 	}
+	e.eventBus.SetLogger(logger.Module("eventBus").Func())
+	modInterfaces.Subscribe(e.eventBus, modInterfaces.TopicShutdownRequested, e.onShutdownRequested)
+	modInterfaces.Subscribe(e.eventBus.Owner("core"), modInterfaces.TopicHandlerFailed, e.onHandlerFailed, modInterfaces.AsObserver())
+	return e
 }
 
 // 加载配置
@@ -39,7 +78,14 @@ func (e *CoreEngine) LoadConfig(path string) error {
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return fmt.Errorf("invalid yaml format: %w", err)
 	}
+	e.lock.Lock()
 	e.globalCfg = cfg
+	e.configPath = path
+	e.cfgHash = sha256.Sum256(data)
+	e.lock.Unlock()
+	e.applyLogConfig(cfg)
+	e.applyShutdownConfig(cfg)
+	e.applyEventBusConfig(cfg)
 	return nil
 }
 
@@ -47,6 +93,8 @@ func (e *CoreEngine) LoadConfig(path string) error {
 // 参数 id 为模块的唯一标识符。
 // 参数 factory 是一个工厂函数，用于创建模块实例。
 // 返回值为错误信息，若注册过程中出现问题则返回相应错误，注册成功返回 nil。
+// 依赖自身或形成循环依赖时拒绝注册。依赖的模块不存在时不在此拒绝：模块按配置文件的顺序注册，
+// 依赖可能稍后才注册，因此全部注册后须调用 ResolveDependencies，由它取消依赖缺失的模块的注册。
 func (e *CoreEngine) Register(id string, factory func() modInterfaces.Module) error {
 	// 加锁，确保在注册模块时不会有其他并发操作修改模块列表
 	e.lock.Lock()
@@ -76,75 +124,262 @@ func (e *CoreEngine) Register(id string, factory func() modInterfaces.Module) er
 	// 将 map[interface{}]interface{} 类型的配置转换为 map[string]interface{} 类型
 	cfg := convertMap(rawMap)
 
+	// 校验重启策略
+	if policy, ok := cfg["restart"]; ok {
+		switch policy {
+		case RestartNever, RestartOnFailure, RestartAlways:
+		default:
+			return fmt.Errorf("module %s has invalid restart policy %v (want never, on-failure or always)", id, policy)
+		}
+	}
+
 	// 使用工厂函数创建模块实例
 	mod := factory()
 
+	// 检查模块依赖，循环依赖时拒绝注册；依赖的模块可以稍后注册，全部注册后由 ResolveDependencies 检查
+	var deps []string
+	if dm, ok := mod.(modInterfaces.DependentModule); ok {
+		deps = dm.Dependencies()
+	}
+	if err := e.checkDependencies(id, deps); err != nil {
+		return err
+	}
+
 	// 构建模块上下文配置
+	modLogger := e.logger.Module(id)
+	e.applyModuleLogLevel(id, cfg)
 	ctx := modInterfaces.Context{
 
-		Config: cfg,        // 模块配置信息
-		Log:    e.log,      // 日志函数
-		Events: e.eventBus, // 事件总线
+		Config:   cfg,                               // 模块配置信息
+		Log:      modLogger.Func(),                  // 日志函数
+		Logger:   modLogger,                         // 结构化日志
+		Events:   e.eventBus.Owner(id),              // 事件总线，模块停止时自动取消其订阅
+		Failures: moduleReporter{engine: e, id: id}, // 异步故障上报
 	}
 
-	// 向模块注入上下文信息并初始化模块
+	// 向模块注入上下文信息并初始化模块，配置校验失败也在此返回
 	if err := mod.Init(ctx); err != nil {
 		// 若初始化失败，返回错误信息
-		return err
+		return fmt.Errorf("module %s init failed: %w", id, err)
 	}
 
+	// 外部模块的事件声明在启动前握手时才能读取，届时由 prepareInstance 重新设置
+	e.applyModuleACL(id, mod, cfg)
+
 	// 将模块实例及其上下文信息添加到核心引擎的模块列表中
 	e.modules[id] = &modInterfaces.ModuleInstance{
 
 		Impl: mod, // 模块实例
 		Ctx:  ctx, // 模块上下文
 	}
+	e.order = append(e.order, id)
+	e.deps[id] = deps
 
 	// 注册成功，返回 nil
 	return nil
 }
 
-// 根据模块配置启动所有模块
+// 根据模块配置启动所有模块，按依赖关系的拓扑顺序启动
 func (e *CoreEngine) StartAll() {
-	for id, inst := range e.modules {
+	e.lock.Lock()
+	e.shuttingDown = false
+	e.lock.Unlock()
+	for _, id := range e.startOrder() {
+		inst, _ := e.instance(id)
 		e.log("info", fmt.Sprintf("Starting module %s", id))
 		enabled, ok := inst.Ctx.Config["enabled"].(bool)
 		// 模块配置为关闭
 		if !ok || !enabled {
 			e.log("info", fmt.Sprintf("Module %s is disabled", id))
 			continue
-		} else {
-			// 模块配置为开启
-			if err := inst.Impl.Start(); err != nil {
-				// 模块启动失败
-				e.log("error", fmt.Sprintf("Module %s failed to start: %v", id, err))
-				inst.Status.LastError = err
-			} else {
-				inst.Status.StartTime = time.Now()
-				inst.Status.Running = true
-			}
 		}
-
+		// 依赖模块未运行，跳过当前模块
+		if reason := e.unmetDependency(id); reason != "" {
+			e.log("warn", fmt.Sprintf("Module %s skipped: %s", id, reason))
+			inst.Mutex.Lock()
+			inst.Status.Skipped = true
+			inst.Status.SkipReason = reason
+			inst.Status.LastError = fmt.Errorf("module %s skipped: %s", id, reason)
+			inst.Mutex.Unlock()
+			continue
+		}
+		// 模块配置为开启
+		if err := e.startInstance(id, inst); err != nil {
+			// 模块启动失败
+			e.log("error", fmt.Sprintf("Module %s failed to start: %v", id, err))
+		}
 	}
 }
 
-// 停止所有模块
+// 停止所有模块，按启动顺序的逆序停止。
+// 停止前先等待已发布的事件处理完，全部停止后关闭事件总线，之后引擎不能再启动
 func (e *CoreEngine) StopAll() {
-	// 遍历所有模块
-	for id, inst := range e.modules {
+	// 先停止配置监听和自动重启，避免退出过程中重新拉起模块
+	e.StopWatchConfig()
+	e.lock.Lock()
+	e.shuttingDown = true
+	for id, st := range e.restarts {
+		if st.timer != nil {
+			st.timer.Stop()
+		}
+		delete(e.restarts, id)
+	}
+	e.lock.Unlock()
+	e.drainEvents()
+	order := e.startOrder()
+	var missed []string // 未在超时时间内停止的模块
+	for i := len(order) - 1; i >= 0; i-- {
+		id := order[i]
+		inst, _ := e.instance(id)
 		// 模块未启动，跳过
-		if !inst.Status.Running {
+		if !statusOf(inst).Running {
 			e.log("info", fmt.Sprintf("Module %s is not running, skipping stop", id))
 			continue
 		}
-		if err := inst.Impl.Stop(); err != nil {
+		if err := e.stopInstance(id, inst); err != nil {
 			e.log("error", fmt.Sprintf("Module %s failed to stop: %v", id, err))
-		} else {
-			e.log("info", fmt.Sprintf("Stopping module %s", id))
-			inst.Status.Running = false
-			inst.Status.EndTime = time.Now()
+			if errors.Is(err, errStopTimeout) {
+				missed = append(missed, id)
+			}
 		}
 	}
+	if len(missed) > 0 {
+		e.log("warn", fmt.Sprintf("Modules missed the stop deadline: %s", strings.Join(missed, ", ")))
+	}
+	e.closeEvents()
+}
+
+// 检查模块依赖是否合法，不允许依赖自身或出现循环依赖
+func (e *CoreEngine) checkDependencies(id string, deps []string) error {
+	for _, dep := range deps {
+		if dep == id {
+			return fmt.Errorf("module %s depends on itself", id)
+		}
+	}
+	// 从新模块的依赖出发沿已注册模块的依赖关系查找，若能回到新模块则存在循环
+	for _, dep := range deps {
+		if path := e.findPath(dep, id, map[string]bool{}); path != nil {
+			cycle := append([]string{id}, path...)
+			return fmt.Errorf("module %s has a dependency cycle: %s", id, strings.Join(cycle, " -> "))
+		}
+	}
+	return nil
+}
+
+// ResolveDependencies 在全部模块注册之后调用，检查每个模块的依赖是否都已注册。
+// 依赖未注册（未列入模块列表、当前系统不可用或注册失败）的模块连同依赖它的模块一起取消注册，
+// 每个被取消注册的模块记录一条错误日志，返回值中每个模块一条错误，没有时返回 nil。
+// 未调用时依赖缺失的模块在启动时被跳过
+func (e *CoreEngine) ResolveDependencies() error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	var errs []error
+	removed := make(map[string]bool)
+	for {
+		id, dep := e.unresolvedLocked()
+		if id == "" {
+			return errors.Join(errs...)
+		}
+		err := fmt.Errorf("module %s depends on %s, which is not registered", id, dep)
+		if removed[dep] {
+			err = fmt.Errorf("module %s depends on %s, which was unregistered", id, dep)
+		}
+		errs = append(errs, err)
+		e.unregisterLocked(id, err)
+		removed[id] = true
+	}
+}
+
+// 按注册顺序查找第一个依赖未注册的模块，调用方持有 e.lock
+func (e *CoreEngine) unresolvedLocked() (id, dep string) {
+	for _, id := range e.order {
+		for _, dep := range e.deps[id] {
+			if _, ok := e.modules[dep]; !ok {
+				return id, dep
+			}
+		}
+	}
+	return "", ""
+}
+
+// 取消模块注册并记录原因，调用方持有 e.lock
+func (e *CoreEngine) unregisterLocked(id string, reason error) {
+	delete(e.modules, id)
+	delete(e.deps, id)
+	for i, other := range e.order {
+		if other == id {
+			e.order = append(e.order[:i], e.order[i+1:]...)
+			break
+		}
+	}
+	e.eventBus.CancelOwner(id)
+	e.eventBus.SetACL(id, nil)
+	e.log("error", fmt.Sprintf("Module %s unregistered: %v", id, reason))
+}
+
+// 深度优先查找 from 到 to 的依赖路径，找不到返回 nil
+func (e *CoreEngine) findPath(from, to string, visited map[string]bool) []string {
+	if from == to {
+		return []string{to}
+	}
+	if visited[from] {
+		return nil
+	}
+	visited[from] = true
+	for _, next := range e.deps[from] {
+		if path := e.findPath(next, to, visited); path != nil {
+			return append([]string{from}, path...)
+		}
+	}
+	return nil
+}
+
+// 计算模块启动顺序（拓扑排序），同级模块按注册顺序排列
+func (e *CoreEngine) startOrder() []string {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	visited := make(map[string]bool, len(e.order))
+	result := make([]string, 0, len(e.order))
+	var visit func(id string)
+	visit = func(id string) {
+		if visited[id] {
+			return
+		}
+		visited[id] = true
+		for _, dep := range e.deps[id] {
+			// 未注册的依赖不参与排序，ResolveDependencies 之后不会出现
+			if _, ok := e.modules[dep]; ok {
+				visit(dep)
+			}
+		}
+		result = append(result, id)
+	}
+	for _, id := range e.order {
+		visit(id)
+	}
+	return result
+}
+
+// 检查模块的依赖是否都已运行，返回第一个未满足的依赖原因，全部满足返回空字符串
+func (e *CoreEngine) unmetDependency(id string) string {
+	e.lock.Lock()
+	deps := e.deps[id]
+	e.lock.Unlock()
+	for _, dep := range deps {
+		inst, err := e.instance(dep)
+		if err != nil {
+			return fmt.Sprintf("dependency %s is not registered", dep)
+		}
+		status := statusOf(inst)
+		if status.Running {
+			continue
+		}
+		if status.LastError != nil {
+			return fmt.Sprintf("dependency %s failed: %v", dep, status.LastError)
+		}
+		return fmt.Sprintf("dependency %s is not running", dep)
+	}
+	return ""
 }
 
 // 获取事件总线
@@ -152,13 +387,30 @@ func (e *CoreEngine) GetEventBus() modInterfaces.EventBus {
 	return e.eventBus
 }
 
+// DeadLetters 返回事件处理函数失败的事件（死信）
+func (e *CoreEngine) DeadLetters() []modInterfaces.DeadLetter {
+	return e.eventBus.DeadLetters()
+}
+
+// RedeliverDeadLetter 将死信重新投递给原订阅所属的模块
+func (e *CoreEngine) RedeliverDeadLetter(id uint64) error {
+	return e.eventBus.Redeliver(id)
+}
+
 // 日志函数
 func (e *CoreEngine) Log(level, msg string) {
 	e.log(level, msg)
 }
 
+// Logger 获取根日志对象，可用于查看最近的日志（Tail、Follow）
+func (e *CoreEngine) Logger() *xlog.Logger {
+	return e.logger
+}
+
 // 获取全局配置
 func (e *CoreEngine) GetConfig() map[string]interface{} {
+	e.lock.Lock()
+	defer e.lock.Unlock()
 	return e.globalCfg
 }
 
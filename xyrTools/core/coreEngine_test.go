@@ -0,0 +1,269 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"xyrTools/xyrTools/modInterfaces"
+)
+
+// 记录模块启动、停止的顺序
+type callLog struct {
+	lock  sync.Mutex
+	calls []string
+}
+
+func (l *callLog) add(call string) {
+	l.lock.Lock()
+	l.calls = append(l.calls, call)
+	l.lock.Unlock()
+}
+
+func (l *callLog) String() string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return strings.Join(l.calls, " ")
+}
+
+// 测试用模块：启动、停止时写入 calls，可设置启动失败、启动时 panic 和停止耗时
+type fakeModule struct {
+	id    string
+	deps  []string
+	calls *callLog
+
+	lock       sync.Mutex
+	ctx        modInterfaces.Context
+	startErr   error
+	startPanic bool
+	stopDelay  time.Duration
+	stopCalled chan struct{} // 不为空时在 Stop 开始时关闭
+	starts     int
+	reloads    int
+}
+
+func (m *fakeModule) ID() string             { return m.id }
+func (m *fakeModule) Name() string           { return m.id }
+func (m *fakeModule) Description() string    { return "" }
+func (m *fakeModule) Version() string        { return "" }
+func (m *fakeModule) Author() string         { return "" }
+func (m *fakeModule) Dependencies() []string { return m.deps }
+
+func (m *fakeModule) Init(ctx modInterfaces.Context) error {
+	m.ctx = ctx
+	return nil
+}
+
+func (m *fakeModule) Start() error {
+	m.lock.Lock()
+	m.starts++
+	err, panics := m.startErr, m.startPanic
+	m.lock.Unlock()
+	if panics {
+		panic("start panic")
+	}
+	if err != nil {
+		return err
+	}
+	if m.calls != nil {
+		m.calls.add("start:" + m.id)
+	}
+	return nil
+}
+
+func (m *fakeModule) Stop(ctx context.Context) error {
+	if m.stopCalled != nil {
+		close(m.stopCalled)
+		m.stopCalled = nil
+	}
+	if m.stopDelay > 0 {
+		select {
+		case <-time.After(m.stopDelay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if m.calls != nil {
+		m.calls.add("stop:" + m.id)
+	}
+	return nil
+}
+
+func (m *fakeModule) Status() modInterfaces.ModuleStatus { return modInterfaces.ModuleStatus{} }
+
+func (m *fakeModule) Reload(ctx modInterfaces.Context) error {
+	m.lock.Lock()
+	m.ctx = ctx
+	m.reloads++
+	m.lock.Unlock()
+	return nil
+}
+
+func (m *fakeModule) startCount() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.starts
+}
+
+func (m *fakeModule) reloadCount() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.reloads
+}
+
+func (m *fakeModule) setStart(err error, panics bool) {
+	m.lock.Lock()
+	m.startErr, m.startPanic = err, panics
+	m.lock.Unlock()
+}
+
+func (m *fakeModule) factory() modInterfaces.Module { return m }
+
+// 写入配置文件并创建引擎，返回引擎和配置文件路径
+func newTestEngine(t *testing.T, config string) (*CoreEngine, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e := NewCoreEngine(func(level, msg string) {})
+	if err := e.LoadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	return e, path
+}
+
+// 生成启用全部模块的配置
+func enabledConfig(ids ...string) string {
+	var b strings.Builder
+	for _, id := range ids {
+		fmt.Fprintf(&b, "%s:\n  enabled: true\n", id)
+	}
+	return b.String()
+}
+
+func mustRegister(t *testing.T, e *CoreEngine, mods ...*fakeModule) {
+	t.Helper()
+	for _, m := range mods {
+		if err := e.Register(m.id, m.factory); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func moduleStatus(t *testing.T, e *CoreEngine, id string) modInterfaces.ModuleStatus {
+	t.Helper()
+	inst, err := e.instance(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return statusOf(inst)
+}
+
+// 按依赖关系的拓扑顺序启动，逆序停止；同级模块按注册顺序
+func TestStartStopOrder(t *testing.T) {
+	e, _ := newTestEngine(t, enabledConfig("web", "db", "cache", "log"))
+	calls := &callLog{}
+	mustRegister(t, e,
+		&fakeModule{id: "web", deps: []string{"db", "cache"}, calls: calls},
+		&fakeModule{id: "db", deps: []string{"log"}, calls: calls},
+		&fakeModule{id: "cache", calls: calls},
+		&fakeModule{id: "log", calls: calls},
+	)
+	if err := e.ResolveDependencies(); err != nil {
+		t.Fatal(err)
+	}
+	e.StartAll()
+	e.StopAll()
+	want := "start:log start:db start:cache start:web stop:web stop:cache stop:db stop:log"
+	if got := calls.String(); got != want {
+		t.Fatalf("calls = %q\nwant    %q", got, want)
+	}
+}
+
+func TestDependencyCycle(t *testing.T) {
+	e, _ := newTestEngine(t, enabledConfig("a", "b", "c", "self"))
+	mustRegister(t, e,
+		&fakeModule{id: "a", deps: []string{"b"}},
+		&fakeModule{id: "b", deps: []string{"c"}},
+	)
+	err := e.Register("c", (&fakeModule{id: "c", deps: []string{"a"}}).factory)
+	if err == nil || !strings.Contains(err.Error(), "c -> a -> b -> c") {
+		t.Fatalf("err = %v, want cycle c -> a -> b -> c", err)
+	}
+	err = e.Register("self", (&fakeModule{id: "self", deps: []string{"self"}}).factory)
+	if err == nil || !strings.Contains(err.Error(), "depends on itself") {
+		t.Fatalf("err = %v, want self dependency error", err)
+	}
+}
+
+// 依赖只出现在配置中而未注册时，全部注册后取消依赖方（及其依赖方）的注册
+func TestResolveDependencies(t *testing.T) {
+	e, _ := newTestEngine(t, enabledConfig("app", "ui", "other", "missing"))
+	var lock sync.Mutex
+	var logs []string
+	e.log = func(level, msg string) {
+		lock.Lock()
+		logs = append(logs, msg)
+		lock.Unlock()
+	}
+	// 依赖缺失时 Register 不拒绝，由 ResolveDependencies 取消注册
+	mustRegister(t, e,
+		&fakeModule{id: "ui", deps: []string{"app"}},
+		&fakeModule{id: "app", deps: []string{"missing"}},
+		&fakeModule{id: "other"},
+	)
+	err := e.ResolveDependencies()
+	if err == nil || !strings.Contains(err.Error(), "module app depends on missing, which is not registered") ||
+		!strings.Contains(err.Error(), "module ui depends on app, which was unregistered") {
+		t.Fatalf("err = %v", err)
+	}
+	var ids []string
+	for _, info := range e.ListModules() {
+		ids = append(ids, info.ID)
+	}
+	if got := strings.Join(ids, " "); got != "other" {
+		t.Fatalf("registered modules = %q, want other", got)
+	}
+	lock.Lock()
+	all := strings.Join(logs, "\n")
+	lock.Unlock()
+	for _, want := range []string{"Module app unregistered: module app depends on missing", "Module ui unregistered: module ui depends on app"} {
+		if !strings.Contains(all, want) {
+			t.Fatalf("log missing %q:\n%s", want, all)
+		}
+	}
+	if err := e.ResolveDependencies(); err != nil {
+		t.Fatalf("second ResolveDependencies = %v", err)
+	}
+}
+
+// 依赖启动失败时跳过依赖方，不影响其他模块
+func TestSkipDependentOfFailedModule(t *testing.T) {
+	e, _ := newTestEngine(t, enabledConfig("db", "web", "cache"))
+	calls := &callLog{}
+	mustRegister(t, e,
+		&fakeModule{id: "db", startErr: errors.New("no disk"), calls: calls},
+		&fakeModule{id: "web", deps: []string{"db"}, calls: calls},
+		&fakeModule{id: "cache", calls: calls},
+	)
+	e.StartAll()
+	defer e.StopAll()
+
+	status := moduleStatus(t, e, "web")
+	if status.Running || !status.Skipped || !strings.Contains(status.SkipReason, "dependency db failed: no disk") {
+		t.Fatalf("web status = %+v, want skipped because db failed", status)
+	}
+	if got := calls.String(); got != "start:cache" {
+		t.Fatalf("calls = %q, want only cache started", got)
+	}
+	if err := e.StartModule("web"); err == nil || !strings.Contains(err.Error(), "dependency db failed") {
+		t.Fatalf("StartModule(web) = %v, want dependency error", err)
+	}
+}
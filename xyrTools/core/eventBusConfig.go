@@ -0,0 +1,88 @@
+package core
+
+import (
+	"fmt"
+
+	"xyrTools/xyrTools/modInterfaces"
+)
+
+// 按配置文件 eventBus 节设置事件总线的访问控制模式（acl）和拦截器链（interceptors，按顺序排列，前面的在外层）。
+// 未配置时关闭访问控制、清空拦截器链，配置错误时保留当前的设置
+func (e *CoreEngine) applyEventBusConfig(cfg map[string]interface{}) {
+	section := make(map[string]interface{})
+	if raw, ok := cfg["eventBus"].(map[interface{}]interface{}); ok {
+		section = convertMap(raw)
+	}
+	aclName, _ := section["acl"].(string)
+	if mode, err := modInterfaces.ParseACLMode(aclName); err != nil {
+		e.log("error", fmt.Sprintf("Invalid eventBus config, keeping current acl mode: %v", err))
+	} else {
+		e.eventBus.SetACLMode(mode)
+	}
+
+	var items []interface{}
+	if v, ok := section["interceptors"]; ok && v != nil {
+		if items, ok = v.([]interface{}); !ok {
+			e.log("error", "Invalid eventBus config, keeping current interceptors: interceptors is not a list")
+			return
+		}
+	}
+	env := modInterfaces.InterceptorEnv{
+		Log:     e.logger.Module("eventBus").Func(),
+		Metrics: e.eventMetrics,
+	}
+	chain := make([]modInterfaces.Interceptor, 0, len(items))
+	for i, item := range items {
+		raw, ok := item.(map[interface{}]interface{})
+		if !ok {
+			e.log("error", fmt.Sprintf("Invalid eventBus config, keeping current interceptors: interceptor #%d is not a map", i+1))
+			return
+		}
+		icCfg := convertMap(raw)
+		typ, _ := icCfg["type"].(string)
+		delete(icCfg, "type")
+		ic, err := modInterfaces.NewInterceptor(typ, icCfg, env)
+		if err != nil {
+			e.log("error", fmt.Sprintf("Invalid eventBus config, keeping current interceptors: interceptor #%d: %v", i+1, err))
+			return
+		}
+		chain = append(chain, ic)
+	}
+	e.eventBus.SetInterceptors(chain...)
+	if len(chain) > 0 {
+		e.log("info", fmt.Sprintf("Event bus interceptors: %d configured", len(chain)))
+	}
+}
+
+// EventMetrics 返回 metrics 拦截器的事件统计，未配置 metrics 拦截器时统计为空
+func (e *CoreEngine) EventMetrics() map[string]modInterfaces.EventStats {
+	return e.eventMetrics.Snapshot()
+}
+
+// 按模块的事件声明和配置中的 publishes、subscribes 设置模块的事件访问控制，配置覆盖模块自身的声明
+func (e *CoreEngine) applyModuleACL(id string, mod modInterfaces.Module, cfg map[string]interface{}) {
+	var acl *modInterfaces.EventACL
+	if d, ok := mod.(modInterfaces.EventDeclarer); ok {
+		if pub, sub := d.PublishesEvents(), d.SubscribesEvents(); pub != nil || sub != nil {
+			acl = &modInterfaces.EventACL{Publish: pub, Subscribe: sub}
+		}
+	}
+	pub, err := modInterfaces.ConfigStrings(cfg, "publishes")
+	if err != nil {
+		e.log("warn", fmt.Sprintf("Module %s has invalid publishes, ignoring: %v", id, err))
+	}
+	sub, err := modInterfaces.ConfigStrings(cfg, "subscribes")
+	if err != nil {
+		e.log("warn", fmt.Sprintf("Module %s has invalid subscribes, ignoring: %v", id, err))
+	}
+	if (pub != nil || sub != nil) && acl == nil {
+		acl = &modInterfaces.EventACL{}
+	}
+	if pub != nil {
+		acl.Publish = pub
+	}
+	if sub != nil {
+		acl.Subscribe = sub
+	}
+	e.eventBus.SetACL(id, acl)
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"xyrTools/xyrTools/modInterfaces"
+
+	"myMod/xlog"
+)
+
+// 配置文件 log 节
+type logConfig struct {
+	Level      string `yaml:"level" default:"info" desc:"默认最低日志级别：debug、info、warn、error"`
+	Console    bool   `yaml:"console" default:"true" desc:"是否输出到控制台"`
+	File       string `yaml:"file" default:"logs/xyrTools.log" desc:"日志文件路径，为空时不写文件"`
+	MaxSize    int    `yaml:"maxSize" default:"10" min:"0" desc:"单个日志文件最大大小（MB），0 表示不按大小切分"`
+	Daily      bool   `yaml:"daily" default:"false" desc:"是否按天切分日志文件"`
+	MaxBackups int    `yaml:"maxBackups" default:"7" min:"0" desc:"最多保留的历史日志文件数，0 表示不限制"`
+	MaxAge     int    `yaml:"maxAge" default:"30" min:"0" desc:"历史日志文件保留天数，0 表示不限制"`
+	RingSize   int    `yaml:"ringSize" default:"1000" min:"1" desc:"内存中保留的最近日志条数"`
+}
+
+// 按配置文件 log 节配置日志，未配置时使用默认值，配置错误时保留当前日志配置
+func (e *CoreEngine) applyLogConfig(cfg map[string]interface{}) {
+	if e.logFixed {
+		return
+	}
+	section := make(map[string]interface{})
+	if raw, ok := cfg["log"].(map[interface{}]interface{}); ok {
+		section = convertMap(raw)
+	}
+	var lc logConfig
+	if err := modInterfaces.DecodeConfig(section, &lc); err != nil {
+		e.log("error", fmt.Sprintf("Invalid log config, keeping current settings: %v", err))
+		return
+	}
+	level, ok := xlog.ParseLevel(lc.Level)
+	if !ok {
+		e.log("error", fmt.Sprintf("Invalid log level %q, keeping current settings", lc.Level))
+		return
+	}
+	err := e.logger.Configure(xlog.Options{
+		Level:      level,
+		Console:    lc.Console,
+		File:       lc.File,
+		MaxSize:    int64(lc.MaxSize) << 20,
+		Daily:      lc.Daily,
+		MaxBackups: lc.MaxBackups,
+		MaxAge:     time.Duration(lc.MaxAge) * 24 * time.Hour,
+		RingSize:   lc.RingSize,
+	})
+	if err != nil {
+		e.log("error", fmt.Sprintf("Failed to configure log: %v", err))
+	}
+}
+
+// 按模块配置的 logLevel 设置模块日志级别，未配置时使用默认级别
+func (e *CoreEngine) applyModuleLogLevel(id string, cfg map[string]interface{}) {
+	name, ok := cfg["logLevel"].(string)
+	if !ok {
+		e.logger.ResetModuleLevel(id)
+		return
+	}
+	level, ok := xlog.ParseLevel(name)
+	if !ok {
+		e.log("warn", fmt.Sprintf("Module %s has invalid logLevel %q, using default level", id, name))
+		e.logger.ResetModuleLevel(id)
+		return
+	}
+	e.logger.SetModuleLevel(id, level)
+}
@@ -0,0 +1,275 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"xyrTools/xyrTools/modInterfaces"
+)
+
+// 模块生命周期事件，模块启停、重载时发布到事件总线
+const (
+	EventModuleStarted  = "core:moduleStarted"  // 模块已启动
+	EventModuleStopped  = "core:moduleStopped"  // 模块已停止
+	EventModuleReloaded = "core:moduleReloaded" // 模块已重新加载
+	EventModuleFailed   = "core:moduleFailed"   // 模块启动、停止或重载失败
+)
+
+var (
+	TopicModuleStarted  = modInterfaces.DeclareEvent[modInterfaces.ModuleLifecycle](EventModuleStarted, "模块已启动")
+	TopicModuleStopped  = modInterfaces.DeclareEvent[modInterfaces.ModuleLifecycle](EventModuleStopped, "模块已停止")
+	TopicModuleReloaded = modInterfaces.DeclareEvent[modInterfaces.ModuleLifecycle](EventModuleReloaded, "模块已重新加载")
+	TopicModuleFailed   = modInterfaces.DeclareEvent[modInterfaces.ModuleLifecycle](EventModuleFailed, "模块启动、停止、重载失败或运行中崩溃")
+)
+
+// StartModule 在运行时启动指定模块，不受配置文件 enabled 开关限制。
+// 模块的依赖必须已经在运行，否则返回错误。
+func (e *CoreEngine) StartModule(id string) error {
+	inst, err := e.instance(id)
+	if err != nil {
+		return err
+	}
+	if reason := e.unmetDependency(id); reason != "" {
+		return fmt.Errorf("cannot start module %s: %s", id, reason)
+	}
+	// 手动启动时取消等待中的自动重启
+	e.cancelRestart(id)
+	return e.startInstance(id, inst)
+}
+
+// StopModule 在运行时停止指定模块。
+// 仍有运行中的模块依赖它时拒绝停止，需先停止依赖方。
+func (e *CoreEngine) StopModule(id string) error {
+	inst, err := e.instance(id)
+	if err != nil {
+		return err
+	}
+	// 手动停止时取消等待中的自动重启
+	e.cancelRestart(id)
+	for _, dependent := range e.dependents(id) {
+		depInst, err := e.instance(dependent)
+		if err == nil && statusOf(depInst).Running {
+			return fmt.Errorf("cannot stop module %s: module %s depends on it", id, dependent)
+		}
+	}
+	return e.stopInstance(id, inst)
+}
+
+// ReloadModule 调用运行中模块的 Reload，使其重新加载配置
+func (e *CoreEngine) ReloadModule(id string) error {
+	inst, err := e.instance(id)
+	if err != nil {
+		return err
+	}
+
+	inst.Mutex.Lock()
+	if !inst.Status.Running {
+		inst.Mutex.Unlock()
+		return fmt.Errorf("module %s is not running", id)
+	}
+	e.log("info", fmt.Sprintf("Reloading module %s", id))
+	err = inst.Impl.Reload(inst.Ctx)
+	if err != nil {
+		inst.Status.LastError = err
+	}
+	inst.Mutex.Unlock()
+
+	if err != nil {
+		e.publishLifecycle(TopicModuleFailed, id, err)
+		return fmt.Errorf("module %s failed to reload: %w", id, err)
+	}
+	e.publishLifecycle(TopicModuleReloaded, id, nil)
+	return nil
+}
+
+// 向模块注入新的上下文（配置热加载使用），运行中的模块会按新配置重启
+func (e *CoreEngine) reloadInstance(id string, inst *modInterfaces.ModuleInstance, ctx modInterfaces.Context) error {
+	// 模块在 Reload 中重启时按新配置的访问控制订阅
+	e.applyModuleACL(id, inst.Impl, ctx.Config)
+	inst.Mutex.Lock()
+	inst.Ctx = ctx
+	running := inst.Status.Running
+	err := inst.Impl.Reload(ctx)
+	if err != nil {
+		inst.Status.LastError = err
+	}
+	inst.Mutex.Unlock()
+
+	if err != nil {
+		e.publishLifecycle(TopicModuleFailed, id, err)
+		return fmt.Errorf("module %s failed to reload: %w", id, err)
+	}
+	if running {
+		e.publishLifecycle(TopicModuleReloaded, id, nil)
+	}
+	return nil
+}
+
+// ListModules 按启动顺序返回所有已注册模块的信息及状态快照
+func (e *CoreEngine) ListModules() []modInterfaces.ModuleInfo {
+	order := e.startOrder()
+	list := make([]modInterfaces.ModuleInfo, 0, len(order))
+	for _, id := range order {
+		inst, err := e.instance(id)
+		if err != nil {
+			continue
+		}
+		enabled, _ := inst.Ctx.Config["enabled"].(bool)
+		e.lock.Lock()
+		deps := append([]string(nil), e.deps[id]...)
+		e.lock.Unlock()
+		var schema []modInterfaces.ConfigField
+		if sp, ok := inst.Impl.(modInterfaces.ConfigSchemaProvider); ok {
+			schema = sp.ConfigSchema()
+		}
+		status := statusOf(inst)
+		status.HandlerFailures = e.eventBus.HandlerFailures(id)
+		list = append(list, modInterfaces.ModuleInfo{
+			ID:           id,
+			Name:         inst.Impl.Name(),
+			Description:  inst.Impl.Description(),
+			Version:      inst.Impl.Version(),
+			Author:       inst.Impl.Author(),
+			Enabled:      enabled,
+			Dependencies: deps,
+			ConfigSchema: schema,
+			Status:       status,
+		})
+	}
+	return list
+}
+
+// 启动单个模块并更新状态，调用方负责检查依赖
+func (e *CoreEngine) startInstance(id string, inst *modInterfaces.ModuleInstance) error {
+	inst.Mutex.Lock()
+	if inst.Status.Running {
+		inst.Mutex.Unlock()
+		return fmt.Errorf("module %s is already running", id)
+	}
+	err := e.prepareInstance(id, inst)
+	if err == nil {
+		err = safeCall(inst.Impl.Start)
+	}
+	if err != nil {
+		inst.Status.LastError = err
+	} else {
+		inst.Status.Running = true
+		inst.Status.StartTime = time.Now()
+		inst.Status.LastError = nil
+		inst.Status.Skipped = false
+		inst.Status.SkipReason = ""
+	}
+	policy := restartPolicy(inst)
+	inst.Mutex.Unlock()
+
+	if err != nil {
+		e.publishLifecycle(TopicModuleFailed, id, err)
+		// always 策略下启动失败也会按退避时间重试
+		if policy == RestartAlways {
+			e.scheduleRestart(id)
+		}
+		return err
+	}
+	e.eventBus.ResetHandlerFailures(id)
+	e.log("info", fmt.Sprintf("Module %s started", id))
+	e.publishLifecycle(TopicModuleStarted, id, nil)
+	return nil
+}
+
+// 调用模块的 Prepare，事件声明可能在准备时才确定（如外部模块握手），因此准备后重新设置访问控制
+func (e *CoreEngine) prepareInstance(id string, inst *modInterfaces.ModuleInstance) error {
+	p, ok := inst.Impl.(modInterfaces.Preparer)
+	if !ok {
+		return nil
+	}
+	if err := safeCall(p.Prepare); err != nil {
+		return err
+	}
+	e.applyModuleACL(id, inst.Impl, inst.Ctx.Config)
+	return nil
+}
+
+// 停止单个模块并更新状态
+func (e *CoreEngine) stopInstance(id string, inst *modInterfaces.ModuleInstance) error {
+	inst.Mutex.Lock()
+	if !inst.Status.Running {
+		inst.Mutex.Unlock()
+		return fmt.Errorf("module %s is not running", id)
+	}
+	err := e.stopWithDeadline(id, inst)
+	switch {
+	case err == nil:
+		inst.Status.Running = false
+		inst.Status.EndTime = time.Now()
+	case errors.Is(err, errStopTimeout):
+		// 超时的模块视为已停止，不再等待
+		inst.Status.Running = false
+		inst.Status.EndTime = time.Now()
+		inst.Status.LastError = err
+	default:
+		inst.Status.LastError = err
+	}
+	inst.Mutex.Unlock()
+
+	if err == nil || errors.Is(err, errStopTimeout) {
+		e.cancelSubscriptions(id)
+	}
+	if err != nil {
+		e.publishLifecycle(TopicModuleFailed, id, err)
+		return err
+	}
+	e.log("info", fmt.Sprintf("Stopping module %s", id))
+	e.publishLifecycle(TopicModuleStopped, id, nil)
+	return nil
+}
+
+// 获取已注册的模块实例
+func (e *CoreEngine) instance(id string) (*modInterfaces.ModuleInstance, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	inst, ok := e.modules[id]
+	if !ok {
+		return nil, fmt.Errorf("module %s not registered", id)
+	}
+	return inst, nil
+}
+
+// 获取直接依赖指定模块的模块ID列表
+func (e *CoreEngine) dependents(id string) []string {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	var result []string
+	for _, other := range e.order {
+		for _, dep := range e.deps[other] {
+			if dep == id {
+				result = append(result, other)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// 取消模块的全部事件订阅，模块重新启动时在 Start 中重新订阅
+func (e *CoreEngine) cancelSubscriptions(id string) {
+	if n := e.eventBus.CancelOwner(id); n > 0 {
+		e.log("info", fmt.Sprintf("Cancelled %d event subscriptions of module %s", n, id))
+	}
+}
+
+// 发布模块生命周期事件
+func (e *CoreEngine) publishLifecycle(topic modInterfaces.Topic[modInterfaces.ModuleLifecycle], id string, err error) {
+	data := modInterfaces.ModuleLifecycle{ID: id}
+	if err != nil {
+		data.Error = err.Error()
+	}
+	modInterfaces.Publish(e.eventBus.Owner("core"), topic, data)
+}
+
+// 在模块锁内读取状态快照
+func statusOf(inst *modInterfaces.ModuleInstance) modInterfaces.ModuleStatus {
+	inst.Mutex.Lock()
+	defer inst.Mutex.Unlock()
+	return inst.Status
+}
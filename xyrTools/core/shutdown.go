@@ -0,0 +1,112 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"xyrTools/xyrTools/modInterfaces"
+)
+
+// 模块停止超时的默认值，可通过配置文件 shutdown 节或模块的 stopTimeout 修改
+const defaultStopTimeout = 5 * time.Second
+
+// 模块未在超时时间内停止
+var errStopTimeout = errors.New("stop timed out")
+
+// 配置文件 shutdown 节
+type shutdownConfig struct {
+	StopTimeout time.Duration `yaml:"stopTimeout" default:"5s" min:"100ms" max:"10m" desc:"每个模块停止的超时时间，超时后放弃等待并继续退出"`
+}
+
+// 按配置文件 shutdown 节设置默认停止超时，配置错误时保留当前设置
+func (e *CoreEngine) applyShutdownConfig(cfg map[string]interface{}) {
+	section := make(map[string]interface{})
+	if raw, ok := cfg["shutdown"].(map[interface{}]interface{}); ok {
+		section = convertMap(raw)
+	}
+	var sc shutdownConfig
+	if err := modInterfaces.DecodeConfig(section, &sc); err != nil {
+		e.log("error", fmt.Sprintf("Invalid shutdown config, keeping current settings: %v", err))
+		return
+	}
+	e.lock.Lock()
+	e.stopTimeout = sc.StopTimeout
+	e.lock.Unlock()
+}
+
+// 模块的停止超时时间：模块配置的 stopTimeout 优先，其次为 shutdown 节的默认值
+func (e *CoreEngine) stopTimeoutOf(id string, inst *modInterfaces.ModuleInstance) time.Duration {
+	def := e.globalStopTimeout()
+	d, err := modInterfaces.ConfigDuration(inst.Ctx.Config, "stopTimeout", def)
+	if err != nil || d <= 0 {
+		e.log("warn", fmt.Sprintf("Module %s has invalid stopTimeout, using %s", id, def))
+		return def
+	}
+	return d
+}
+
+// 在超时时间内停止模块，超时后放弃等待（模块的 Stop 仍在后台执行），返回 errStopTimeout。
+// 调用方需持有 inst.Mutex。
+func (e *CoreEngine) stopWithDeadline(id string, inst *modInterfaces.ModuleInstance) error {
+	timeout := e.stopTimeoutOf(id, inst)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- safeCall(func() error { return inst.Impl.Stop(ctx) })
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		e.log("warn", fmt.Sprintf("Module %s did not stop within %s, abandoning", id, timeout))
+		return fmt.Errorf("module %s: %w after %s", id, errStopTimeout, timeout)
+	}
+}
+
+// ShutdownRequested 收到退出请求（core:shutdownRequested 事件或 RequestShutdown）时关闭
+func (e *CoreEngine) ShutdownRequested() <-chan struct{} {
+	return e.shutdownCh
+}
+
+// RequestShutdown 请求退出程序，重复请求只记录第一次
+func (e *CoreEngine) RequestShutdown(source, reason string) {
+	e.shutdownOnce.Do(func() {
+		e.log("info", fmt.Sprintf("Shutdown requested by %s: %s", source, reason))
+		close(e.shutdownCh)
+	})
+}
+
+// 处理模块通过事件总线发来的退出请求
+func (e *CoreEngine) onShutdownRequested(req modInterfaces.ShutdownRequest, evt modInterfaces.Event) {
+	e.log("info", fmt.Sprintf("Received %s", evt))
+	e.RequestShutdown(req.Source, req.Reason)
+}
+
+// 配置文件 shutdown 节的停止超时
+func (e *CoreEngine) globalStopTimeout() time.Duration {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.stopTimeout
+}
+
+// 等待事件总线中已发布的事件处理完，最多等待默认停止超时
+func (e *CoreEngine) drainEvents() {
+	ctx, cancel := context.WithTimeout(context.Background(), e.globalStopTimeout())
+	defer cancel()
+	if err := e.eventBus.Drain(ctx); err != nil {
+		e.log("warn", fmt.Sprintf("Pending events were not delivered before shutdown: %v", err))
+	}
+}
+
+// 关闭事件总线，处理完剩余事件后结束各订阅的处理协程
+func (e *CoreEngine) closeEvents() {
+	ctx, cancel := context.WithTimeout(context.Background(), e.globalStopTimeout())
+	defer cancel()
+	if err := e.eventBus.Close(ctx); err != nil {
+		e.log("warn", fmt.Sprintf("Event bus did not close cleanly: %v", err))
+	}
+}
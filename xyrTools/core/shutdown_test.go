@@ -0,0 +1,75 @@
+package core
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"xyrTools/xyrTools/modInterfaces"
+)
+
+// 停止超时的模块被放弃，其余模块照常停止，退出不会等待超时的模块
+func TestStopAllDeadline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, `shutdown:
+  stopTimeout: 2s
+fast:
+  enabled: true
+slow:
+  enabled: true
+  stopTimeout: 100ms
+last:
+  enabled: true
+`)
+	var lock sync.Mutex
+	var logs []string
+	e := NewCoreEngine(func(level, msg string) {
+		lock.Lock()
+		logs = append(logs, msg)
+		lock.Unlock()
+	})
+	if err := e.LoadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	calls := &callLog{}
+	mustRegister(t, e,
+		&fakeModule{id: "fast", calls: calls},
+		&fakeModule{id: "slow", calls: calls, stopDelay: 5 * time.Second},
+		&fakeModule{id: "last", calls: calls},
+	)
+	e.StartAll()
+
+	begin := time.Now()
+	e.StopAll()
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Fatalf("StopAll took %s, want the slow module abandoned after 100ms", elapsed)
+	}
+	if got, want := calls.String(), "start:fast start:slow start:last stop:last stop:fast"; got != want {
+		t.Fatalf("calls = %q, want %q", got, want)
+	}
+	status := moduleStatus(t, e, "slow")
+	if status.Running || !errors.Is(status.LastError, errStopTimeout) {
+		t.Fatalf("slow status = %+v, want stopped with errStopTimeout", status)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if !strings.Contains(strings.Join(logs, "\n"), "Modules missed the stop deadline: slow") {
+		t.Fatalf("missed deadline not logged:\n%s", strings.Join(logs, "\n"))
+	}
+}
+
+// 通过事件总线请求退出
+func TestShutdownRequestedEvent(t *testing.T) {
+	e, _ := newTestEngine(t, enabledConfig("a"))
+	modInterfaces.Publish(e.GetEventBus(), modInterfaces.TopicShutdownRequested, modInterfaces.ShutdownRequest{Source: "a", Reason: "test"})
+	select {
+	case <-e.ShutdownRequested():
+	case <-time.After(3 * time.Second):
+		t.Fatal("shutdown request not received")
+	}
+	// 重复请求不会 panic
+	e.RequestShutdown("b", "again")
+}
@@ -0,0 +1,238 @@
+package core
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"xyrTools/xyrTools/modInterfaces"
+)
+
+// 模块重启策略，对应模块配置中的 restart 项
+const (
+	RestartNever     = "never"      // 不自动重启（默认）
+	RestartOnFailure = "on-failure" // 运行中异常（panic 或上报错误）后重启
+	RestartAlways    = "always"     // 在 on-failure 基础上，启动失败也会重试
+)
+
+// 重启退避参数：首次等待 restartBaseDelay，之后每次翻倍，最长 restartMaxDelay。
+// 模块稳定运行超过 restartStableAfter 后再崩溃，退避从头计算。测试中会缩短这些时间
+var (
+	restartBaseDelay   = time.Second
+	restartMaxDelay    = time.Minute
+	restartStableAfter = time.Minute
+)
+
+// 单个模块的重启状态
+type restartState struct {
+	attempts int         // 连续重启次数，用于计算退避时间
+	timer    *time.Timer // 等待中的重启任务
+}
+
+// 模块故障上报器，注入到模块上下文中
+type moduleReporter struct {
+	engine *CoreEngine
+	id     string
+}
+
+func (r moduleReporter) ReportFailure(err error, stack string) {
+	// 异步处理：上报可能来自模块自身的协程，直接停止模块会等待该协程退出而死锁
+	go r.engine.handleFailure(r.id, err, stack)
+}
+
+// 处理模块运行中的故障：记录状态、发布事件，并按重启策略安排重启
+func (e *CoreEngine) handleFailure(id string, err error, stack string) {
+	inst, getErr := e.instance(id)
+	if getErr != nil {
+		return
+	}
+
+	inst.Mutex.Lock()
+	if !inst.Status.Running {
+		// 模块已停止或已被判定为故障，忽略重复上报
+		inst.Mutex.Unlock()
+		e.log("warn", fmt.Sprintf("Ignoring failure from stopped module %s: %v", id, err))
+		return
+	}
+	now := time.Now()
+	uptime := now.Sub(inst.Status.StartTime)
+	inst.Status.Running = false
+	inst.Status.LastError = err
+	inst.Status.EndTime = now
+	inst.Status.LastCrashTime = now
+	inst.Status.LastCrashStack = stack
+	policy := restartPolicy(inst)
+	// 清理模块残留的协程和资源
+	if stopErr := e.stopWithDeadline(id, inst); stopErr != nil {
+		e.log("warn", fmt.Sprintf("Module %s cleanup after failure: %v", id, stopErr))
+	}
+	inst.Mutex.Unlock()
+	e.cancelSubscriptions(id)
+
+	if stack != "" {
+		e.log("error", fmt.Sprintf("Module %s crashed: %v\n%s", id, err, stack))
+	} else {
+		e.log("error", fmt.Sprintf("Module %s failed: %v", id, err))
+	}
+	e.publishLifecycle(TopicModuleFailed, id, err)
+
+	if policy == RestartNever {
+		return
+	}
+	if uptime >= restartStableAfter {
+		e.resetRestarts(id)
+	}
+	e.scheduleRestart(id)
+}
+
+// 模块的事件处理函数失败：模块配置了 maxHandlerFailures 时，本次启动以来的失败次数达到该值即按模块故障处理
+func (e *CoreEngine) onHandlerFailed(f modInterfaces.HandlerFailure, evt modInterfaces.Event) {
+	inst, err := e.instance(f.Module)
+	if err != nil {
+		return
+	}
+	limit, ok := inst.Ctx.Config["maxHandlerFailures"].(int)
+	if !ok || limit <= 0 {
+		return
+	}
+	n := e.eventBus.HandlerFailures(f.Module)
+	if n < uint64(limit) {
+		return
+	}
+	// 异步处理：此处在事件总线的投递协程中，停止模块会阻塞投递，模块停止时等待事件投递还会死锁
+	go e.handleFailure(f.Module, fmt.Errorf("%d event handler failures, last on %s: %s", n, f.Event.Name, f.Error), f.Stack)
+}
+
+// 按退避时间安排一次重启
+func (e *CoreEngine) scheduleRestart(id string) {
+	inst, err := e.instance(id)
+	if err != nil {
+		return
+	}
+	maxRestarts := 0
+	if n, ok := inst.Ctx.Config["maxRestarts"].(int); ok {
+		maxRestarts = n
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.shuttingDown {
+		return
+	}
+	st, ok := e.restarts[id]
+	if !ok {
+		st = &restartState{}
+		e.restarts[id] = st
+	}
+	if maxRestarts > 0 && st.attempts >= maxRestarts {
+		e.log("error", fmt.Sprintf("Module %s reached max restarts (%d), giving up", id, maxRestarts))
+		return
+	}
+	if st.timer != nil {
+		st.timer.Stop()
+	}
+	delay := restartDelay(st.attempts)
+	st.attempts++
+	e.log("info", fmt.Sprintf("Restarting module %s in %s (attempt %d)", id, delay, st.attempts))
+	st.timer = time.AfterFunc(delay, func() { e.restartModule(id) })
+}
+
+// 第 attempts+1 次重启前的等待时间：从 restartBaseDelay 开始每次翻倍，最长 restartMaxDelay
+func restartDelay(attempts int) time.Duration {
+	delay := restartBaseDelay
+	for i := 0; i < attempts && delay < restartMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, restartMaxDelay)
+}
+
+// 执行重启，失败（包括依赖模块未运行）时按策略继续安排下一次重启
+func (e *CoreEngine) restartModule(id string) {
+	inst, err := e.instance(id)
+	if err != nil {
+		return
+	}
+
+	// 与 StartModule 一样，依赖模块未运行时不启动
+	reason := e.unmetDependency(id)
+	inst.Mutex.Lock()
+	if inst.Status.Running {
+		// 已被手动启动
+		inst.Mutex.Unlock()
+		return
+	}
+	if reason != "" {
+		err = fmt.Errorf("cannot restart module %s: %s", id, reason)
+	} else if err = e.prepareInstance(id, inst); err == nil {
+		err = safeCall(inst.Impl.Start)
+	}
+	if err != nil {
+		inst.Status.LastError = err
+	} else {
+		inst.Status.Running = true
+		inst.Status.StartTime = time.Now()
+		inst.Status.Restarts++
+	}
+	inst.Mutex.Unlock()
+
+	if err != nil {
+		e.log("error", fmt.Sprintf("Module %s failed to restart: %v", id, err))
+		e.publishLifecycle(TopicModuleFailed, id, err)
+		e.scheduleRestart(id)
+		return
+	}
+	e.eventBus.ResetHandlerFailures(id)
+	e.log("info", fmt.Sprintf("Module %s restarted", id))
+	e.publishLifecycle(TopicModuleStarted, id, nil)
+}
+
+// 取消等待中的重启并清零重启计数（手动停止模块时调用）
+func (e *CoreEngine) cancelRestart(id string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if st, ok := e.restarts[id]; ok {
+		if st.timer != nil {
+			st.timer.Stop()
+		}
+		delete(e.restarts, id)
+	}
+}
+
+// 清零连续重启计数
+func (e *CoreEngine) resetRestarts(id string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if st, ok := e.restarts[id]; ok {
+		st.attempts = 0
+	}
+}
+
+// 可选接口：模块未配置 restart 时使用的默认重启策略（如外部模块进程崩溃后默认重启）
+type defaultRestartPolicy interface {
+	DefaultRestartPolicy() string
+}
+
+// 读取模块重启策略，未配置时使用模块默认策略，都没有或配置非法时为 never
+func restartPolicy(inst *modInterfaces.ModuleInstance) string {
+	policy, ok := inst.Ctx.Config["restart"].(string)
+	if !ok {
+		if d, isDefault := inst.Impl.(defaultRestartPolicy); isDefault {
+			policy = d.DefaultRestartPolicy()
+		}
+	}
+	switch policy {
+	case RestartOnFailure, RestartAlways:
+		return policy
+	}
+	return RestartNever
+}
+
+// 调用模块方法并捕获 panic，避免重启过程中的 panic 导致进程退出
+func safeCall(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return fn()
+}
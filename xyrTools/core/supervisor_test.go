@@ -0,0 +1,236 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"xyrTools/xyrTools/modInterfaces"
+)
+
+// 在模块受监管的协程中 panic，模拟模块运行中崩溃
+func (m *fakeModule) crash() {
+	m.lock.Lock()
+	ctx := m.ctx
+	m.lock.Unlock()
+	ctx.Go(func() { panic("boom") })
+}
+
+// 缩短重启退避时间，测试结束后恢复
+func fastRestarts(t *testing.T, stableAfter time.Duration) {
+	t.Helper()
+	base, max, stable := restartBaseDelay, restartMaxDelay, restartStableAfter
+	restartBaseDelay, restartMaxDelay, restartStableAfter = 10*time.Millisecond, 40*time.Millisecond, stableAfter
+	t.Cleanup(func() {
+		restartBaseDelay, restartMaxDelay, restartStableAfter = base, max, stable
+	})
+}
+
+// 等待条件成立，超时则失败
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// 等待一段时间，确认条件始终不成立
+func never(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(200 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		if cond() {
+			t.Fatalf("unexpected %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func (e *CoreEngine) restartAttempts(id string) int {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if st, ok := e.restarts[id]; ok {
+		return st.attempts
+	}
+	return 0
+}
+
+func TestRestartDelayDoubles(t *testing.T) {
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 32 * time.Second, time.Minute, time.Minute}
+	for attempts, d := range want {
+		if got := restartDelay(attempts); got != d {
+			t.Errorf("restartDelay(%d) = %s, want %s", attempts, got, d)
+		}
+	}
+	if got := restartDelay(100); got != restartMaxDelay {
+		t.Errorf("restartDelay(100) = %s, want %s", got, restartMaxDelay)
+	}
+}
+
+// 运行中 panic 后按 on-failure 策略重启，记录崩溃调用栈
+func TestRestartAfterPanic(t *testing.T) {
+	fastRestarts(t, time.Minute)
+	e, _ := newTestEngine(t, "worker:\n  enabled: true\n  restart: on-failure\n")
+	m := &fakeModule{id: "worker"}
+	mustRegister(t, e, m)
+	e.StartAll()
+	defer e.StopAll()
+
+	m.crash()
+	waitFor(t, "restart", func() bool { return moduleStatus(t, e, "worker").Restarts == 1 })
+	status := moduleStatus(t, e, "worker")
+	if !status.Running || m.startCount() != 2 {
+		t.Fatalf("status = %+v, starts = %d", status, m.startCount())
+	}
+	if !strings.Contains(status.LastCrashStack, "panic") || status.LastError == nil {
+		t.Fatalf("crash not recorded: %+v", status)
+	}
+}
+
+// 连续重启失败达到 maxRestarts 后放弃
+func TestMaxRestarts(t *testing.T) {
+	fastRestarts(t, time.Minute)
+	e, _ := newTestEngine(t, "worker:\n  enabled: true\n  restart: on-failure\n  maxRestarts: 2\n")
+	m := &fakeModule{id: "worker"}
+	mustRegister(t, e, m)
+	e.StartAll()
+	defer e.StopAll()
+
+	m.setStart(errors.New("still broken"), false)
+	m.crash()
+	waitFor(t, "two restart attempts", func() bool { return m.startCount() == 3 })
+	never(t, "restart beyond maxRestarts", func() bool { return m.startCount() > 3 })
+	if status := moduleStatus(t, e, "worker"); status.Running || status.LastError == nil {
+		t.Fatalf("status = %+v, want stopped with error", status)
+	}
+}
+
+// 稳定运行超过 restartStableAfter 后再崩溃，退避从头计算
+func TestRestartBackoffResetsAfterStableRun(t *testing.T) {
+	fastRestarts(t, 150*time.Millisecond)
+	e, _ := newTestEngine(t, "worker:\n  enabled: true\n  restart: on-failure\n")
+	m := &fakeModule{id: "worker"}
+	mustRegister(t, e, m)
+	e.StartAll()
+	defer e.StopAll()
+
+	for i := 1; i <= 2; i++ {
+		m.crash()
+		waitFor(t, "restart", func() bool { return moduleStatus(t, e, "worker").Restarts == i })
+	}
+	if n := e.restartAttempts("worker"); n != 2 {
+		t.Fatalf("attempts after quick crashes = %d, want 2", n)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	m.crash()
+	waitFor(t, "restart", func() bool { return moduleStatus(t, e, "worker").Restarts == 3 })
+	if n := e.restartAttempts("worker"); n != 1 {
+		t.Fatalf("attempts after stable run = %d, want 1", n)
+	}
+}
+
+func TestRestartPolicies(t *testing.T) {
+	fastRestarts(t, time.Minute)
+	e, _ := newTestEngine(t, `never:
+  enabled: true
+  restart: never
+onFailure:
+  enabled: true
+  restart: on-failure
+always:
+  enabled: true
+  restart: always
+`)
+	neverMod := &fakeModule{id: "never"}
+	onFailure := &fakeModule{id: "onFailure", startPanic: true}
+	always := &fakeModule{id: "always", startPanic: true}
+	mustRegister(t, e, neverMod, onFailure, always)
+	e.StartAll()
+	defer e.StopAll()
+
+	// 启动失败只有 always 会重试
+	waitFor(t, "always to retry", func() bool { return always.startCount() >= 2 })
+	always.setStart(nil, false)
+	waitFor(t, "always to start", func() bool { return moduleStatus(t, e, "always").Running })
+	if n := onFailure.startCount(); n != 1 {
+		t.Fatalf("on-failure module retried a failed start %d times", n-1)
+	}
+
+	// 运行中崩溃：never 不重启
+	neverMod.crash()
+	never(t, "restart with policy never", func() bool { return neverMod.startCount() > 1 })
+	if moduleStatus(t, e, "never").Running {
+		t.Fatal("never module still running after crash")
+	}
+}
+
+// 依赖模块未运行时不重启，与手动启动一致
+func TestRestartChecksDependencies(t *testing.T) {
+	fastRestarts(t, time.Minute)
+	e, _ := newTestEngine(t, `db:
+  enabled: true
+web:
+  enabled: true
+  restart: on-failure
+  maxRestarts: 1
+`)
+	db := &fakeModule{id: "db"}
+	web := &fakeModule{id: "web", deps: []string{"db"}}
+	mustRegister(t, e, db, web)
+	e.StartAll()
+	defer e.StopAll()
+
+	db.crash()
+	waitFor(t, "db to stop", func() bool { return !moduleStatus(t, e, "db").Running })
+	web.crash()
+	waitFor(t, "restart attempt", func() bool { return e.restartAttempts("web") == 1 })
+	waitFor(t, "restart to be refused", func() bool {
+		err := moduleStatus(t, e, "web").LastError
+		return err != nil && strings.Contains(err.Error(), "dependency db failed")
+	})
+	if n := web.startCount(); n != 1 {
+		t.Fatalf("web started %d times while db was down", n)
+	}
+}
+
+// 事件处理函数失败次数达到 maxHandlerFailures 时按模块故障处理；
+// 停止较慢的模块不会阻塞其他模块的故障处理
+func TestHandlerFailuresDoNotBlockDelivery(t *testing.T) {
+	fastRestarts(t, time.Minute)
+	e, _ := newTestEngine(t, `slow:
+  enabled: true
+  maxHandlerFailures: 1
+  stopTimeout: 2s
+fast:
+  enabled: true
+  maxHandlerFailures: 1
+`)
+	stopping := make(chan struct{})
+	slow := &fakeModule{id: "slow", stopDelay: time.Second, stopCalled: stopping}
+	fast := &fakeModule{id: "fast"}
+	mustRegister(t, e, slow, fast)
+	e.StartAll()
+	defer e.StopAll()
+	for _, m := range []*fakeModule{slow, fast} {
+		m.ctx.Events.Subscribe("test:"+m.id, func(modInterfaces.Event) { panic("handler failed") })
+	}
+
+	e.GetEventBus().Publish("test:slow", nil)
+	select {
+	case <-stopping:
+	case <-time.After(3 * time.Second):
+		t.Fatal("slow module not stopped after handler failure")
+	}
+	begin := time.Now()
+	e.GetEventBus().Publish("test:fast", nil)
+	waitFor(t, "fast module failure", func() bool { return moduleStatus(t, e, "fast").LastError != nil })
+	if elapsed := time.Since(begin); elapsed > 500*time.Millisecond {
+		t.Fatalf("fast module failure handled after %s, blocked by the slow module's stop", elapsed)
+	}
+}
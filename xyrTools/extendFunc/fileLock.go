@@ -3,24 +3,12 @@ package extendFunc
 import (
 	"fmt"
 	"os"
-	"syscall"
-	"unsafe"
 )
 
 var (
 	lockFilePath = "./program.lock"
-
-	user32         = syscall.NewLazyDLL("user32.dll")
-	procMessageBox = user32.NewProc("MessageBoxW")
 )
 
-// 弹出消息框
-func MessageBox(title, text string) {
-	titlePtr, _ := syscall.UTF16PtrFromString(title)
-	textPtr, _ := syscall.UTF16PtrFromString(text)
-	procMessageBox.Call(0, uintptr(unsafe.Pointer(textPtr)), uintptr(unsafe.Pointer(titlePtr)), 0)
-}
-
 func CheckLockFile() bool {
 	// 检查锁文件是否已经存在
 	if _, err := os.Stat(lockFilePath); err == nil {
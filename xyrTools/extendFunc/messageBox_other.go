@@ -0,0 +1,16 @@
+//go:build !windows
+
+package extendFunc
+
+import "fmt"
+
+// 非 Windows 系统没有消息框，输出到标准输出
+func MessageBox(title, text string) {
+	fmt.Printf("[%s] %s\n", title, text)
+}
+
+// 非 Windows 系统无法询问，输出到标准输出并视为选择“否”
+func MessageBoxYesNo(title, text string) bool {
+	fmt.Printf("[%s] %s\n", title, text)
+	return false
+}
@@ -0,0 +1,34 @@
+package extendFunc
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var (
+	user32         = syscall.NewLazyDLL("user32.dll")
+	procMessageBox = user32.NewProc("MessageBoxW")
+)
+
+// 弹出消息框
+func MessageBox(title, text string) {
+	titlePtr, _ := syscall.UTF16PtrFromString(title)
+	textPtr, _ := syscall.UTF16PtrFromString(text)
+	procMessageBox.Call(0, uintptr(unsafe.Pointer(textPtr)), uintptr(unsafe.Pointer(titlePtr)), 0)
+}
+
+// MessageBox 的按钮、图标和返回值
+const (
+	mbYesNo        = 0x00000004
+	mbIconQuestion = 0x00000020
+	mbTopmost      = 0x00040000
+	idYes          = 6
+)
+
+// 弹出是否对话框，点击“是”时返回 true
+func MessageBoxYesNo(title, text string) bool {
+	titlePtr, _ := syscall.UTF16PtrFromString(title)
+	textPtr, _ := syscall.UTF16PtrFromString(text)
+	ret, _, _ := procMessageBox.Call(0, uintptr(unsafe.Pointer(textPtr)), uintptr(unsafe.Pointer(titlePtr)), mbYesNo|mbIconQuestion|mbTopmost)
+	return ret == idYes
+}
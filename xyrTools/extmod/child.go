@@ -0,0 +1,287 @@
+package extmod
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"xyrTools/xyrTools/modInterfaces"
+
+	"myMod/xlog"
+)
+
+// Serve 在外部模块进程中运行，把 mod 通过标准输入输出暴露给主程序，直到主程序关闭连接。
+// 外部模块不要向标准输出打印内容，调试信息请写标准错误输出或使用 Context.Log。
+func Serve(mod modInterfaces.Module) error {
+	s := &server{mod: mod, handlers: make(map[uint64]func(modInterfaces.Event))}
+	s.bus = &remoteBus{server: s}
+	s.conn = newRPCConn(os.Stdin, os.Stdout, s.handle)
+	<-s.conn.Done()
+
+	// 主程序已断开，确保模块停止
+	if mod.Status().Running {
+		ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+		_ = mod.Stop(ctx)
+		cancel()
+	}
+	if errors.Is(s.conn.err, errConnClosed) {
+		return nil
+	}
+	return s.conn.err
+}
+
+// 外部模块进程中的服务端
+type server struct {
+	mod  modInterfaces.Module
+	conn *rpcConn
+	bus  *remoteBus
+	ctx  modInterfaces.Context
+
+	lock     sync.Mutex
+	handlers map[uint64]func(modInterfaces.Event) // 订阅ID -> 事件处理函数
+}
+
+// 处理主程序发来的请求
+func (s *server) handle(method string, raw json.RawMessage) (interface{}, error) {
+	switch method {
+	case "module.info":
+		info := moduleInfo{
+			ID:          s.mod.ID(),
+			Name:        s.mod.Name(),
+			Description: s.mod.Description(),
+			Version:     s.mod.Version(),
+			Author:      s.mod.Author(),
+		}
+		if d, ok := s.mod.(modInterfaces.EventDeclarer); ok {
+			info.Publishes, info.Subscribes = d.PublishesEvents(), d.SubscribesEvents()
+		}
+		return info, nil
+
+	case "module.init":
+		var p initParams
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, err
+		}
+		s.ctx = modInterfaces.Context{
+			Config:   p.Config,
+			Log:      s.log,
+			Logger:   xlog.FromFunc(s.log),
+			Events:   s.bus,
+			Failures: s,
+		}
+		return nil, s.mod.Init(s.ctx)
+
+	case "module.start":
+		return nil, s.mod.Start()
+
+	case "module.stop":
+		var p stopParams
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, &p); err != nil {
+				return nil, err
+			}
+		}
+		timeout := callTimeout
+		if p.Timeout > 0 {
+			timeout = time.Duration(p.Timeout) * time.Millisecond
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		return nil, s.mod.Stop(ctx)
+
+	case "module.status":
+		status := s.mod.Status()
+		result := statusResult{Running: status.Running}
+		if status.LastError != nil {
+			result.LastError = status.LastError.Error()
+		}
+		return result, nil
+
+	case "module.reload":
+		var p reloadParams
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, err
+		}
+		ctx := s.ctx
+		ctx.Config = p.Config
+		if err := s.mod.Reload(ctx); err != nil {
+			return nil, err
+		}
+		s.ctx = ctx
+		return nil, nil
+
+	case "bus.event":
+		var p eventParams
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, err
+		}
+		data, err := modInterfaces.DecodeEventData(p.Name, p.Data)
+		if err != nil {
+			return nil, err
+		}
+		s.lock.Lock()
+		handler, ok := s.handlers[p.Subscription]
+		s.lock.Unlock()
+		if !ok {
+			return eventResult{}, nil
+		}
+		if !p.Request {
+			handler(modInterfaces.Event{Name: p.Name, Data: data, EventMeta: p.Meta.meta()})
+			return nil, nil
+		}
+		// 请求：外部模块的处理函数需要在返回前调用 Respond
+		evt, replies := modInterfaces.NewRequestEvent(p.Name, data, p.Meta.meta())
+		handler(evt)
+		select {
+		case r := <-replies:
+			result := eventResult{Responded: true}
+			if r.Err != nil {
+				result.Error = r.Err.Error()
+			}
+			if result.Data, err = json.Marshal(r.Data); err != nil {
+				return nil, err
+			}
+			return result, nil
+		default:
+			return eventResult{}, nil
+		}
+	}
+	return nil, methodNotFound(method)
+}
+
+// 带超时调用主程序
+func (s *server) call(method string, params, result interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	return s.conn.Call(ctx, method, params, result)
+}
+
+// 日志转发给主程序
+func (s *server) log(level, msg string) {
+	if err := s.conn.Notify("log", logParams{Level: level, Msg: msg}); err != nil {
+		fmt.Fprintf(os.Stderr, "[%s] %s\n", level, msg)
+	}
+}
+
+// ReportFailure 异步故障转发给主程序，调用栈附在错误信息后
+func (s *server) ReportFailure(err error, stack string) {
+	msg := err.Error()
+	if stack != "" {
+		msg += "\n" + stack
+	}
+	_ = s.conn.Notify("module.failed", failedParams{Error: msg})
+}
+
+// 外部模块进程内的事件总线代理，发布和订阅都转发给主程序的事件总线
+type remoteBus struct {
+	server *server
+}
+
+// 投递选项只在主进程一侧生效，事件到达子进程后各自在独立协程中处理，不保证顺序
+func (b *remoteBus) Subscribe(event string, handler func(modInterfaces.Event), opts ...modInterfaces.SubscribeOption) modInterfaces.Subscription {
+	sub := &remoteSubscription{server: b.server, event: event}
+	mode, size, overflow := modInterfaces.DeliveryOf(opts...)
+	params := subscribeParams{Event: event, Mode: "queued", QueueSize: size, Overflow: overflow.String()}
+	if mode == modInterfaces.DeliverSync {
+		params = subscribeParams{Event: event, Mode: "sync"}
+	}
+	var result subscribeResult
+	if err := b.server.call("bus.subscribe", params, &result); err != nil {
+		// 订阅失败时返回不会收到事件的订阅
+		b.server.log("error", fmt.Sprintf("subscribe %s failed: %v", event, err))
+		return sub
+	}
+	sub.id = result.Subscription
+	b.server.lock.Lock()
+	b.server.handlers[sub.id] = handler
+	b.server.lock.Unlock()
+	return sub
+}
+
+func (b *remoteBus) Request(ctx context.Context, event string, data interface{}) (interface{}, error) {
+	return b.request(ctx, nil, event, data)
+}
+
+func (b *remoteBus) RequestCaused(ctx context.Context, cause modInterfaces.EventMeta, event string, data interface{}) (interface{}, error) {
+	c := toEventMeta(cause)
+	return b.request(ctx, &c, event, data)
+}
+
+func (b *remoteBus) request(ctx context.Context, cause *eventMeta, event string, data interface{}) (interface{}, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, modInterfaces.DefaultRequestTimeout)
+		defer cancel()
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	deadline, _ := ctx.Deadline()
+	params := requestParams{Event: event, Data: raw, Timeout: time.Until(deadline).Milliseconds(), Cause: cause}
+	var result requestResult
+	if err := b.server.conn.Call(ctx, "bus.request", params, &result); err != nil {
+		var rpcErr *rpcError
+		if errors.As(err, &rpcErr) {
+			if rpcErr.Code == codeNoHandler {
+				return nil, fmt.Errorf("%w: %s", modInterfaces.ErrNoHandler, event)
+			}
+			// 处理函数回复的错误
+			return nil, errors.New(rpcErr.Message)
+		}
+		return nil, err
+	}
+	return modInterfaces.DecodeReplyData(event, result.Data)
+}
+
+// Logf 写入主进程日志，类型化订阅在数据无法转换时使用
+func (b *remoteBus) Logf(level, format string, args ...interface{}) {
+	b.server.log(level, fmt.Sprintf(format, args...))
+}
+
+func (b *remoteBus) Publish(event string, data interface{}) {
+	b.publish(nil, event, data)
+}
+
+func (b *remoteBus) PublishCaused(cause modInterfaces.EventMeta, event string, data interface{}) {
+	c := toEventMeta(cause)
+	b.publish(&c, event, data)
+}
+
+func (b *remoteBus) publish(cause *eventMeta, event string, data interface{}) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		b.server.log("error", fmt.Sprintf("publish %s failed: %v", event, err))
+		return
+	}
+	if err := b.server.conn.Notify("bus.publish", publishParams{Event: event, Data: raw, Cause: cause}); err != nil {
+		b.server.log("error", fmt.Sprintf("publish %s failed: %v", event, err))
+	}
+}
+
+// 外部模块进程内的订阅句柄，ID 为主程序分配的订阅ID
+type remoteSubscription struct {
+	server *server
+	id     uint64
+	event  string
+	once   sync.Once
+}
+
+func (s *remoteSubscription) ID() uint64    { return s.id }
+func (s *remoteSubscription) Event() string { return s.event }
+
+func (s *remoteSubscription) Cancel() {
+	if s.id == 0 {
+		return
+	}
+	s.once.Do(func() {
+		s.server.lock.Lock()
+		delete(s.server.handlers, s.id)
+		s.server.lock.Unlock()
+		_ = s.server.call("bus.unsubscribe", unsubscribeParams{Subscription: s.id}, nil)
+	})
+}
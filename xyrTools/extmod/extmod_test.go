@@ -0,0 +1,265 @@
+package extmod
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"xyrTools/xyrTools/modInterfaces"
+)
+
+// 设置该环境变量时测试程序作为外部模块子进程运行
+const childEnv = "XYR_EXTMOD_TEST_CHILD"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(childEnv) != "" {
+		if err := Serve(&echoModule{}); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// 子进程中运行的测试模块：
+// 启动时发布 test:started，回复 test:ping 请求，把 test:echo 转发为 test:echoed，
+// 收到 test:fail 时把事件数据记为最近的错误，收到 test:crash 时直接退出进程
+type echoModule struct {
+	ctx    modInterfaces.Context
+	lock   sync.Mutex
+	status modInterfaces.ModuleStatus
+}
+
+func (m *echoModule) ID() string          { return "echo" }
+func (m *echoModule) Name() string        { return "Echo" }
+func (m *echoModule) Description() string { return "extmod test module" }
+func (m *echoModule) Version() string     { return "1.0" }
+func (m *echoModule) Author() string      { return "test" }
+
+func (m *echoModule) Init(ctx modInterfaces.Context) error {
+	m.ctx = ctx
+	return nil
+}
+
+func (m *echoModule) Start() error {
+	bus := m.ctx.Events
+	bus.Subscribe("test:ping", func(evt modInterfaces.Event) {
+		s, _ := evt.Data.(string)
+		evt.Respond("pong:"+s, nil)
+	})
+	bus.Subscribe("test:echo", func(evt modInterfaces.Event) {
+		bus.Publish("test:echoed", evt.Data)
+	})
+	bus.Subscribe("test:fail", func(evt modInterfaces.Event) {
+		s, _ := evt.Data.(string)
+		m.lock.Lock()
+		m.status.LastError = errors.New(s)
+		m.lock.Unlock()
+	})
+	bus.Subscribe("test:crash", func(modInterfaces.Event) {
+		os.Exit(3)
+	})
+	bus.Publish("test:started", m.ctx.Config["greeting"])
+	m.lock.Lock()
+	m.status.Running = true
+	m.lock.Unlock()
+	return nil
+}
+
+func (m *echoModule) Stop(ctx context.Context) error {
+	m.lock.Lock()
+	m.status.Running = false
+	m.lock.Unlock()
+	return nil
+}
+
+func (m *echoModule) Status() modInterfaces.ModuleStatus {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.status
+}
+
+func (m *echoModule) Reload(ctx modInterfaces.Context) error {
+	m.ctx = ctx
+	return nil
+}
+
+// 记录上报的故障
+type failureRecorder struct {
+	errs chan error
+}
+
+func (r *failureRecorder) ReportFailure(err error, stack string) {
+	r.errs <- err
+}
+
+// 以测试程序自身作为外部模块可执行文件，返回模块代理、事件总线和故障记录
+func newTestModule(t *testing.T) (*ExternalModule, modInterfaces.ScopedEventBus, *failureRecorder) {
+	t.Helper()
+	t.Setenv(childEnv, "1")
+	bus := modInterfaces.NewEventBus()
+	failures := &failureRecorder{errs: make(chan error, 4)}
+	m := Factory("echo", os.Args[0], nil)().(*ExternalModule)
+	err := m.Init(modInterfaces.Context{
+		Config:   map[string]interface{}{"greeting": "hello"},
+		Log:      func(level, msg string) {},
+		Events:   bus.Owner("echo"),
+		Failures: failures,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = m.Stop(ctx)
+	})
+	return m, bus, failures
+}
+
+// 订阅事件，把事件数据写入通道
+func collect(bus modInterfaces.EventBus, event string) <-chan interface{} {
+	ch := make(chan interface{}, 4)
+	bus.Subscribe(event, func(evt modInterfaces.Event) { ch <- evt.Data })
+	return ch
+}
+
+func receive(t *testing.T, ch <-chan interface{}, what string) interface{} {
+	t.Helper()
+	select {
+	case data := <-ch:
+		return data
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+		return nil
+	}
+}
+
+func running(m *ExternalModule) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.cmd != nil
+}
+
+func TestExternalModuleLifecycle(t *testing.T) {
+	m, bus, _ := newTestModule(t)
+	if running(m) {
+		t.Fatal("process started by Init")
+	}
+
+	started := collect(bus, "test:started")
+	echoed := collect(bus, "test:echoed")
+	if err := m.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Name(); got != "Echo" {
+		t.Fatalf("Name = %q after handshake, want Echo", got)
+	}
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if got := receive(t, started, "test:started"); got != "hello" {
+		t.Fatalf("test:started data = %v, want hello", got)
+	}
+
+	bus.Publish("test:echo", "abc")
+	if got := receive(t, echoed, "test:echoed"); got != "abc" {
+		t.Fatalf("test:echoed data = %v, want abc", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reply, err := bus.Request(ctx, "test:ping", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "pong:x" {
+		t.Fatalf("reply = %v, want pong:x", reply)
+	}
+
+	// 状态由外部模块通过 module.status 返回
+	bus.Publish("test:fail", "disk full")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status := m.Status()
+		if !status.Running {
+			t.Fatal("status not running")
+		}
+		if status.LastError != nil && status.LastError.Error() == "disk full" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("lastError of the external module not reported, status = %+v", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := m.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if running(m) {
+		t.Fatal("process still running after Stop")
+	}
+	if m.Status().Running {
+		t.Fatal("status still running after Stop")
+	}
+	// 停止后外部模块的订阅已取消
+	if _, err := bus.Request(ctx, "test:ping", "x"); !errors.Is(err, modInterfaces.ErrNoHandler) {
+		t.Fatalf("Request after Stop err = %v, want ErrNoHandler", err)
+	}
+}
+
+// 进程崩溃时上报故障，再次启动时重新拉起进程
+func TestExternalModuleCrashRestart(t *testing.T) {
+	m, bus, failures := newTestModule(t)
+	started := collect(bus, "test:started")
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, started, "test:started")
+
+	bus.Publish("test:crash", nil)
+	select {
+	case err := <-failures.errs:
+		if err == nil {
+			t.Fatal("nil failure reported")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("crash not reported")
+	}
+	if running(m) {
+		t.Fatal("crashed process still recorded as running")
+	}
+	if status := m.Status(); status.Running || status.LastError == nil {
+		t.Fatalf("status after crash = %+v, want stopped with error", status)
+	}
+
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, started, "test:started after restart")
+	if status := m.Status(); !status.Running || status.LastError != nil {
+		t.Fatalf("status after restart = %+v, want running without error", status)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reply, err := bus.Request(ctx, "test:ping", "again")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "pong:again" {
+		t.Fatalf("reply = %v, want pong:again", reply)
+	}
+
+	if err := m.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-failures.errs:
+		t.Fatalf("Stop reported failure: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,497 @@
+package extmod
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"xyrTools/xyrTools/modInterfaces"
+)
+
+// 调用外部模块方法的超时时间
+const (
+	callTimeout   = 10 * time.Second
+	exitTimeout   = 5 * time.Second // 停止后等待进程退出的时间，超时强制结束
+	statusTimeout = time.Second     // 查询状态的超时时间，超时时返回主程序记录的状态
+)
+
+// 外部模块在主程序中的代理，实现 modInterfaces.Module，
+// 对引擎而言与进程内模块没有区别（配置注入、日志、状态、事件总线、故障重启）。
+type ExternalModule struct {
+	id   string
+	path string
+	args []string
+
+	ctx  modInterfaces.Context
+	info moduleInfo
+
+	lock     sync.Mutex
+	status   modInterfaces.ModuleStatus
+	cmd      *exec.Cmd
+	conn     *rpcConn
+	stdin    io.WriteCloser
+	exited   chan struct{} // 进程退出时关闭
+	stopping bool          // 主动停止中，进程退出不视为故障
+
+	subLock sync.Mutex
+	nextSub uint64
+	subs    map[uint64]*remoteSub
+}
+
+// 外部模块的一个事件订阅
+type remoteSub struct {
+	sub    modInterfaces.Subscription
+	active bool
+}
+
+// Factory 返回外部模块的工厂函数，path 为外部模块可执行文件路径
+func Factory(id, path string, args []string) func() modInterfaces.Module {
+	return func() modInterfaces.Module {
+		return &ExternalModule{
+			id:   id,
+			path: path,
+			args: args,
+			info: moduleInfo{ID: id, Name: id},
+			subs: make(map[uint64]*remoteSub),
+		}
+	}
+}
+
+func (m *ExternalModule) ID() string          { return m.id }
+func (m *ExternalModule) Name() string        { return m.moduleInfo().Name }
+func (m *ExternalModule) Description() string { return m.moduleInfo().Description }
+func (m *ExternalModule) Version() string     { return m.moduleInfo().Version }
+func (m *ExternalModule) Author() string      { return m.moduleInfo().Author }
+
+// 读取握手时获得的模块信息
+func (m *ExternalModule) moduleInfo() moduleInfo {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.info
+}
+
+// 外部模块在握手时声明的事件，供事件总线访问控制使用
+func (m *ExternalModule) PublishesEvents() []string  { return m.moduleInfo().Publishes }
+func (m *ExternalModule) SubscribesEvents() []string { return m.moduleInfo().Subscribes }
+
+// 外部模块进程崩溃后默认自动重启
+func (m *ExternalModule) DefaultRestartPolicy() string { return "on-failure" }
+
+// Init 只保存上下文，进程在首次启动时才拉起，未启用的外部模块不会产生进程
+func (m *ExternalModule) Init(ctx modInterfaces.Context) error {
+	m.ctx = ctx
+	return nil
+}
+
+// Prepare 拉起进程并完成握手（获取模块信息和事件声明、注入配置），配置校验失败等错误会使本次启动失败
+func (m *ExternalModule) Prepare() error {
+	_, err := m.ensureProcess()
+	return err
+}
+
+// Start 启动外部模块，进程未运行（首次启动或崩溃后重启）时先拉起进程
+func (m *ExternalModule) Start() error {
+	conn, err := m.ensureProcess()
+	if err != nil {
+		return err
+	}
+	if err := m.call(conn, "module.start", nil, nil); err != nil {
+		return err
+	}
+	m.lock.Lock()
+	m.status.Running = true
+	m.status.StartTime = time.Now()
+	m.status.LastError = nil
+	m.lock.Unlock()
+	return nil
+}
+
+// Stop 停止外部模块并结束进程，ctx 的剩余时间同时作为外部模块 Stop 的超时时间
+func (m *ExternalModule) Stop(ctx context.Context) error {
+	m.lock.Lock()
+	conn := m.conn
+	m.stopping = true
+	m.lock.Unlock()
+
+	var err error
+	if conn != nil {
+		var params stopParams
+		if deadline, ok := ctx.Deadline(); ok {
+			params.Timeout = time.Until(deadline).Milliseconds()
+		}
+		err = conn.Call(ctx, "module.stop", params, nil)
+	}
+	m.terminate()
+	m.cancelSubs()
+	m.lock.Lock()
+	m.status.Running = false
+	m.status.EndTime = time.Now()
+	m.lock.Unlock()
+	return err
+}
+
+// Status 返回外部模块的状态：进程运行中时通过 module.status 查询模块自身的运行状态和最近的错误，
+// 进程已退出或查询失败时返回主程序记录的状态
+func (m *ExternalModule) Status() modInterfaces.ModuleStatus {
+	m.lock.Lock()
+	status, conn := m.status, m.conn
+	m.lock.Unlock()
+	if conn == nil || !status.Running {
+		return status
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), statusTimeout)
+	defer cancel()
+	var result statusResult
+	if err := conn.Call(ctx, "module.status", nil, &result); err != nil {
+		return status
+	}
+	status.Running = result.Running
+	if result.LastError != "" {
+		status.LastError = errors.New(result.LastError)
+	}
+	return status
+}
+
+// Reload 向外部模块推送新配置，进程未运行时新配置在下次拉起时注入
+func (m *ExternalModule) Reload(ctx modInterfaces.Context) error {
+	m.ctx = ctx
+	m.lock.Lock()
+	conn := m.conn
+	m.lock.Unlock()
+	if conn == nil {
+		return nil
+	}
+	return m.call(conn, "module.reload", reloadParams{Config: jsonSafeConfig(ctx.Config)}, nil)
+}
+
+// 启动进程并完成握手（获取模块信息、初始化）
+func (m *ExternalModule) launch() error {
+	cmd := exec.Command(m.path, m.args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	// 使用 io.Pipe 而不是 StdoutPipe，Wait 会等待输出全部转发完毕，不会与读取协程竞争
+	stdoutR, stdoutW := io.Pipe()
+	stderrR, stderrW := io.Pipe()
+	cmd.Stdout = stdoutW
+	cmd.Stderr = stderrW
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start external module %s (%s): %w", m.id, m.path, err)
+	}
+
+	conn := newRPCConn(stdoutR, stdin, m.handle)
+	exited := make(chan struct{})
+	m.lock.Lock()
+	m.cmd = cmd
+	m.conn = conn
+	m.stdin = stdin
+	m.exited = exited
+	m.stopping = false
+	m.lock.Unlock()
+
+	// 子进程标准错误输出写入日志
+	go func() {
+		scanner := bufio.NewScanner(stderrR)
+		for scanner.Scan() {
+			m.ctx.Log("info", scanner.Text())
+		}
+	}()
+
+	// 等待进程退出，非主动停止时作为故障上报给监管器
+	go func() {
+		waitErr := cmd.Wait()
+		stdoutW.Close()
+		stderrW.Close()
+		close(exited)
+
+		if waitErr == nil {
+			waitErr = errors.New("exit status 0")
+		}
+		exitErr := fmt.Errorf("external module %s exited unexpectedly: %w", m.id, waitErr)
+		m.lock.Lock()
+		stopping := m.stopping
+		if m.cmd == cmd {
+			m.conn = nil
+			m.cmd = nil
+			if m.status.Running {
+				m.status.Running = false
+				m.status.EndTime = time.Now()
+				if !stopping {
+					m.status.LastError = exitErr
+				}
+			}
+		}
+		m.lock.Unlock()
+		if !stopping {
+			m.cancelSubs()
+			m.ctx.ReportError(exitErr)
+		}
+	}()
+
+	var info moduleInfo
+	if err := m.call(conn, "module.info", nil, &info); err != nil {
+		m.terminate()
+		return fmt.Errorf("external module %s handshake failed: %w", m.id, err)
+	}
+	if info.Name == "" {
+		info.Name = m.id
+	}
+	m.lock.Lock()
+	m.info = info
+	m.lock.Unlock()
+	if err := m.call(conn, "module.init", initParams{ID: m.id, Config: jsonSafeConfig(m.ctx.Config)}, nil); err != nil {
+		m.terminate()
+		return fmt.Errorf("external module %s init failed: %w", m.id, err)
+	}
+	return nil
+}
+
+// 确保进程在运行，返回可用的连接
+func (m *ExternalModule) ensureProcess() (*rpcConn, error) {
+	m.lock.Lock()
+	conn := m.conn
+	m.lock.Unlock()
+	if conn != nil {
+		return conn, nil
+	}
+	if err := m.launch(); err != nil {
+		return nil, err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.conn, nil
+}
+
+// 结束进程：关闭标准输入通知其退出，超时后强制结束
+func (m *ExternalModule) terminate() {
+	m.lock.Lock()
+	cmd, stdin, exited := m.cmd, m.stdin, m.exited
+	m.stopping = true
+	m.lock.Unlock()
+	if cmd == nil {
+		return
+	}
+	_ = stdin.Close()
+	select {
+	case <-exited:
+	case <-time.After(exitTimeout):
+		m.ctx.Log("warn", fmt.Sprintf("External module %s did not exit in %s, killing", m.id, exitTimeout))
+		_ = cmd.Process.Kill()
+		<-exited
+	}
+}
+
+// 带超时的调用
+func (m *ExternalModule) call(conn *rpcConn, method string, params, result interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	return conn.Call(ctx, method, params, result)
+}
+
+// 处理外部模块发来的请求和通知
+func (m *ExternalModule) handle(method string, raw json.RawMessage) (interface{}, error) {
+	switch method {
+	case "log":
+		var p logParams
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, err
+		}
+		m.ctx.Log(p.Level, p.Msg)
+		return nil, nil
+
+	case "module.failed":
+		var p failedParams
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, err
+		}
+		m.ctx.ReportError(errors.New(p.Error))
+		return nil, nil
+
+	case "bus.publish":
+		var p publishParams
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, err
+		}
+		data, err := modInterfaces.DecodeEventData(p.Event, p.Data)
+		if err != nil {
+			return nil, err
+		}
+		causedBus(m.ctx.Events, p.Cause).Publish(p.Event, data)
+		return nil, nil
+
+	case "bus.request":
+		var p requestParams
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, err
+		}
+		data, err := modInterfaces.DecodeEventData(p.Event, p.Data)
+		if err != nil {
+			return nil, err
+		}
+		ctx := context.Background()
+		if p.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(p.Timeout)*time.Millisecond)
+			defer cancel()
+		}
+		reply, err := causedBus(m.ctx.Events, p.Cause).Request(ctx, p.Event, data)
+		if errors.Is(err, modInterfaces.ErrNoHandler) {
+			return nil, &rpcError{Code: codeNoHandler, Message: err.Error()}
+		}
+		if err != nil {
+			return nil, err
+		}
+		replyData, err := json.Marshal(reply)
+		if err != nil {
+			return nil, err
+		}
+		return requestResult{Data: replyData}, nil
+
+	case "bus.subscribe":
+		var p subscribeParams
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, err
+		}
+		opts, err := subscribeOptions(p)
+		if err != nil {
+			return nil, err
+		}
+		return subscribeResult{Subscription: m.subscribe(p.Event, opts...)}, nil
+
+	case "bus.unsubscribe":
+		var p unsubscribeParams
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, err
+		}
+		m.unsubscribe(p.Subscription)
+		return nil, nil
+	}
+	return nil, methodNotFound(method)
+}
+
+// 外部模块带起因事件发布时，返回继承起因关联ID的总线视图
+func causedBus(bus modInterfaces.EventBus, cause *eventMeta) modInterfaces.EventBus {
+	if cause == nil {
+		return bus
+	}
+	return modInterfaces.Event{EventMeta: cause.meta()}.FollowUp(bus)
+}
+
+func toEventMeta(m modInterfaces.EventMeta) eventMeta {
+	return eventMeta{ID: m.ID, Source: m.Source, Time: m.Time, CorrelationID: m.CorrelationID, CausedBy: m.CausedBy}
+}
+
+func (m eventMeta) meta() modInterfaces.EventMeta {
+	return modInterfaces.EventMeta{ID: m.ID, Source: m.Source, Time: m.Time, CorrelationID: m.CorrelationID, CausedBy: m.CausedBy}
+}
+
+// 将 bus.subscribe 参数转换为订阅选项
+func subscribeOptions(p subscribeParams) ([]modInterfaces.SubscribeOption, error) {
+	switch p.Mode {
+	case "", "queued":
+	case "sync":
+		return []modInterfaces.SubscribeOption{modInterfaces.WithSync()}, nil
+	default:
+		return nil, fmt.Errorf("unknown delivery mode %q", p.Mode)
+	}
+	overflow := modInterfaces.DropOldest
+	switch p.Overflow {
+	case "", "drop-oldest":
+	case "drop-newest":
+		overflow = modInterfaces.DropNewest
+	case "block":
+		overflow = modInterfaces.Block
+	default:
+		return nil, fmt.Errorf("unknown overflow policy %q", p.Overflow)
+	}
+	return []modInterfaces.SubscribeOption{modInterfaces.WithQueue(p.QueueSize, overflow)}, nil
+}
+
+// 代外部模块订阅事件，收到事件后推送给外部模块
+func (m *ExternalModule) subscribe(event string, opts ...modInterfaces.SubscribeOption) uint64 {
+	m.subLock.Lock()
+	defer m.subLock.Unlock()
+	m.nextSub++
+	id := m.nextSub
+	rs := &remoteSub{active: true}
+	rs.sub = m.ctx.Events.Subscribe(event, func(evt modInterfaces.Event) {
+		m.subLock.Lock()
+		active := rs.active
+		m.subLock.Unlock()
+		if !active {
+			return
+		}
+		data, err := json.Marshal(evt.Data)
+		if err != nil {
+			m.ctx.Log("warn", fmt.Sprintf("Event %s cannot be forwarded to external module %s: %v", evt.Name, m.id, err))
+			return
+		}
+		m.lock.Lock()
+		conn := m.conn
+		m.lock.Unlock()
+		if conn == nil {
+			return
+		}
+		params := eventParams{Subscription: id, Name: evt.Name, Data: data, Meta: toEventMeta(evt.EventMeta)}
+		if !evt.IsRequest() {
+			_ = conn.Notify("bus.event", params)
+			return
+		}
+		// 请求：等待外部模块处理函数的回复并转交给请求方
+		params.Request = true
+		var result eventResult
+		if err := m.call(conn, "bus.event", params, &result); err != nil {
+			m.ctx.Log("warn", fmt.Sprintf("Request %s to external module %s failed: %v", evt.Name, m.id, err))
+			return
+		}
+		if !result.Responded {
+			return
+		}
+		replyData, err := modInterfaces.DecodeReplyData(evt.Name, result.Data)
+		if err != nil {
+			evt.Respond(nil, err)
+			return
+		}
+		var replyErr error
+		if result.Error != "" {
+			replyErr = errors.New(result.Error)
+		}
+		evt.Respond(replyData, replyErr)
+	}, opts...)
+	m.subs[id] = rs
+	return id
+}
+
+// 取消外部模块的订阅
+func (m *ExternalModule) unsubscribe(id uint64) {
+	m.subLock.Lock()
+	rs, ok := m.subs[id]
+	if ok {
+		rs.active = false
+		delete(m.subs, id)
+	}
+	m.subLock.Unlock()
+	if ok {
+		rs.sub.Cancel()
+	}
+}
+
+// 取消外部模块的全部订阅（进程退出时）
+func (m *ExternalModule) cancelSubs() {
+	m.subLock.Lock()
+	ids := make([]uint64, 0, len(m.subs))
+	for id := range m.subs {
+		ids = append(ids, id)
+	}
+	m.subLock.Unlock()
+	for _, id := range ids {
+		m.unsubscribe(id)
+	}
+}
@@ -0,0 +1,366 @@
+// 外部模块（独立进程）支持。
+//
+// 主程序以子进程方式启动外部模块，双方通过子进程的标准输入输出交换按行分隔的 JSON-RPC 2.0 消息，
+// 每行一条消息。子进程的标准错误输出会按行写入主程序日志。
+//
+// 主程序 -> 外部模块：
+//
+//	module.info    获取模块信息，返回 {id, name, description, version, author}
+//	module.init    初始化，参数 {id, config}
+//	module.start   启动
+//	module.stop    停止，参数 {timeout}（毫秒，0 表示使用默认超时）
+//	module.status  获取状态，返回 {running, lastError}
+//	module.reload  重新加载配置，参数 {config}
+//	bus.event      推送已订阅的事件，参数 {subscription, name, data, request, meta}，
+//	               meta 为 {id, source, time, correlationId, causedBy}。
+//	               普通事件为通知；request 为 true 时是请求，返回 {responded, data, error}
+//
+// 外部模块 -> 主程序：
+//
+//	bus.publish     发布事件，参数 {event, data, cause}，cause 为起因事件的 meta（可省略）
+//	bus.subscribe   订阅事件，参数 {event, mode, queueSize, overflow}，返回 {subscription}
+//	bus.unsubscribe 取消订阅，参数 {subscription}
+//	bus.request     发送请求并等待回复，参数 {event, data, timeout, cause}（timeout 为毫秒），返回 {data}
+//	log             （通知）写日志，参数 {level, msg}
+//	module.failed   （通知）上报异步故障，参数 {error}
+//
+// Go 编写的外部模块可直接用 Serve 包装 modInterfaces.Module，无需自己处理协议。
+package extmod
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// JSON-RPC 消息，请求、响应、通知共用
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *uint64         `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// JSON-RPC 错误
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// JSON-RPC 标准错误码
+const (
+	codeMethodNotFound = -32601
+	codeInternalError  = -32603
+	codeNoHandler      = -32001 // 请求没有处理函数，对应 modInterfaces.ErrNoHandler
+)
+
+// 连接已关闭
+var errConnClosed = errors.New("extmod: connection closed")
+
+// 处理对端发来的请求或通知，通知的返回值会被忽略
+type rpcHandler func(method string, params json.RawMessage) (interface{}, error)
+
+// 双向 JSON-RPC 连接，双方都可以发起请求
+type rpcConn struct {
+	w       io.Writer
+	wLock   sync.Mutex
+	handler rpcHandler
+
+	lock    sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan *message
+	closed  chan struct{}
+	err     error
+}
+
+func newRPCConn(r io.Reader, w io.Writer, handler rpcHandler) *rpcConn {
+	c := &rpcConn{
+		w:       w,
+		handler: handler,
+		pending: make(map[uint64]chan *message),
+		closed:  make(chan struct{}),
+	}
+	go c.readLoop(r)
+	return c
+}
+
+// Call 发起请求并等待响应，result 为 nil 时忽略返回值
+func (c *rpcConn) Call(ctx context.Context, method string, params, result interface{}) error {
+	c.lock.Lock()
+	if c.err != nil {
+		c.lock.Unlock()
+		return c.err
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan *message, 1)
+	c.pending[id] = ch
+	c.lock.Unlock()
+
+	defer func() {
+		c.lock.Lock()
+		delete(c.pending, id)
+		c.lock.Unlock()
+	}()
+
+	if err := c.send(&id, method, params); err != nil {
+		return err
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result != nil && len(resp.Result) > 0 {
+			return json.Unmarshal(resp.Result, result)
+		}
+		return nil
+	case <-c.closed:
+		return c.err
+	case <-ctx.Done():
+		return fmt.Errorf("extmod: %s: %w", method, ctx.Err())
+	}
+}
+
+// Notify 发送通知，不等待响应
+func (c *rpcConn) Notify(method string, params interface{}) error {
+	return c.send(nil, method, params)
+}
+
+// Done 连接关闭时关闭
+func (c *rpcConn) Done() <-chan struct{} {
+	return c.closed
+}
+
+// 发送请求或通知
+func (c *rpcConn) send(id *uint64, method string, params interface{}) error {
+	msg := message{JSONRPC: "2.0", ID: id, Method: method}
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("extmod: marshal %s params: %w", method, err)
+		}
+		msg.Params = raw
+	}
+	return c.write(&msg)
+}
+
+// 写入一行消息
+func (c *rpcConn) write(msg *message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	c.wLock.Lock()
+	defer c.wLock.Unlock()
+	_, err = c.w.Write(data)
+	return err
+}
+
+// 逐行读取消息并分发
+func (c *rpcConn) readLoop(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var msg message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			// 无法解析的行直接丢弃，避免子进程误写标准输出导致连接中断
+			continue
+		}
+		if msg.Method == "" {
+			// 响应
+			if msg.ID == nil {
+				continue
+			}
+			c.lock.Lock()
+			ch, ok := c.pending[*msg.ID]
+			c.lock.Unlock()
+			if ok {
+				ch <- &msg
+			}
+			continue
+		}
+		// 请求或通知，单独协程处理，避免处理函数内再发起请求时阻塞读取
+		go c.handle(msg)
+	}
+
+	err := scanner.Err()
+	if err == nil {
+		err = errConnClosed
+	}
+	c.lock.Lock()
+	c.err = err
+	c.lock.Unlock()
+	close(c.closed)
+}
+
+// 处理请求或通知，请求需要回复
+func (c *rpcConn) handle(msg message) {
+	result, err := c.handler(msg.Method, msg.Params)
+	if msg.ID == nil {
+		return
+	}
+	resp := message{JSONRPC: "2.0", ID: msg.ID}
+	if err != nil {
+		var rpcErr *rpcError
+		if errors.As(err, &rpcErr) {
+			resp.Error = rpcErr
+		} else {
+			resp.Error = &rpcError{Code: codeInternalError, Message: err.Error()}
+		}
+	} else {
+		raw, marshalErr := json.Marshal(result)
+		if marshalErr != nil {
+			resp.Error = &rpcError{Code: codeInternalError, Message: marshalErr.Error()}
+		} else {
+			resp.Result = raw
+		}
+	}
+	_ = c.write(&resp)
+}
+
+// 未知方法
+func methodNotFound(method string) error {
+	return &rpcError{Code: codeMethodNotFound, Message: "method not found: " + method}
+}
+
+// --- 协议参数 ---
+
+type moduleInfo struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Version     string   `json:"version"`
+	Author      string   `json:"author"`
+	Publishes   []string `json:"publishes"`  // 模块声明发布的事件，未声明时为 null
+	Subscribes  []string `json:"subscribes"` // 模块声明订阅的事件
+}
+
+type initParams struct {
+	ID     string                 `json:"id"`
+	Config map[string]interface{} `json:"config"`
+}
+
+type reloadParams struct {
+	Config map[string]interface{} `json:"config"`
+}
+
+type stopParams struct {
+	Timeout int64 `json:"timeout,omitempty"`
+}
+
+type statusResult struct {
+	Running   bool   `json:"running"`
+	LastError string `json:"lastError,omitempty"`
+}
+
+type eventParams struct {
+	Subscription uint64          `json:"subscription"`
+	Name         string          `json:"name"`
+	Data         json.RawMessage `json:"data,omitempty"`
+	Request      bool            `json:"request,omitempty"`
+	Meta         eventMeta       `json:"meta"`
+}
+
+// 事件元数据，对应 modInterfaces.EventMeta
+type eventMeta struct {
+	ID            uint64    `json:"id,omitempty"`
+	Source        string    `json:"source,omitempty"`
+	Time          time.Time `json:"time"`
+	CorrelationID uint64    `json:"correlationId,omitempty"`
+	CausedBy      uint64    `json:"causedBy,omitempty"`
+}
+
+type eventResult struct {
+	Responded bool            `json:"responded"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+type requestParams struct {
+	Event   string          `json:"event"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Timeout int64           `json:"timeout,omitempty"`
+	Cause   *eventMeta      `json:"cause,omitempty"` // 起因事件，请求继承其关联ID
+}
+
+type requestResult struct {
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+type publishParams struct {
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	Cause *eventMeta      `json:"cause,omitempty"` // 起因事件，发布的事件继承其关联ID
+}
+
+type subscribeParams struct {
+	Event     string `json:"event"`
+	Mode      string `json:"mode,omitempty"`      // 主进程一侧的投递方式：queued（默认）、sync
+	QueueSize int    `json:"queueSize,omitempty"` // 队列长度，0 表示默认
+	Overflow  string `json:"overflow,omitempty"`  // 队列满时的策略：drop-oldest（默认）、drop-newest、block
+}
+
+type subscribeResult struct {
+	Subscription uint64 `json:"subscription"`
+}
+
+type unsubscribeParams struct {
+	Subscription uint64 `json:"subscription"`
+}
+
+type logParams struct {
+	Level string `json:"level"`
+	Msg   string `json:"msg"`
+}
+
+type failedParams struct {
+	Error string `json:"error"`
+}
+
+// 将 yaml 解析出的 map[interface{}]interface{} 递归转换为可 JSON 序列化的结构
+func jsonSafe(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[fmt.Sprint(k)] = jsonSafe(item)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[k] = jsonSafe(item)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(val))
+		for i, item := range val {
+			list[i] = jsonSafe(item)
+		}
+		return list
+	}
+	return v
+}
+
+// 转换模块配置
+func jsonSafeConfig(cfg map[string]interface{}) map[string]interface{} {
+	return jsonSafe(cfg).(map[string]interface{})
+}
@@ -4,22 +4,16 @@ import (
 	"fmt"
 	"strings"
 	"xyrTools/xyrTools/core"
-	modInterfaces "xyrTools/xyrTools/modInterfaces"
-	memopt "xyrTools/xyrTools/modules/memoryOptimizer"
-	sysTray "xyrTools/xyrTools/modules/tray"
+	"xyrTools/xyrTools/extmod"
+	"xyrTools/xyrTools/modInterfaces"
+	"xyrTools/xyrTools/registry"
 )
 
 // InitSys 初始化系统，加载配置并注册配置文件中指定的模块。
 // 参数 coreEngine 是核心引擎实例，用于配置加载、日志记录和模块注册。
 // 参数 confPath 是配置文件的路径。
+// 模块工厂来自模块注册表，各模块在自己的 init() 中注册，新增模块无需修改此处。
 func InitSys(coreEngine *core.CoreEngine, confPath string) {
-	// 模块工厂映射，键为模块名称，值为创建模块实例的工厂函数。
-	// 当有新模块添加或现有模块移除时，需要修改此映射。
-	moduleFactoriesMap := map[string]func() modInterfaces.Module{
-		"memopt":  memopt.New,
-		"sysTray": sysTray.New,
-	}
-
 	// 若加载失败，记录致命错误日志并终止初始化流程。
 	if err := coreEngine.LoadConfig(confPath); err != nil {
 		coreEngine.Log("fatal", err.Error())
@@ -27,6 +21,10 @@ func InitSys(coreEngine *core.CoreEngine, confPath string) {
 		return
 	}
 
+	// 记录当前系统可用的模块
+	available := registry.Available()
+	coreEngine.Log("info", fmt.Sprintf("Available modules on %s: %s", registry.OS(), strings.Join(available, ", ")))
+
 	// 从核心引擎获取全局配置信息。
 	globalCfg := coreEngine.GetConfig()
 	// 尝试从配置中读取模块列表。
@@ -45,11 +43,15 @@ func InitSys(coreEngine *core.CoreEngine, confPath string) {
 	}
 	// 遍历模块列表，对每个模块进行处理。
 	for _, mod := range modulesSlice {
-		// 根据模块名从模块工厂映射中获取对应的工厂函数。
-		factory, ok := moduleFactoriesMap[mod]
+		// 根据模块名从模块注册表中获取对应的工厂函数。
+		factory, ok := registry.Lookup(mod)
 		if !ok {
-			// 若未找到对应的工厂函数，记录错误日志并跳过该模块。
-			coreEngine.Log("error", fmt.Sprintf("No module factory found for module %s", mod))
+			// 未内置的模块，若配置了 exec 则作为外部模块进程运行
+			factory, ok = externalFactory(mod, globalCfg[mod])
+		}
+		if !ok {
+			// 若未找到对应的工厂函数（模块不存在或不支持当前系统），记录错误日志并跳过该模块。
+			coreEngine.Log("error", fmt.Sprintf("Module %s is not available on %s", mod, registry.OS()))
 			continue
 		}
 		// 使用核心引擎注册模块，传入模块名和对应的工厂函数。
@@ -58,4 +60,39 @@ func InitSys(coreEngine *core.CoreEngine, confPath string) {
 			coreEngine.Log("error", fmt.Sprintf("Failed to register module %s: %v", mod, err))
 		}
 	}
+	// 全部注册后检查依赖，依赖缺失的模块被取消注册
+	if err := coreEngine.ResolveDependencies(); err != nil {
+		coreEngine.Log("error", fmt.Sprintf("Unresolved module dependencies: %v", err))
+	}
+}
+
+// 根据模块配置中的 exec、args 创建外部模块工厂，未配置 exec 时返回 false
+func externalFactory(id string, section interface{}) (func() modInterfaces.Module, bool) {
+	var cfg map[string]interface{}
+	switch v := section.(type) {
+	case map[string]interface{}:
+		cfg = v
+	case map[interface{}]interface{}:
+		// yaml.v2 将模块配置节解析为 map[interface{}]interface{}，与 Register 一样转换键
+		cfg = make(map[string]interface{}, len(v))
+		for key, value := range v {
+			cfg[fmt.Sprint(key)] = value
+		}
+	default:
+		return nil, false
+	}
+	path, _ := cfg["exec"].(string)
+	if path == "" {
+		return nil, false
+	}
+	var args []string
+	switch v := cfg["args"].(type) {
+	case string:
+		args = strings.Fields(v)
+	case []interface{}:
+		for _, arg := range v {
+			args = append(args, fmt.Sprint(arg))
+		}
+	}
+	return extmod.Factory(id, path, args), true
 }
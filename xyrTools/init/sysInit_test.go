@@ -0,0 +1,39 @@
+package initSys
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+// 模块配置节按配置文件的实际解析结果（yaml.v2）传入
+func TestExternalFactoryFromYAML(t *testing.T) {
+	data := []byte(`
+modules: myPlugin other
+myPlugin:
+  enabled: true
+  exec: plugins/myPlugin
+  args: [--verbose, 3]
+other:
+  enabled: true
+`)
+	cfg := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	factory, ok := externalFactory("myPlugin", cfg["myPlugin"])
+	if !ok {
+		t.Fatal("module with exec should be an external module")
+	}
+	if id := factory().ID(); id != "myPlugin" {
+		t.Fatalf("ID = %q", id)
+	}
+
+	if _, ok := externalFactory("other", cfg["other"]); ok {
+		t.Fatal("module without exec should not be an external module")
+	}
+	if _, ok := externalFactory("missing", cfg["missing"]); ok {
+		t.Fatal("missing section should not be an external module")
+	}
+}
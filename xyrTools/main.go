@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	//"net/http"
 	_ "net/http/pprof"
@@ -9,10 +10,21 @@ import (
 	"syscall"
 	"xyrTools/xyrTools/core"
 	"xyrTools/xyrTools/extendFunc"
+
+	"myMod/xlog"
 	initSys "xyrTools/xyrTools/init"
+	"xyrTools/xyrTools/modInterfaces"
+	_ "xyrTools/xyrTools/modules" // 导入所有模块，完成模块注册
 )
 
 func main() {
+	listEvents := flag.Bool("events", false, "列出事件目录（事件名、数据类型、说明）后退出")
+	flag.Parse()
+	if *listEvents {
+		modInterfaces.WriteEventCatalog(os.Stdout)
+		return
+	}
+
 	// 检查是否已存在锁文件
 	if extendFunc.CheckLockFile() {
 		extendFunc.MessageBox("提示", "程序已在运行！")
@@ -25,15 +37,19 @@ func main() {
 		fmt.Printf("创建锁文件失败: %v\n", err)
 		return
 	}
-	// 确保退出时删除锁文件
+	// 确保退出时删除锁文件，最先注册的 defer 最后执行，锁文件在模块停止、日志关闭之后才释放
 	defer extendFunc.RemoveLockFile()
-	// 日志函数
-	logFunc := func(level, msg string) {
-		fmt.Printf("[%s] %s\n", level, msg)
+	// 日志，加载配置前先输出到控制台，加载配置后按 log 节输出到文件
+	logger, err := xlog.New(xlog.Options{Level: xlog.LevelInfo, Console: true})
+	if err != nil {
+		fmt.Printf("创建日志失败: %v\n", err)
+		return
 	}
+	defer logger.Close()
+	logFunc := logger.Module("main").Func()
 
 	// 创建核心引擎
-	engine := core.NewCoreEngine(logFunc)
+	engine := core.NewCoreEngineWithLogger(logger)
 	//获取当前项目的路径
 	configPath, err := os.Getwd()
 	if err != nil {
@@ -50,12 +66,21 @@ func main() {
 	// 启动所有模块
 	engine.StartAll()
 
-	// 捕捉退出信号
+	// 监听配置文件变化，热加载模块配置
+	if err := engine.WatchConfig(); err != nil {
+		logFunc("error", fmt.Sprintf("配置文件监听失败: %v", err))
+	}
+
+	// 等待退出信号或模块的退出请求（如托盘菜单退出）
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
-	<-sig
+	select {
+	case s := <-sig:
+		logFunc("info", fmt.Sprintf("收到退出信号: %v", s))
+	case <-engine.ShutdownRequested():
+	}
 
-	// 停止所有模块
+	// 按依赖逆序停止所有模块，每个模块最多等待 stopTimeout
 	engine.StopAll()
 
 	logFunc("info", "程序已退出")
@@ -0,0 +1,426 @@
+package modInterfaces
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 引擎保留的配置项，由核心引擎统一处理，模块解码配置时忽略这些键
+var EngineConfigKeys = map[string]bool{
+	"enabled":            true,
+	"restart":            true, // 重启策略：never、on-failure、always
+	"maxRestarts":        true, // 最大连续重启次数，0 表示不限制
+	"maxHandlerFailures": true, // 事件处理函数失败达到该次数时按模块故障处理，0 表示不限制
+	"publishes":          true, // 模块允许发布的事件，覆盖模块自身的声明
+	"subscribes":         true, // 模块允许订阅的事件，覆盖模块自身的声明
+	"exec":               true, // 外部模块可执行文件路径
+	"args":               true, // 外部模块启动参数
+	"logLevel":           true, // 模块最低日志级别：debug、info、warn、error
+	"stopTimeout":        true, // 模块停止超时时间，不配置时使用 shutdown 节的 stopTimeout
+}
+
+// 配置项说明，用于文档和工具展示模块配置结构
+type ConfigField struct {
+	Name        string // 配置键名
+	Type        string // 类型，如 int、string、duration
+	Default     string // 默认值
+	Required    bool   // 是否必填
+	Min         string // 最小值（数值或时长）
+	Max         string // 最大值（数值或时长）
+	Description string // 说明
+}
+
+// 可选接口：模块对外暴露自身的配置结构
+type ConfigSchemaProvider interface {
+	ConfigSchema() []ConfigField
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// DecodeConfig 将模块配置解码到结构体指针 out 中，并按结构体标签校验。
+//
+// 支持的标签：
+//
+//	yaml:"interval"   配置键名，缺省为字段名首字母小写
+//	default:"30s"     缺省值
+//	required:"true"   必填
+//	min:"1s" max:"1h" 数值或时长的取值范围
+//	desc:"..."        配置说明
+//
+// time.Duration 字段支持 "30s"、"5m" 等写法，纯数字按秒计算。
+// map[string]T 字段对应配置中的嵌套映射（yaml.v2 解析为 map[interface{}]interface{}），键转换为字符串。
+// 配置中出现结构体未声明的键（引擎保留键除外）时返回错误。
+func (c Context) DecodeConfig(out interface{}) error {
+	return DecodeConfig(c.Config, out)
+}
+
+// DecodeConfig 将配置 map 解码到结构体指针 out 中，规则同 Context.DecodeConfig
+func DecodeConfig(cfg map[string]interface{}, out interface{}) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("DecodeConfig: out must be a non-nil pointer to struct, got %T", out)
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	var errs []error
+	known := make(map[string]bool)
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := configKey(field)
+		known[name] = true
+		fv := rv.Field(i)
+
+		raw, present := cfg[name]
+		if !present || raw == nil {
+			if field.Tag.Get("required") == "true" {
+				errs = append(errs, fmt.Errorf("config %q is required", name))
+				continue
+			}
+			def, ok := field.Tag.Lookup("default")
+			if !ok {
+				continue
+			}
+			raw = def
+		}
+
+		if err := assignValue(fv, raw); err != nil {
+			errs = append(errs, fmt.Errorf("config %q: %w", name, err))
+			continue
+		}
+		if err := checkRange(fv, field.Tag.Get("min"), field.Tag.Get("max")); err != nil {
+			errs = append(errs, fmt.Errorf("config %q: %w", name, err))
+		}
+	}
+
+	var unknown []string
+	for key := range cfg {
+		if !known[key] && !EngineConfigKeys[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		errs = append(errs, fmt.Errorf("unknown config keys: %s", strings.Join(unknown, ", ")))
+	}
+	return errors.Join(errs...)
+}
+
+// ConfigSchema 根据配置结构体（或其指针）的字段和标签生成配置说明
+func ConfigSchema(v interface{}) []ConfigField {
+	rt := reflect.TypeOf(v)
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt.Kind() != reflect.Struct {
+		return nil
+	}
+	var fields []ConfigField
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fields = append(fields, ConfigField{
+			Name:        configKey(field),
+			Type:        typeName(field.Type),
+			Default:     field.Tag.Get("default"),
+			Required:    field.Tag.Get("required") == "true",
+			Min:         field.Tag.Get("min"),
+			Max:         field.Tag.Get("max"),
+			Description: field.Tag.Get("desc"),
+		})
+	}
+	return fields
+}
+
+// ConfigDuration 读取配置中的时长，未配置时返回 def，写法同 DecodeConfig 的 time.Duration 字段
+func ConfigDuration(cfg map[string]interface{}, key string, def time.Duration) (time.Duration, error) {
+	raw, ok := cfg[key]
+	if !ok || raw == nil {
+		return def, nil
+	}
+	d, err := toDuration(raw)
+	if err != nil {
+		return def, fmt.Errorf("config %q: %w", key, err)
+	}
+	return d, nil
+}
+
+// ConfigStrings 读取配置中的字符串列表，未配置时返回 nil，写法同 DecodeConfig 的 []string 字段
+func ConfigStrings(cfg map[string]interface{}, key string) ([]string, error) {
+	raw, ok := cfg[key]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	var out []string
+	if err := assignValue(reflect.ValueOf(&out).Elem(), raw); err != nil {
+		return nil, fmt.Errorf("config %q: %w", key, err)
+	}
+	return out, nil
+}
+
+// 获取字段对应的配置键名
+func configKey(field reflect.StructField) string {
+	if tag := field.Tag.Get("yaml"); tag != "" {
+		if name := strings.Split(tag, ",")[0]; name != "" {
+			return name
+		}
+	}
+	return strings.ToLower(field.Name[:1]) + field.Name[1:]
+}
+
+// 获取字段类型的展示名称
+func typeName(t reflect.Type) string {
+	if t == durationType {
+		return "duration"
+	}
+	if t.Kind() == reflect.Slice {
+		return "[]" + typeName(t.Elem())
+	}
+	if t.Kind() == reflect.Map {
+		return "map[" + typeName(t.Key()) + "]" + typeName(t.Elem())
+	}
+	return t.Kind().String()
+}
+
+// 将 yaml 解析出的原始值（或默认值字符串）赋给字段
+func assignValue(fv reflect.Value, raw interface{}) error {
+	if fv.Type() == durationType {
+		d, err := toDuration(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		switch v := raw.(type) {
+		case string:
+			fv.SetString(v)
+		case int, int64, uint64, float64, bool:
+			fv.SetString(fmt.Sprint(v))
+		default:
+			return fmt.Errorf("expected string, got %T", raw)
+		}
+	case reflect.Bool:
+		switch v := raw.(type) {
+		case bool:
+			fv.SetBool(v)
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("expected bool, got %q", v)
+			}
+			fv.SetBool(b)
+		default:
+			return fmt.Errorf("expected bool, got %T", raw)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := toInt(raw)
+		if err != nil {
+			return err
+		}
+		if fv.OverflowInt(n) {
+			return fmt.Errorf("value %d overflows %s", n, fv.Kind())
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := toInt(raw)
+		if err != nil {
+			return err
+		}
+		if n < 0 || fv.OverflowUint(uint64(n)) {
+			return fmt.Errorf("value %d out of range for %s", n, fv.Kind())
+		}
+		fv.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		f, err := toFloat(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		var items []interface{}
+		switch v := raw.(type) {
+		case []interface{}:
+			items = v
+		case string:
+			// 默认值或单个值写成逗号分隔的字符串
+			for _, part := range strings.Split(v, ",") {
+				if part = strings.TrimSpace(part); part != "" {
+					items = append(items, part)
+				}
+			}
+		default:
+			return fmt.Errorf("expected list, got %T", raw)
+		}
+		slice := reflect.MakeSlice(fv.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := assignValue(slice.Index(i), item); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+		fv.Set(slice)
+	case reflect.Map:
+		if fv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("unsupported field type %s", fv.Type())
+		}
+		var items map[string]interface{}
+		switch v := raw.(type) {
+		case map[string]interface{}:
+			items = v
+		case map[interface{}]interface{}:
+			// yaml.v2 将嵌套映射解析为 map[interface{}]interface{}，与引擎转换模块配置节一样转换键
+			items = make(map[string]interface{}, len(v))
+			for key, value := range v {
+				items[fmt.Sprint(key)] = value
+			}
+		default:
+			return fmt.Errorf("expected map, got %T", raw)
+		}
+		m := reflect.MakeMapWithSize(fv.Type(), len(items))
+		for key, item := range items {
+			value := reflect.New(fv.Type().Elem()).Elem()
+			if err := assignValue(value, item); err != nil {
+				return fmt.Errorf("key %q: %w", key, err)
+			}
+			m.SetMapIndex(reflect.ValueOf(key).Convert(fv.Type().Key()), value)
+		}
+		fv.Set(m)
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
+
+// 转换为整数，yaml 中的数字可能被解析为 int、int64、uint64 或 float64
+func toInt(raw interface{}) (int64, error) {
+	switch v := raw.(type) {
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, fmt.Errorf("value %d too large", v)
+		}
+		return int64(v), nil
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, fmt.Errorf("expected integer, got %v", v)
+		}
+		return int64(v), nil
+	case string:
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("expected integer, got %q", v)
+		}
+		return n, nil
+	}
+	return 0, fmt.Errorf("expected integer, got %T", raw)
+}
+
+// 转换为浮点数
+func toFloat(raw interface{}) (float64, error) {
+	switch v := raw.(type) {
+	case int, int64, uint64:
+		n, err := toInt(v)
+		return float64(n), err
+	case float64:
+		return v, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("expected number, got %q", v)
+		}
+		return f, nil
+	}
+	return 0, fmt.Errorf("expected number, got %T", raw)
+}
+
+// 转换为时长，字符串按 time.ParseDuration 解析，纯数字按秒计算
+func toDuration(raw interface{}) (time.Duration, error) {
+	if s, ok := raw.(string); ok {
+		s = strings.TrimSpace(s)
+		if d, err := time.ParseDuration(s); err == nil {
+			return d, nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return time.Duration(f * float64(time.Second)), nil
+		}
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	f, err := toFloat(raw)
+	if err != nil {
+		return 0, fmt.Errorf("expected duration, got %T", raw)
+	}
+	return time.Duration(f * float64(time.Second)), nil
+}
+
+// 校验数值或时长是否在 min、max 范围内，未设置的边界不校验
+func checkRange(fv reflect.Value, min, max string) error {
+	if min == "" && max == "" {
+		return nil
+	}
+	var value float64
+	parse := toFloat
+	switch {
+	case fv.Type() == durationType:
+		value = float64(fv.Int())
+		parse = func(raw interface{}) (float64, error) {
+			d, err := toDuration(raw)
+			return float64(d), err
+		}
+	case fv.CanInt():
+		value = float64(fv.Int())
+	case fv.CanUint():
+		value = float64(fv.Uint())
+	case fv.CanFloat():
+		value = fv.Float()
+	case fv.Kind() == reflect.String || fv.Kind() == reflect.Slice || fv.Kind() == reflect.Map:
+		// 字符串、列表和映射按长度校验
+		value = float64(fv.Len())
+	default:
+		return nil
+	}
+
+	if min != "" {
+		bound, err := parse(min)
+		if err != nil {
+			return fmt.Errorf("invalid min tag: %w", err)
+		}
+		if value < bound {
+			return fmt.Errorf("value %s is less than minimum %s", formatValue(fv), min)
+		}
+	}
+	if max != "" {
+		bound, err := parse(max)
+		if err != nil {
+			return fmt.Errorf("invalid max tag: %w", err)
+		}
+		if value > bound {
+			return fmt.Errorf("value %s is greater than maximum %s", formatValue(fv), max)
+		}
+	}
+	return nil
+}
+
+// 格式化字段值用于错误信息
+func formatValue(fv reflect.Value) string {
+	if fv.Type() == durationType {
+		return time.Duration(fv.Int()).String()
+	}
+	return fmt.Sprint(fv.Interface())
+}
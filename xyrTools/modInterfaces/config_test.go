@@ -0,0 +1,183 @@
+package modInterfaces
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Interval time.Duration     `default:"30s" min:"1s" max:"1h"`
+	Path     string            `required:"true"`
+	Workers  int               `yaml:"workers" default:"2" min:"1" max:"8"`
+	Ratio    float64           `default:"0.5"`
+	Verbose  bool              `default:"false"`
+	Tags     []string          `default:"a, b"`
+	Limits   map[string]int    `yaml:"limits"`
+	Labels   map[string]string `yaml:"labels" max:"2"`
+}
+
+func TestDecodeConfig(t *testing.T) {
+	defaults := testConfig{Interval: 30 * time.Second, Path: "/tmp", Workers: 2, Ratio: 0.5, Tags: []string{"a", "b"}}
+	tests := []struct {
+		name    string
+		cfg     map[string]interface{}
+		want    testConfig
+		wantErr string // 错误信息应包含的内容，为空表示不应出错
+	}{
+		{
+			name: "defaults",
+			cfg:  map[string]interface{}{"path": "/tmp"},
+			want: defaults,
+		},
+		{
+			name: "engine keys ignored",
+			cfg:  map[string]interface{}{"path": "/tmp", "enabled": true, "restart": "always", "logLevel": "debug"},
+			want: defaults,
+		},
+		{
+			name: "nil uses default",
+			cfg:  map[string]interface{}{"path": "/tmp", "workers": nil},
+			want: defaults,
+		},
+		{
+			name:    "required missing",
+			cfg:     map[string]interface{}{},
+			wantErr: `config "path" is required`,
+		},
+		{
+			name: "values",
+			cfg: map[string]interface{}{
+				"path": 42, "workers": "4", "ratio": 2, "verbose": "true", "tags": []interface{}{"x", 1},
+			},
+			want: testConfig{Interval: 30 * time.Second, Path: "42", Workers: 4, Ratio: 2, Verbose: true, Tags: []string{"x", "1"}},
+		},
+		{
+			name: "duration string",
+			cfg:  map[string]interface{}{"path": "/tmp", "interval": "5m"},
+			want: func() testConfig { c := defaults; c.Interval = 5 * time.Minute; return c }(),
+		},
+		{
+			name: "duration seconds",
+			cfg:  map[string]interface{}{"path": "/tmp", "interval": 90},
+			want: func() testConfig { c := defaults; c.Interval = 90 * time.Second; return c }(),
+		},
+		{
+			name: "duration number string",
+			cfg:  map[string]interface{}{"path": "/tmp", "interval": "1.5"},
+			want: func() testConfig { c := defaults; c.Interval = 1500 * time.Millisecond; return c }(),
+		},
+		{
+			name:    "invalid duration",
+			cfg:     map[string]interface{}{"path": "/tmp", "interval": "soon"},
+			wantErr: `config "interval": invalid duration "soon"`,
+		},
+		{
+			name:    "duration below min",
+			cfg:     map[string]interface{}{"path": "/tmp", "interval": "500ms"},
+			wantErr: "value 500ms is less than minimum 1s",
+		},
+		{
+			name:    "duration above max",
+			cfg:     map[string]interface{}{"path": "/tmp", "interval": "2h"},
+			wantErr: "value 2h0m0s is greater than maximum 1h",
+		},
+		{
+			name:    "int below min",
+			cfg:     map[string]interface{}{"path": "/tmp", "workers": 0},
+			wantErr: "value 0 is less than minimum 1",
+		},
+		{
+			name:    "int above max",
+			cfg:     map[string]interface{}{"path": "/tmp", "workers": 9},
+			wantErr: "value 9 is greater than maximum 8",
+		},
+		{
+			name:    "not an integer",
+			cfg:     map[string]interface{}{"path": "/tmp", "workers": 1.5},
+			wantErr: "expected integer, got 1.5",
+		},
+		{
+			// yaml.v2 把嵌套映射解析为 map[interface{}]interface{}
+			name: "yaml map",
+			cfg: map[string]interface{}{
+				"path":   "/tmp",
+				"limits": map[interface{}]interface{}{"cpu": 2, 10: "3"},
+				"labels": map[string]interface{}{"env": "prod"},
+			},
+			want: func() testConfig {
+				c := defaults
+				c.Limits = map[string]int{"cpu": 2, "10": 3}
+				c.Labels = map[string]string{"env": "prod"}
+				return c
+			}(),
+		},
+		{
+			name:    "yaml map bad value",
+			cfg:     map[string]interface{}{"path": "/tmp", "limits": map[interface{}]interface{}{"cpu": "many"}},
+			wantErr: `config "limits": key "cpu": expected integer, got "many"`,
+		},
+		{
+			name:    "map too long",
+			cfg:     map[string]interface{}{"path": "/tmp", "labels": map[interface{}]interface{}{"a": 1, "b": 2, "c": 3}},
+			wantErr: `config "labels": value`,
+		},
+		{
+			name:    "not a map",
+			cfg:     map[string]interface{}{"path": "/tmp", "limits": "cpu=2"},
+			wantErr: "expected map, got string",
+		},
+		{
+			name:    "unknown keys",
+			cfg:     map[string]interface{}{"path": "/tmp", "workerz": 3, "colour": "red"},
+			wantErr: "unknown config keys: colour, workerz",
+		},
+		{
+			name:    "errors joined",
+			cfg:     map[string]interface{}{"workers": 100, "extra": 1},
+			wantErr: `config "path" is required` + "\n" + `config "workers": value 100 is greater than maximum 8` + "\nunknown config keys: extra",
+		},
+	}
+	for _, tt := range tests {
+		var got testConfig
+		err := DecodeConfig(tt.cfg, &got)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v\nwant %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeConfigInvalidTarget(t *testing.T) {
+	var cfg testConfig
+	for _, out := range []interface{}{nil, cfg, (*testConfig)(nil), new(int)} {
+		if err := DecodeConfig(map[string]interface{}{}, out); err == nil {
+			t.Errorf("DecodeConfig(%T) succeeded, want error", out)
+		}
+	}
+}
+
+func TestConfigSchema(t *testing.T) {
+	fields := ConfigSchema(testConfig{})
+	var types []string
+	for _, f := range fields {
+		types = append(types, f.Name+":"+f.Type)
+	}
+	want := "interval:duration path:string workers:int ratio:float64 verbose:bool tags:[]string limits:map[string]int labels:map[string]string"
+	if got := strings.Join(types, " "); got != want {
+		t.Fatalf("schema = %q\nwant     %q", got, want)
+	}
+	if !fields[1].Required || fields[0].Default != "30s" || fields[2].Max != "8" {
+		t.Fatalf("schema tags not reported: %+v", fields[:3])
+	}
+}
@@ -0,0 +1,161 @@
+package modInterfaces
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 处理函数失败：处理函数 panic，或调用 Event.Fail 上报错误（类型化的 Subscribe、Handle 在数据类型不符、
+// 处理普通事件返回错误时自动上报）。失败会记录日志、计入订阅所属模块的失败次数、
+// 存入死信列表，并发布 bus:handlerFailed 事件。
+
+// 处理函数失败的信息，也是 bus:handlerFailed 事件的数据
+type HandlerFailure struct {
+	Event          Event     // 处理失败的原事件，请求的回复通道已去掉
+	SubscriptionID uint64    // 处理失败的订阅
+	Pattern        string    // 订阅的事件名或通配符
+	Module         string    // 订阅所属模块，未以模块名义订阅时为空
+	Error          string    // 错误信息，panic 时为 "panic: ..."
+	Stack          string    // panic 时的调用栈，上报错误时为空
+	Time           time.Time // 失败时间
+}
+
+// 死信：处理失败的事件，可查看后重新投递
+type DeadLetter struct {
+	ID uint64 // 死信编号，用于重新投递
+	HandlerFailure
+}
+
+// 最多保留的死信数，超出时丢弃最早的
+const DefaultDeadLetters = 1000
+
+var TopicHandlerFailed = DeclareEvent[HandlerFailure]("bus:handlerFailed", "事件处理函数 panic 或上报错误")
+
+// 重新投递的死信不存在（已重新投递、已清除或超出保留数量）
+var ErrNoDeadLetter = errors.New("eventbus: dead letter not found")
+
+// Fail 上报处理函数未能处理该事件，只能在处理函数内对传入的事件调用。
+// 不在处理函数内（如外部模块中的事件）时返回 false
+func (e Event) Fail(err error) bool {
+	if e.sub == nil || err == nil {
+		return false
+	}
+	e.sub.bus.handlerFailed(e.sub, e, err.Error(), "")
+	return true
+}
+
+// 去掉回复通道和所属订阅，用于保存和重新投递
+func (e Event) detach() Event {
+	return Event{Name: e.Name, Data: e.Data, EventMeta: e.EventMeta}
+}
+
+// 记录处理函数失败，发布 bus:handlerFailed 事件。
+// bus:handlerFailed 自身处理失败时只记录日志和次数，避免循环
+func (bus *defaultEventBus) handlerFailed(s *subscription, evt Event, msg, stack string) {
+	if stack != "" {
+		bus.Logf("error", "Event handler panic on %s: subscription %d (%s, owner %q): %s\n%s", evt, s.id, s.event, s.owner, msg, stack)
+	} else {
+		bus.Logf("error", "Event handler failed on %s: subscription %d (%s, owner %q): %s", evt, s.id, s.event, s.owner, msg)
+	}
+	failure := HandlerFailure{
+		Event:          evt.detach(),
+		SubscriptionID: s.id,
+		Pattern:        s.event,
+		Module:         s.owner,
+		Error:          msg,
+		Stack:          stack,
+		Time:           time.Now(),
+	}
+	bus.lock.Lock()
+	bus.failures[s.owner]++
+	if evt.Name == TopicHandlerFailed.Name() {
+		bus.lock.Unlock()
+		return
+	}
+	bus.nextDeadID++
+	bus.deadLetters = append(bus.deadLetters, DeadLetter{ID: bus.nextDeadID, HandlerFailure: failure})
+	if n := len(bus.deadLetters) - DefaultDeadLetters; n > 0 {
+		bus.deadLetters = append([]DeadLetter(nil), bus.deadLetters[n:]...)
+	}
+	bus.lock.Unlock()
+	bus.publish("bus", &evt.EventMeta, TopicHandlerFailed.Name(), failure)
+}
+
+// HandlerFailures 返回模块的处理函数失败次数，module 为空时返回未以模块名义订阅的失败次数
+func (bus *defaultEventBus) HandlerFailures(module string) uint64 {
+	bus.lock.RLock()
+	defer bus.lock.RUnlock()
+	return bus.failures[module]
+}
+
+// ResetHandlerFailures 将模块的处理函数失败次数清零
+func (bus *defaultEventBus) ResetHandlerFailures(module string) {
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+	delete(bus.failures, module)
+}
+
+// DeadLetters 返回当前的死信，按失败时间排列
+func (bus *defaultEventBus) DeadLetters() []DeadLetter {
+	bus.lock.RLock()
+	defer bus.lock.RUnlock()
+	return append([]DeadLetter(nil), bus.deadLetters...)
+}
+
+// Redeliver 将死信中的事件重新投递给原订阅所属模块当前匹配该事件的订阅（模块重启后订阅会重建），
+// 未以模块名义订阅时投递给原订阅。投递后从死信中移除，再次失败时产生新的死信。
+// 重新投递不经过发布拦截器，请求作为普通事件投递
+func (bus *defaultEventBus) Redeliver(id uint64) error {
+	bus.lock.Lock()
+	idx := -1
+	for i, dl := range bus.deadLetters {
+		if dl.ID == id {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		bus.lock.Unlock()
+		return fmt.Errorf("%w: %d", ErrNoDeadLetter, id)
+	}
+	dl := bus.deadLetters[idx]
+	bus.lock.Unlock()
+
+	var targets []*subscription
+	for _, sub := range bus.match(dl.Event.Name) {
+		if sub.owner == dl.Module && (dl.Module != "" || sub.id == dl.SubscriptionID) {
+			targets = append(targets, sub)
+		}
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("eventbus: redeliver %d: no subscription of module %q for %s", id, dl.Module, dl.Event.Name)
+	}
+
+	bus.lock.Lock()
+	removed := false
+	for i, d := range bus.deadLetters {
+		if d.ID == id {
+			bus.deadLetters = append(bus.deadLetters[:i:i], bus.deadLetters[i+1:]...)
+			removed = true
+			break
+		}
+	}
+	bus.lock.Unlock()
+	// 并发重新投递同一死信时只投递一次
+	if !removed {
+		return fmt.Errorf("%w: %d", ErrNoDeadLetter, id)
+	}
+	bus.Logf("info", "Redelivering %s to module %q (dead letter %d)", dl.Event, dl.Module, id)
+	bus.deliver(targets, dl.Event)
+	return nil
+}
+
+// ClearDeadLetters 清除全部死信，返回清除的数量
+func (bus *defaultEventBus) ClearDeadLetters() int {
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+	n := len(bus.deadLetters)
+	bus.deadLetters = nil
+	return n
+}
@@ -0,0 +1,124 @@
+package modInterfaces
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type testFailData struct {
+	N int
+}
+
+var testFailTopic = DeclareRequest[testFailData, int]("deadLetterTest:double", "dead letter test request")
+
+func TestHandlerFailedEvent(t *testing.T) {
+	bus := NewEventBus()
+	bus.SetLogger(func(string, string) {})
+	failures := make(chan HandlerFailure, 4)
+	Subscribe(bus, TopicHandlerFailed, func(f HandlerFailure, evt Event) {
+		if evt.Source != "bus" || evt.CausedBy == 0 {
+			t.Errorf("failure event meta %s", evt.EventMeta)
+		}
+		failures <- f
+	}, WithSync())
+
+	bus.Owner("worker").Subscribe("job:run", func(Event) { panic("boom") }, WithSync())
+	bus.Owner("worker").Publish("job:run", 7)
+	f := <-failures
+	if f.Module != "worker" || f.Event.Name != "job:run" || f.Event.Data != 7 || f.Error != "panic: boom" || !strings.Contains(f.Stack, "panic") {
+		t.Fatalf("unexpected failure %+v", f)
+	}
+
+	// 处理函数上报错误
+	bus.Owner("worker").Subscribe("job:check", func(evt Event) {
+		if !evt.Fail(errors.New("bad input")) {
+			t.Error("Fail returned false inside handler")
+		}
+	}, WithSync())
+	bus.Publish("job:check", nil)
+	if f := <-failures; f.Error != "bad input" || f.Stack != "" {
+		t.Fatalf("unexpected failure %+v", f)
+	}
+
+	// 类型化处理函数处理普通事件返回错误
+	Handle(bus.Owner("math"), testFailTopic, func(d testFailData, evt Event) (int, error) {
+		return 0, errors.New("odd")
+	}, WithSync())
+	bus.Publish(testFailTopic.Name(), testFailData{N: 1})
+	if f := <-failures; f.Module != "math" || f.Error != "odd" {
+		t.Fatalf("unexpected failure %+v", f)
+	}
+	// 请求的错误回复给请求方，不算处理函数失败
+	if _, err := Request(context.Background(), bus, testFailTopic, testFailData{N: 1}); err == nil || err.Error() != "odd" {
+		t.Fatalf("Request = %v, want odd", err)
+	}
+
+	if n := bus.HandlerFailures("worker"); n != 2 {
+		t.Fatalf("worker failures = %d, want 2", n)
+	}
+	if n := bus.HandlerFailures("math"); n != 1 {
+		t.Fatalf("math failures = %d, want 1", n)
+	}
+	bus.ResetHandlerFailures("worker")
+	if n := bus.HandlerFailures("worker"); n != 0 {
+		t.Fatalf("worker failures after reset = %d", n)
+	}
+	if len(bus.DeadLetters()) != 3 {
+		t.Fatalf("dead letters = %d, want 3", len(bus.DeadLetters()))
+	}
+	if (Event{}).Fail(errors.New("x")) {
+		t.Fatal("Fail outside handler returned true")
+	}
+}
+
+func TestRedeliver(t *testing.T) {
+	bus := NewEventBus()
+	bus.SetLogger(func(string, string) {})
+	var fail atomic.Bool
+	fail.Store(true)
+	var handled atomic.Int64
+	handler := func(evt Event) {
+		if fail.Load() {
+			panic("not ready")
+		}
+		handled.Add(1)
+	}
+	sub := bus.Owner("worker").Subscribe("job:*", handler, WithSync())
+	bus.Subscribe("job:*", func(Event) {}, WithSync())
+	bus.Publish("job:a", 1)
+
+	letters := bus.DeadLetters()
+	if len(letters) != 1 || letters[0].Event.Name != "job:a" {
+		t.Fatalf("dead letters %+v", letters)
+	}
+	id := letters[0].ID
+
+	// 模块重启后订阅重建，重新投递给新的订阅，其他订阅者不再收到
+	sub.Cancel()
+	bus.Owner("worker").Subscribe("job:*", handler, WithSync())
+	fail.Store(false)
+	if err := bus.Redeliver(id); err != nil {
+		t.Fatal(err)
+	}
+	if handled.Load() != 1 || len(bus.DeadLetters()) != 0 {
+		t.Fatalf("handled %d, %d dead letters left", handled.Load(), len(bus.DeadLetters()))
+	}
+	if err := bus.Redeliver(id); !errors.Is(err, ErrNoDeadLetter) {
+		t.Fatalf("second Redeliver = %v, want ErrNoDeadLetter", err)
+	}
+
+	// 模块没有匹配的订阅时保留死信
+	fail.Store(true)
+	bus.Publish("job:b", 2)
+	bus.CancelOwner("worker")
+	id = bus.DeadLetters()[0].ID
+	if err := bus.Redeliver(id); err == nil {
+		t.Fatal("Redeliver without subscription succeeded")
+	}
+	if n := bus.ClearDeadLetters(); n != 1 {
+		t.Fatalf("ClearDeadLetters = %d, want 1", n)
+	}
+}
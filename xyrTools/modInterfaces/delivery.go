@@ -0,0 +1,248 @@
+package modInterfaces
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// 投递方式
+type DeliveryMode int
+
+const (
+	DeliverQueued DeliveryMode = iota // 有序异步（默认）：每个订阅一个有界队列和一个处理协程，按发布顺序处理
+	DeliverSync                       // 同步：在 Publish 调用方的协程中依次执行，处理函数应尽快返回
+)
+
+// 队列满时的处理策略
+type OverflowPolicy int
+
+const (
+	DropOldest OverflowPolicy = iota // 丢弃队列中最旧的事件（默认），跳过请求
+	DropNewest                       // 丢弃新发布的事件，新的请求等待队列有空位
+	Block                            // 阻塞发布方直到队列有空位。处理函数内向自己订阅的事件发布时可能死锁
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case DropOldest:
+		return "drop-oldest"
+	case DropNewest:
+		return "drop-newest"
+	case Block:
+		return "block"
+	}
+	return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+}
+
+// 默认队列长度
+const DefaultQueueSize = 256
+
+// 订阅选项
+type SubscribeOption func(*subscribeOptions)
+
+type subscribeOptions struct {
+	mode     DeliveryMode
+	size     int
+	overflow OverflowPolicy
+	observer bool
+}
+
+func defaultSubscribeOptions() subscribeOptions {
+	return subscribeOptions{mode: DeliverQueued, size: DefaultQueueSize, overflow: DropOldest}
+}
+
+// WithQueue 使用有序异步投递，size 为队列长度（<= 0 时使用默认值），overflow 为队列满时的策略
+func WithQueue(size int, overflow OverflowPolicy) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.mode = DeliverQueued
+		if size > 0 {
+			o.size = size
+		}
+		o.overflow = overflow
+	}
+}
+
+// AsObserver 以旁观者身份订阅：接收匹配的事件和请求，但不回复请求，
+// 也不算作请求的处理函数（只有旁观者时 Request 返回 ErrNoHandler）。用于事件日志、监控等
+func AsObserver() SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.observer = true
+	}
+}
+
+// WithSync 使用同步投递
+func WithSync() SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.mode = DeliverSync
+	}
+}
+
+// DeliveryOf 返回订阅选项对应的投递方式、队列长度和溢出策略（跨进程转发订阅时使用）
+func DeliveryOf(opts ...SubscribeOption) (DeliveryMode, int, OverflowPolicy) {
+	o := resolveOptions(opts)
+	return o.mode, o.size, o.overflow
+}
+
+func resolveOptions(opts []SubscribeOption) subscribeOptions {
+	o := defaultSubscribeOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// 订阅的有界事件队列，由一个协程按顺序处理
+type eventQueue struct {
+	sub      *subscription
+	size     int
+	overflow OverflowPolicy
+
+	lock    sync.Mutex
+	cond    *sync.Cond
+	items   []Event
+	busy    bool // 处理函数执行中
+	closed  bool
+	dropped uint64
+	done    chan struct{} // 处理协程退出时关闭
+}
+
+func newEventQueue(sub *subscription, size int, overflow OverflowPolicy) *eventQueue {
+	q := &eventQueue{sub: sub, size: size, overflow: overflow, done: make(chan struct{})}
+	q.cond = sync.NewCond(&q.lock)
+	go q.run()
+	return q
+}
+
+// 入队，队列关闭后丢弃。需要回复的请求不会被丢弃，否则请求方只能等到超时：
+// 队列中全是请求时，新的请求与 Block 策略一样等待队列有空位
+func (q *eventQueue) push(evt Event) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for !q.closed && len(q.items) >= q.size && q.mustWait(evt) {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return
+	}
+	if len(q.items) >= q.size {
+		q.dropped++
+		// 首次丢弃和之后每 1000 次记录一次日志，避免刷屏
+		if q.dropped == 1 || q.dropped%1000 == 0 {
+			q.sub.bus.Logf("warn", "Event queue of subscription %d (%s, owner %q) is full, %s, %d events dropped so far, latest %s",
+				q.sub.id, q.sub.event, q.sub.owner, q.overflow, q.dropped, evt)
+		}
+		// 丢弃最旧的普通事件，没有可丢弃的旧事件时丢弃新事件
+		i := -1
+		if q.overflow == DropOldest {
+			i = q.oldestDroppable()
+		}
+		if i < 0 {
+			return
+		}
+		copy(q.items[i:], q.items[i+1:])
+		q.items[len(q.items)-1] = Event{}
+		q.items = q.items[:len(q.items)-1]
+	}
+	q.items = append(q.items, evt)
+	q.cond.Broadcast()
+}
+
+// 是否为需要回复的请求，旁观者不回复请求，按普通事件处理
+func (q *eventQueue) isRequest(evt Event) bool {
+	return evt.IsRequest() && !q.sub.observer
+}
+
+// 队列满时是否等待空位而不是丢弃
+func (q *eventQueue) mustWait(evt Event) bool {
+	switch {
+	case q.overflow == Block:
+		return true
+	case !q.isRequest(evt):
+		return false
+	case q.overflow == DropOldest:
+		return q.oldestDroppable() < 0
+	}
+	return true
+}
+
+// 队列中最旧的可丢弃事件的位置，全是请求时返回 -1
+func (q *eventQueue) oldestDroppable() int {
+	for i, evt := range q.items {
+		if !q.isRequest(evt) {
+			return i
+		}
+	}
+	return -1
+}
+
+// 处理协程，队列关闭且处理完后退出
+func (q *eventQueue) run() {
+	defer close(q.done)
+	for {
+		q.lock.Lock()
+		for len(q.items) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if len(q.items) == 0 {
+			q.lock.Unlock()
+			return
+		}
+		evt := q.items[0]
+		q.items[0] = Event{}
+		q.items = q.items[1:]
+		q.busy = true
+		q.cond.Broadcast()
+		q.lock.Unlock()
+
+		q.sub.invoke(evt)
+
+		q.lock.Lock()
+		q.busy = false
+		q.cond.Broadcast()
+		q.lock.Unlock()
+	}
+}
+
+// 关闭队列，已入队的事件仍会处理完（订阅已取消时跳过）
+func (q *eventQueue) close() {
+	q.lock.Lock()
+	q.closed = true
+	q.cond.Broadcast()
+	q.lock.Unlock()
+}
+
+// 等待队列中已有的事件处理完
+func (q *eventQueue) drain(ctx context.Context) error {
+	idle := make(chan struct{})
+	go func() {
+		q.lock.Lock()
+		for len(q.items) > 0 || q.busy {
+			q.cond.Wait()
+		}
+		q.lock.Unlock()
+		close(idle)
+	}()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		// 放弃等待，等待协程在队列处理完后自行退出
+		return ctx.Err()
+	}
+}
+
+// 执行处理函数，捕获 panic 并按处理函数失败上报，已取消的订阅不再处理
+func (s *subscription) invoke(evt Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.bus.handlerFailed(s, evt, fmt.Sprintf("panic: %v", r), string(debug.Stack()))
+		}
+	}()
+	if s.cancelled.Load() {
+		return
+	}
+	evt.sub = s
+	s.interceptHandle(evt)
+}
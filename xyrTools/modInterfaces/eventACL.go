@@ -0,0 +1,143 @@
+package modInterfaces
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 事件访问控制：限制模块通过 Context.Events 发布（含请求）和订阅的事件。
+// 只对以模块名义发布、订阅（Owner 返回的总线视图）生效，未设置访问控制的模块不受限制。
+
+// 访问控制模式
+type ACLMode string
+
+const (
+	ACLOff     ACLMode = "off"     // 不检查
+	ACLAudit   ACLMode = "audit"   // 记录违规但放行，用于迁移期间补全声明
+	ACLEnforce ACLMode = "enforce" // 记录并拒绝违规
+)
+
+// ParseACLMode 解析配置中的访问控制模式，空字符串为 off
+func ParseACLMode(s string) (ACLMode, error) {
+	switch mode := ACLMode(s); mode {
+	case "", ACLOff:
+		return ACLOff, nil
+	case ACLAudit, ACLEnforce:
+		return mode, nil
+	}
+	return ACLOff, fmt.Errorf("invalid acl mode %q (want off, audit or enforce)", s)
+}
+
+// 模块允许的事件，均为事件名或通配符
+type EventACL struct {
+	Publish   []string // 允许发布和请求的事件
+	Subscribe []string // 允许订阅的事件，订阅通配符时须被某一项完全覆盖
+}
+
+// 发布被访问控制拒绝
+var ErrNotPermitted = fmt.Errorf("%w: not permitted by module event ACL", ErrRejected)
+
+// CanPublish 是否允许发布事件
+func (acl EventACL) CanPublish(event string) bool {
+	for _, p := range acl.Publish {
+		if MatchTopic(p, event) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanSubscribe 是否允许订阅事件名或通配符
+func (acl EventACL) CanSubscribe(pattern string) bool {
+	for _, p := range acl.Subscribe {
+		if coversTopic(p, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// 通配符 allowed 匹配的事件是否包含 pattern 可能匹配的全部事件
+func coversTopic(allowed, pattern string) bool {
+	if !isPattern(pattern) {
+		return MatchTopic(allowed, pattern)
+	}
+	if !isPattern(allowed) {
+		return false
+	}
+	a, p := strings.Split(allowed, topicSeparator), strings.Split(pattern, topicSeparator)
+	for i, seg := range a {
+		if seg == wildcardSegment && i == len(a)-1 {
+			return len(p) > i
+		}
+		if i >= len(p) {
+			return false
+		}
+		// 非末尾的 ** 按 * 处理；pattern 末尾的 ** 可匹配多段，只有 allowed 末尾的 ** 能覆盖
+		ps := p[i]
+		if ps == wildcardSegment && i == len(p)-1 {
+			return false
+		}
+		if seg == wildcardOne || seg == wildcardSegment {
+			continue
+		}
+		if ps != seg {
+			return false
+		}
+	}
+	return len(a) == len(p)
+}
+
+// SetACLMode 设置访问控制模式
+func (bus *defaultEventBus) SetACLMode(mode ACLMode) {
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+	bus.aclMode = mode
+}
+
+// SetACL 设置模块的访问控制，acl 为 nil 时模块不受限制
+func (bus *defaultEventBus) SetACL(owner string, acl *EventACL) {
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+	if acl == nil {
+		delete(bus.acls, owner)
+		return
+	}
+	bus.acls[owner] = *acl
+}
+
+// 检查模块能否发布或订阅，违规时记录日志，enforce 模式下返回 false
+func (bus *defaultEventBus) permit(owner, action, event string) bool {
+	if owner == "" {
+		return true
+	}
+	bus.lock.RLock()
+	mode := bus.aclMode
+	acl, ok := bus.acls[owner]
+	bus.lock.RUnlock()
+	if !ok || mode == ACLOff || mode == "" {
+		return true
+	}
+	allowed := acl.CanPublish(event)
+	if action == "subscribe" {
+		allowed = acl.CanSubscribe(event)
+	}
+	if allowed {
+		return true
+	}
+	if mode == ACLAudit {
+		bus.Logf("warn", "ACL audit: module %s %s %s not declared", owner, action, event)
+		return true
+	}
+	bus.Logf("error", "ACL denied: module %s may not %s %s", owner, action, event)
+	return false
+}
+
+// 被访问控制拒绝的订阅，不会收到任何事件
+type deniedSubscription struct {
+	event string
+}
+
+func (s deniedSubscription) ID() uint64    { return 0 }
+func (s deniedSubscription) Event() string { return s.event }
+func (s deniedSubscription) Cancel()       {}
@@ -0,0 +1,101 @@
+package modInterfaces
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCoversTopic(t *testing.T) {
+	cases := []struct {
+		allowed, pattern string
+		want             bool
+	}{
+		{"a:b", "a:b", true},
+		{"a:*", "a:b", true},
+		{"a:*", "a:*", true},
+		{"a:**", "a:*", true},
+		{"a:**", "a:b:**", true},
+		{"a:**", "a:**", true},
+		{"**", "a:**", true},
+		{"a:*", "a:**", false},
+		{"a:b", "a:*", false},
+		{"a:*", "*:b", false},
+		{"*:b", "a:b", true},
+		{"a:*:c", "a:*:c", true},
+		{"a:*:c", "a:b", false},
+	}
+	for _, c := range cases {
+		if got := coversTopic(c.allowed, c.pattern); got != c.want {
+			t.Errorf("coversTopic(%q, %q) = %v, want %v", c.allowed, c.pattern, got, c.want)
+		}
+	}
+}
+
+func TestEventACL(t *testing.T) {
+	bus := NewEventBus()
+	var lock sync.Mutex
+	var logs []string
+	bus.SetLogger(func(level, msg string) {
+		lock.Lock()
+		logs = append(logs, level+" "+msg)
+		lock.Unlock()
+	})
+	bus.SetACL("tray", &EventACL{Publish: []string{"tray:*", "memory:optimize"}, Subscribe: []string{"tray:**"}})
+	tray := bus.Owner("tray")
+
+	var got atomic.Int64
+	bus.Subscribe("**", func(Event) { got.Add(1) }, WithSync())
+	bus.Subscribe("memory:optimize", func(evt Event) { evt.Respond(nil, nil) }, WithSync())
+
+	// 默认 off，不检查
+	tray.Publish("network:apply", nil)
+	if got.Load() != 1 {
+		t.Fatalf("delivered %d, want 1", got.Load())
+	}
+
+	bus.SetACLMode(ACLAudit)
+	tray.Publish("network:apply", nil)
+	if got.Load() != 2 || len(logs) != 1 || !strings.Contains(logs[0], "ACL audit") {
+		t.Fatalf("audit: delivered %d, logs %q", got.Load(), logs)
+	}
+
+	bus.SetACLMode(ACLEnforce)
+	tray.Publish("network:apply", nil)
+	tray.Publish("tray:clicked", nil)
+	if got.Load() != 3 {
+		t.Fatalf("enforce: delivered %d, want 3", got.Load())
+	}
+	if _, err := tray.Request(context.Background(), "memory:optimize", nil); err != nil {
+		t.Fatalf("allowed request: %v", err)
+	}
+	if _, err := tray.Request(context.Background(), "network:apply", nil); !errors.Is(err, ErrNotPermitted) || !errors.Is(err, ErrRejected) {
+		t.Fatalf("denied request = %v, want ErrNotPermitted", err)
+	}
+
+	var handled atomic.Int64
+	if sub := tray.Subscribe("tray:menu:*", func(Event) { handled.Add(1) }, WithSync()); sub.ID() == 0 {
+		t.Fatal("allowed subscription rejected")
+	}
+	denied := tray.Subscribe("**", func(Event) { handled.Add(100) }, WithSync())
+	if denied.ID() != 0 {
+		t.Fatal("denied subscription accepted")
+	}
+	denied.Cancel()
+	bus.Publish("tray:menu:open", nil)
+	if handled.Load() != 1 {
+		t.Fatalf("handled = %d, want 1", handled.Load())
+	}
+
+	// 未设置访问控制的模块和非模块发布者不受限制
+	bus.Owner("other").Publish("network:apply", nil)
+	bus.Publish("network:apply", nil)
+	bus.SetACL("tray", nil)
+	tray.Publish("network:apply", nil)
+	if got.Load() != 8 {
+		t.Fatalf("delivered %d, want 8", got.Load())
+	}
+}
@@ -0,0 +1,418 @@
+package modInterfaces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// --- 事件结构体 ---
+type Event struct {
+	Name      string
+	Data      interface{}
+	EventMeta // 发布模块、时间、ID、关联ID，见 eventMeta.go
+
+	reply *replyState   // 请求的回复通道，普通事件为 nil
+	sub   *subscription // 正在处理该事件的订阅，用于 Fail
+}
+
+// 请求的回复
+type Reply struct {
+	Data interface{}
+	Err  error
+}
+
+// 回复通道，只接收第一个回复
+type replyState struct {
+	once sync.Once
+	ch   chan Reply
+}
+
+// IsRequest 是否为 Request 发出的请求，请求需要处理函数调用 Respond 回复
+func (e Event) IsRequest() bool {
+	return e.reply != nil
+}
+
+// Respond 回复请求，多个处理函数回复时只有第一个生效。
+// 普通事件或已被回复时返回 false。
+func (e Event) Respond(data interface{}, err error) bool {
+	if e.reply == nil {
+		return false
+	}
+	sent := false
+	e.reply.once.Do(func() {
+		e.reply.ch <- Reply{Data: data, Err: err}
+		sent = true
+	})
+	return sent
+}
+
+// NewRequestEvent 创建请求事件，返回的通道接收第一个回复。用于在其他传输方式（如外部模块）上转发请求。
+func NewRequestEvent(name string, data interface{}, meta EventMeta) (Event, <-chan Reply) {
+	r := &replyState{ch: make(chan Reply, 1)}
+	return Event{Name: name, Data: data, EventMeta: meta, reply: r}, r.ch
+}
+
+// 请求没有任何处理函数订阅
+var ErrNoHandler = errors.New("eventbus: no handler for request")
+
+// 请求的默认超时时间，ctx 没有截止时间时使用
+const DefaultRequestTimeout = 10 * time.Second
+
+// --- 事件总线接口 ---
+type EventBus interface {
+	// 订阅事件，支持 * 和 ** 通配符（见 topicTrie.go），返回的订阅用于取消订阅。
+	// 默认按发布顺序异步投递（见 delivery.go），可用 WithQueue、WithSync 修改。
+	Subscribe(event string, handler func(Event), opts ...SubscribeOption) Subscription
+	Publish(event string, data interface{}) // 发布事件
+
+	// 发送请求并等待第一个回复。没有订阅者时返回 ErrNoHandler，
+	// 超时（ctx 到期，未设置截止时间时为 DefaultRequestTimeout）返回 ctx 的错误。
+	Request(ctx context.Context, event string, data interface{}) (interface{}, error)
+}
+
+// 订阅句柄
+type Subscription interface {
+	ID() uint64    // 订阅ID，同一总线内唯一
+	Event() string // 订阅的事件名或通配符
+	Cancel()       // 取消订阅，可重复调用；取消后不会再收到新的事件
+}
+
+// 支持按所有者管理订阅的事件总线，核心引擎用它在模块停止时取消模块的全部订阅
+type ScopedEventBus interface {
+	EventBus
+	Owner(owner string) EventBus           // 返回以 owner 名义订阅的总线视图，发布与原总线相同
+	CancelOwner(owner string) int          // 取消 owner 的全部订阅，返回取消的数量
+	SetLogger(log func(level, msg string)) // 设置总线自身的日志（处理函数 panic、队列溢出等）
+	SetInterceptors(ics ...Interceptor)    // 设置发布和处理函数调用的拦截器链，替换原有的链
+	HandlerFailures(module string) uint64  // 模块的处理函数失败次数，见 deadLetter.go
+	ResetHandlerFailures(module string)    // 将模块的处理函数失败次数清零
+	DeadLetters() []DeadLetter             // 处理失败的事件
+	Redeliver(id uint64) error             // 重新投递死信
+	ClearDeadLetters() int                 // 清除全部死信
+	SetACLMode(mode ACLMode)               // 设置访问控制模式，见 eventACL.go
+	SetACL(owner string, acl *EventACL)    // 设置模块允许发布、订阅的事件，nil 表示不受限制
+	Drain(ctx context.Context) error       // 等待所有订阅队列中已发布的事件处理完
+	Close(ctx context.Context) error       // 关闭总线：不再接受发布，处理完队列中的事件后结束处理协程
+}
+
+// --- 默认事件总线实现 ---
+type defaultEventBus struct {
+	subscribers map[string][]*subscription // 精确订阅，键为事件名
+	patterns    *topicNode                 // 通配符订阅
+	owned       map[uint64]*subscription   // 全部订阅，用于按所有者取消
+	lock        sync.RWMutex
+	nextID      uint64
+	nextEventID atomic.Uint64
+	closed      bool
+	log         func(level, msg string)
+
+	interceptors atomic.Pointer[[]Interceptor] // 拦截器链，见 interceptor.go
+
+	failures    map[string]uint64 // 各模块的处理函数失败次数，见 deadLetter.go
+	deadLetters []DeadLetter
+	nextDeadID  uint64
+
+	aclMode ACLMode             // 访问控制模式，见 eventACL.go
+	acls    map[string]EventACL // 各模块的访问控制
+}
+
+// 一个订阅
+type subscription struct {
+	bus       *defaultEventBus
+	id        uint64
+	event     string
+	owner     string
+	handler   func(Event)
+	cancelled atomic.Bool
+	queue     *eventQueue // 有序异步投递的队列，同步投递时为 nil
+	observer  bool        // 旁观者，不算作请求的处理函数
+}
+
+func (s *subscription) ID() uint64    { return s.id }
+func (s *subscription) Event() string { return s.event }
+
+func (s *subscription) Cancel() {
+	if s.cancelled.Swap(true) {
+		return
+	}
+	s.bus.remove(s)
+	if s.queue != nil {
+		s.queue.close()
+	}
+}
+
+func NewEventBus() ScopedEventBus {
+	return &defaultEventBus{
+		subscribers: make(map[string][]*subscription),
+		patterns:    newTopicNode(),
+		owned:       make(map[uint64]*subscription),
+		failures:    make(map[string]uint64),
+		acls:        make(map[string]EventACL),
+		log: func(level, msg string) {
+			fmt.Printf("[%s] %s\n", level, msg)
+		},
+	}
+}
+
+func (bus *defaultEventBus) SetLogger(log func(level, msg string)) {
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+	bus.log = log
+}
+
+// Logf 写总线日志
+func (bus *defaultEventBus) Logf(level, format string, args ...interface{}) {
+	bus.lock.RLock()
+	log := bus.log
+	bus.lock.RUnlock()
+	log(level, fmt.Sprintf(format, args...))
+}
+
+func (bus *defaultEventBus) Subscribe(event string, handler func(Event), opts ...SubscribeOption) Subscription {
+	return bus.subscribe("", event, handler, opts)
+}
+
+func (bus *defaultEventBus) subscribe(owner, event string, handler func(Event), opts []SubscribeOption) Subscription {
+	if !bus.permit(owner, "subscribe", event) {
+		return deniedSubscription{event: event}
+	}
+	o := resolveOptions(opts)
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+	bus.nextID++
+	sub := &subscription{bus: bus, id: bus.nextID, event: event, owner: owner, handler: handler, observer: o.observer}
+	if o.mode == DeliverQueued {
+		sub.queue = newEventQueue(sub, o.size, o.overflow)
+		if bus.closed {
+			sub.queue.close()
+		}
+	}
+	if isPattern(event) {
+		bus.patterns.insert(sub)
+	} else {
+		bus.subscribers[event] = append(bus.subscribers[event], sub)
+	}
+	bus.owned[sub.id] = sub
+	return sub
+}
+
+// 从订阅者列表中移除，复制新切片，不影响 Publish 中正在遍历的旧切片
+func (bus *defaultEventBus) remove(sub *subscription) {
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+	delete(bus.owned, sub.id)
+	if isPattern(sub.event) {
+		bus.patterns.remove(sub, strings.Split(sub.event, topicSeparator))
+		return
+	}
+	if rest := without(bus.subscribers[sub.event], sub); len(rest) == 0 {
+		delete(bus.subscribers, sub.event)
+	} else {
+		bus.subscribers[sub.event] = rest
+	}
+}
+
+func (bus *defaultEventBus) Owner(owner string) EventBus {
+	return ownedBus{bus: bus, owner: owner}
+}
+
+func (bus *defaultEventBus) CancelOwner(owner string) int {
+	bus.lock.RLock()
+	var owned []*subscription
+	for _, sub := range bus.owned {
+		if sub.owner == owner {
+			owned = append(owned, sub)
+		}
+	}
+	bus.lock.RUnlock()
+	for _, sub := range owned {
+		sub.Cancel()
+	}
+	return len(owned)
+}
+
+// Publish 方法用于发布一个事件，会触发所有订阅该事件的处理函数。
+// event 为要发布的事件名称。
+// data 为事件携带的数据，可传递任意类型的数据。
+// 发布事件，数据与事件目录中声明的类型不一致时记录错误并丢弃
+func (bus *defaultEventBus) Publish(event string, data interface{}) {
+	bus.publish("", nil, event, data)
+}
+
+func (bus *defaultEventBus) PublishCaused(cause EventMeta, event string, data interface{}) {
+	bus.publish("", &cause, event, data)
+}
+
+func (bus *defaultEventBus) publish(source string, cause *EventMeta, event string, data interface{}) {
+	evt := Event{Name: event, Data: data, EventMeta: bus.stamp(source, cause)}
+	if err := CheckEventData(event, data); err != nil {
+		bus.Logf("error", "Event %s dropped: %v", evt, err)
+		return
+	}
+	if !bus.permit(source, "publish", event) {
+		return
+	}
+	// 拒绝发布的拦截器自行记录日志
+	_ = bus.interceptPublish(evt, func(evt Event) error {
+		bus.deliver(bus.match(evt.Name), evt)
+		return nil
+	})
+}
+
+func (bus *defaultEventBus) Request(ctx context.Context, event string, data interface{}) (interface{}, error) {
+	return bus.request(ctx, "", nil, event, data)
+}
+
+func (bus *defaultEventBus) RequestCaused(ctx context.Context, cause EventMeta, event string, data interface{}) (interface{}, error) {
+	return bus.request(ctx, "", &cause, event, data)
+}
+
+func (bus *defaultEventBus) request(ctx context.Context, source string, cause *EventMeta, event string, data interface{}) (interface{}, error) {
+	meta := bus.stamp(source, cause)
+	if err := CheckEventData(event, data); err != nil {
+		bus.Logf("error", "Request %s %s rejected: %v", event, meta, err)
+		return nil, err
+	}
+	if !bus.permit(source, "publish", event) {
+		return nil, fmt.Errorf("%w: %s", ErrNotPermitted, event)
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultRequestTimeout)
+		defer cancel()
+	}
+	evt, replies := NewRequestEvent(event, data, meta)
+	err := bus.interceptPublish(evt, func(evt Event) error {
+		subs := bus.match(evt.Name)
+		if !hasHandler(subs) {
+			return fmt.Errorf("%w: %s", ErrNoHandler, evt.Name)
+		}
+		bus.deliver(subs, evt)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case r := <-replies:
+		return r.Data, r.Err
+	case <-ctx.Done():
+		bus.Logf("warn", "Request %s got no reply: %v", evt, ctx.Err())
+		return nil, fmt.Errorf("request %s: %w", event, ctx.Err())
+	}
+}
+
+// 是否有可以回复请求的订阅（旁观者除外）
+func hasHandler(subs []*subscription) bool {
+	for _, sub := range subs {
+		if !sub.observer {
+			return true
+		}
+	}
+	return false
+}
+
+// 获取匹配事件名的全部订阅，总线关闭后返回空
+func (bus *defaultEventBus) match(event string) []*subscription {
+	// 使用读锁，允许多个 goroutine 同时读取订阅者列表，提高并发性能
+	bus.lock.RLock()
+	if bus.closed {
+		bus.lock.RUnlock()
+		return nil
+	}
+	// 获取订阅该事件的所有订阅，取消订阅时会替换切片，这里拿到的切片不会被修改
+	subs := bus.subscribers[event]
+	// 再加上匹配的通配符订阅
+	if !bus.patterns.empty() {
+		subs = bus.patterns.match(strings.Split(event, topicSeparator), append([]*subscription(nil), subs...))
+	}
+	// 释放读锁
+	bus.lock.RUnlock()
+	return subs
+}
+
+// 将事件投递给订阅：同步订阅在当前协程中执行，其余进入各自的队列
+func (bus *defaultEventBus) deliver(subs []*subscription, evt Event) {
+	for _, sub := range subs {
+		if sub.queue == nil {
+			sub.invoke(evt)
+			continue
+		}
+		sub.queue.push(evt)
+	}
+}
+
+// 全部队列
+func (bus *defaultEventBus) queues() []*eventQueue {
+	bus.lock.RLock()
+	defer bus.lock.RUnlock()
+	queues := make([]*eventQueue, 0, len(bus.owned))
+	for _, sub := range bus.owned {
+		if sub.queue != nil {
+			queues = append(queues, sub.queue)
+		}
+	}
+	return queues
+}
+
+func (bus *defaultEventBus) Drain(ctx context.Context) error {
+	for _, q := range bus.queues() {
+		if err := q.drain(ctx); err != nil {
+			return fmt.Errorf("eventbus: drain: %w", err)
+		}
+	}
+	return nil
+}
+
+func (bus *defaultEventBus) Close(ctx context.Context) error {
+	bus.lock.Lock()
+	bus.closed = true
+	bus.lock.Unlock()
+	queues := bus.queues()
+	for _, q := range queues {
+		q.close()
+	}
+	for _, q := range queues {
+		select {
+		case <-q.done:
+		case <-ctx.Done():
+			return fmt.Errorf("eventbus: close: %w", ctx.Err())
+		}
+	}
+	return nil
+}
+
+// 以某个所有者名义订阅的总线视图
+type ownedBus struct {
+	bus   *defaultEventBus
+	owner string
+}
+
+func (b ownedBus) Subscribe(event string, handler func(Event), opts ...SubscribeOption) Subscription {
+	return b.bus.subscribe(b.owner, event, handler, opts)
+}
+
+func (b ownedBus) Publish(event string, data interface{}) {
+	b.bus.publish(b.owner, nil, event, data)
+}
+
+func (b ownedBus) PublishCaused(cause EventMeta, event string, data interface{}) {
+	b.bus.publish(b.owner, &cause, event, data)
+}
+
+func (b ownedBus) Request(ctx context.Context, event string, data interface{}) (interface{}, error) {
+	return b.bus.request(ctx, b.owner, nil, event, data)
+}
+
+func (b ownedBus) RequestCaused(ctx context.Context, cause EventMeta, event string, data interface{}) (interface{}, error) {
+	return b.bus.request(ctx, b.owner, &cause, event, data)
+}
+
+func (b ownedBus) Logf(level, format string, args ...interface{}) {
+	b.bus.Logf(level, format, args...)
+}
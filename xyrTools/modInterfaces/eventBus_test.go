@@ -0,0 +1,642 @@
+package modInterfaces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 等待计数达到期望值，超时失败
+func waitCount(t *testing.T, n *atomic.Int64, want int64) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for n.Load() < want {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d deliveries, got %d", want, n.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// 等待异步处理函数全部执行完，确认没有多余的投递
+func settle() {
+	time.Sleep(50 * time.Millisecond)
+}
+
+func TestSubscribePublish(t *testing.T) {
+	bus := NewEventBus()
+	var got atomic.Int64
+	sub := bus.Subscribe("a", func(evt Event) {
+		if evt.Name != "a" || evt.Data != 1 {
+			t.Errorf("unexpected event %+v", evt)
+		}
+		got.Add(1)
+	})
+	if sub.ID() == 0 || sub.Event() != "a" {
+		t.Fatalf("unexpected subscription id=%d event=%q", sub.ID(), sub.Event())
+	}
+	bus.Publish("a", 1)
+	bus.Publish("b", 1)
+	waitCount(t, &got, 1)
+	settle()
+	if got.Load() != 1 {
+		t.Fatalf("got %d deliveries, want 1", got.Load())
+	}
+}
+
+func TestSubscriptionIDsUnique(t *testing.T) {
+	bus := NewEventBus()
+	seen := make(map[uint64]bool)
+	for i := 0; i < 100; i++ {
+		sub := bus.Subscribe("a", func(Event) {})
+		if seen[sub.ID()] {
+			t.Fatalf("duplicate subscription id %d", sub.ID())
+		}
+		seen[sub.ID()] = true
+	}
+}
+
+func TestCancel(t *testing.T) {
+	bus := NewEventBus()
+	var kept, cancelled atomic.Int64
+	bus.Subscribe("a", func(Event) { kept.Add(1) })
+	sub := bus.Subscribe("a", func(Event) { cancelled.Add(1) })
+
+	sub.Cancel()
+	sub.Cancel() // 重复取消无影响
+	bus.Publish("a", nil)
+	waitCount(t, &kept, 1)
+	settle()
+	if cancelled.Load() != 0 {
+		t.Fatalf("cancelled handler was called %d times", cancelled.Load())
+	}
+}
+
+// 同一个函数订阅两次，取消其中一个不影响另一个
+func TestCancelSameHandler(t *testing.T) {
+	bus := NewEventBus()
+	var got atomic.Int64
+	handler := func(Event) { got.Add(1) }
+	first := bus.Subscribe("a", handler)
+	bus.Subscribe("a", handler)
+
+	first.Cancel()
+	bus.Publish("a", nil)
+	waitCount(t, &got, 1)
+	settle()
+	if got.Load() != 1 {
+		t.Fatalf("got %d deliveries, want 1", got.Load())
+	}
+}
+
+func TestCancelOwner(t *testing.T) {
+	bus := NewEventBus()
+	var modA, modB, root atomic.Int64
+	a := bus.Owner("modA")
+	a.Subscribe("x", func(Event) { modA.Add(1) })
+	a.Subscribe("y", func(Event) { modA.Add(1) })
+	bus.Owner("modB").Subscribe("x", func(Event) { modB.Add(1) })
+	bus.Subscribe("x", func(Event) { root.Add(1) })
+
+	if n := bus.CancelOwner("modA"); n != 2 {
+		t.Fatalf("CancelOwner cancelled %d subscriptions, want 2", n)
+	}
+	if n := bus.CancelOwner("modA"); n != 0 {
+		t.Fatalf("second CancelOwner cancelled %d subscriptions, want 0", n)
+	}
+	// 通过所有者视图发布，与原总线相同
+	a.Publish("x", nil)
+	bus.Publish("y", nil)
+	waitCount(t, &modB, 1)
+	waitCount(t, &root, 1)
+	settle()
+	if modA.Load() != 0 {
+		t.Fatalf("cancelled owner received %d events", modA.Load())
+	}
+}
+
+// 处理函数 panic 不影响其他订阅者
+func TestHandlerPanic(t *testing.T) {
+	bus := NewEventBus()
+	var got atomic.Int64
+	bus.Subscribe("a", func(Event) { panic("boom") })
+	bus.Subscribe("a", func(Event) { got.Add(1) })
+	bus.Publish("a", nil)
+	waitCount(t, &got, 1)
+}
+
+// 并发订阅、发布、取消，配合 go test -race 检查数据竞争
+func TestConcurrentSubscribePublishCancel(t *testing.T) {
+	bus := NewEventBus()
+	var delivered atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(3)
+		owner := bus.Owner(string(rune('a' + i)))
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				sub := owner.Subscribe("evt", func(Event) { delivered.Add(1) })
+				if j%2 == 0 {
+					sub.Cancel()
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				bus.Publish("evt", j)
+			}
+		}()
+		go func(owner string) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				bus.CancelOwner(owner)
+			}
+		}(string(rune('a' + i)))
+	}
+	wg.Wait()
+
+	// 全部取消后不再有投递
+	for i := 0; i < 4; i++ {
+		bus.CancelOwner(string(rune('a' + i)))
+	}
+	settle()
+	before := delivered.Load()
+	bus.Publish("evt", nil)
+	settle()
+	if after := delivered.Load(); after != before {
+		t.Fatalf("got %d deliveries after cancelling all subscriptions", after-before)
+	}
+}
+
+// 通配符匹配规则，与精确订阅对照
+func TestWildcardMatching(t *testing.T) {
+	cases := []struct {
+		pattern string
+		event   string
+		want    bool
+	}{
+		{"memory:optimized", "memory:optimized", true},
+		{"memory:optimized", "memory:optimizeByNames", false},
+		{"memory:optimized", "memory", false},
+		{"memory:*", "memory:optimized", true},
+		{"memory:*", "memory:optimizeByNames", true},
+		{"memory:*", "memory", false},
+		{"memory:*", "memory:a:b", false},
+		{"memory:*", "sysTray:netCfgChanged", false},
+		{"*:alert", "fileMonitor:alert", true},
+		{"*:alert", "fileMonitor:changed", false},
+		{"*:alert", "alert", false},
+		{"*:*", "sysTray:netCfgChanged", true},
+		{"*", "memory", true},
+		{"*", "memory:optimized", false},
+		{"core:**", "core:moduleStarted", true},
+		{"core:**", "core:a:b:c", true},
+		{"core:**", "core", false},
+		{"**", "memory:optimized", true},
+		{"**", "x", true},
+		{"a:**:c", "a:b:c", true}, // 不在末尾的 ** 按 * 处理
+		{"a:**:c", "a:b:b:c", false},
+	}
+	for _, c := range cases {
+		bus := NewEventBus()
+		var got atomic.Int64
+		bus.Subscribe(c.pattern, func(evt Event) {
+			if evt.Name != c.event {
+				t.Errorf("pattern %q: handler got event name %q, want %q", c.pattern, evt.Name, c.event)
+			}
+			got.Add(1)
+		})
+		bus.Publish(c.event, nil)
+		settle()
+		if matched := got.Load() == 1; matched != c.want {
+			t.Errorf("pattern %q event %q: matched=%v, want %v", c.pattern, c.event, matched, c.want)
+		}
+		if matched := MatchTopic(c.pattern, c.event); matched != c.want {
+			t.Errorf("MatchTopic(%q, %q) = %v, want %v", c.pattern, c.event, matched, c.want)
+		}
+	}
+}
+
+// 精确订阅和通配符订阅同时存在时各收到一次
+func TestWildcardAndExact(t *testing.T) {
+	bus := NewEventBus()
+	var exact, domain, all atomic.Int64
+	bus.Subscribe("memory:optimized", func(Event) { exact.Add(1) })
+	bus.Subscribe("memory:*", func(Event) { domain.Add(1) })
+	bus.Subscribe("**", func(Event) { all.Add(1) })
+
+	bus.Publish("memory:optimized", nil)
+	bus.Publish("memory:optimizeByNames", nil)
+	bus.Publish("sysTray:netCfgChanged", nil)
+	waitCount(t, &all, 3)
+	settle()
+	if exact.Load() != 1 || domain.Load() != 2 || all.Load() != 3 {
+		t.Fatalf("exact=%d domain=%d all=%d, want 1 2 3", exact.Load(), domain.Load(), all.Load())
+	}
+}
+
+func TestCancelWildcard(t *testing.T) {
+	bus := NewEventBus()
+	var got, kept atomic.Int64
+	sub := bus.Subscribe("memory:*", func(Event) { got.Add(1) })
+	bus.Subscribe("memory:*", func(Event) { kept.Add(1) })
+	sub.Cancel()
+	bus.Publish("memory:optimized", nil)
+	waitCount(t, &kept, 1)
+	settle()
+	if got.Load() != 0 {
+		t.Fatalf("cancelled wildcard handler was called %d times", got.Load())
+	}
+
+	// 全部取消后前缀树应清空
+	bus.CancelOwner("")
+	if b := bus.(*defaultEventBus); !b.patterns.empty() {
+		t.Fatal("pattern trie not empty after cancelling all subscriptions")
+	}
+}
+
+func TestCancelOwnerWildcard(t *testing.T) {
+	bus := NewEventBus()
+	var got atomic.Int64
+	bus.Owner("audit").Subscribe("*:alert", func(Event) { got.Add(1) })
+	if n := bus.CancelOwner("audit"); n != 1 {
+		t.Fatalf("CancelOwner cancelled %d subscriptions, want 1", n)
+	}
+	bus.Publish("fileMonitor:alert", nil)
+	settle()
+	if got.Load() != 0 {
+		t.Fatalf("cancelled owner received %d events", got.Load())
+	}
+}
+
+// 大量订阅时的匹配开销
+func BenchmarkPublishMatch(b *testing.B) {
+	bus := NewEventBus().(*defaultEventBus)
+	for i := 0; i < 1000; i++ {
+		bus.Subscribe(fmt.Sprintf("domain%d:*", i), func(Event) {})
+		bus.Subscribe(fmt.Sprintf("domain%d:event%d", i, i), func(Event) {})
+	}
+	bus.Subscribe("*:alert", func(Event) {})
+	segs := strings.Split("domain500:alert", topicSeparator)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bus.lock.RLock()
+		bus.patterns.match(segs, nil)
+		bus.lock.RUnlock()
+	}
+}
+
+func TestRequestReply(t *testing.T) {
+	bus := NewEventBus()
+	bus.Subscribe("math:double", func(evt Event) {
+		if !evt.IsRequest() {
+			t.Error("expected request event")
+		}
+		evt.Respond(evt.Data.(int)*2, nil)
+	})
+	reply, err := bus.Request(context.Background(), "math:double", 21)
+	if err != nil || reply != 42 {
+		t.Fatalf("Request = %v, %v; want 42, nil", reply, err)
+	}
+}
+
+func TestRequestError(t *testing.T) {
+	bus := NewEventBus()
+	want := errors.New("failed")
+	bus.Owner("mod").Subscribe("a:b", func(evt Event) { evt.Respond(nil, want) })
+	if _, err := bus.Request(context.Background(), "a:b", nil); !errors.Is(err, want) {
+		t.Fatalf("Request error = %v, want %v", err, want)
+	}
+}
+
+func TestRequestNoHandler(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe("a:b", func(evt Event) { evt.Respond(1, nil) })
+	sub.Cancel()
+	if _, err := bus.Request(context.Background(), "a:b", nil); !errors.Is(err, ErrNoHandler) {
+		t.Fatalf("Request error = %v, want ErrNoHandler", err)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	bus := NewEventBus()
+	bus.Subscribe("a:b", func(Event) {}) // 不回复
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := bus.Request(ctx, "a:b", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Request error = %v, want DeadlineExceeded", err)
+	}
+}
+
+// 多个处理函数回复时只有第一个生效，通配符订阅也可以回复
+func TestRequestFirstReplyWins(t *testing.T) {
+	bus := NewEventBus()
+	var sent atomic.Int64
+	for i := 0; i < 5; i++ {
+		bus.Subscribe("a:*", func(evt Event) {
+			if evt.Respond("ok", nil) {
+				sent.Add(1)
+			}
+		})
+	}
+	reply, err := bus.Request(context.Background(), "a:b", nil)
+	if err != nil || reply != "ok" {
+		t.Fatalf("Request = %v, %v; want ok, nil", reply, err)
+	}
+	settle()
+	if sent.Load() != 1 {
+		t.Fatalf("%d replies accepted, want 1", sent.Load())
+	}
+}
+
+func TestRespondToPublishedEvent(t *testing.T) {
+	bus := NewEventBus()
+	done := make(chan bool, 1)
+	bus.Subscribe("a", func(evt Event) { done <- evt.Respond(1, nil) })
+	bus.Publish("a", nil)
+	if <-done {
+		t.Fatal("Respond to a published event returned true")
+	}
+}
+
+// 阻塞处理函数直到 release 关闭，用于让队列积压
+func blockingHandler(started chan<- struct{}, release <-chan struct{}, got *[]int, lock *sync.Mutex) func(Event) {
+	var once sync.Once
+	return func(evt Event) {
+		once.Do(func() {
+			close(started)
+			<-release
+		})
+		lock.Lock()
+		*got = append(*got, evt.Data.(int))
+		lock.Unlock()
+	}
+}
+
+// 默认有序投递：同一订阅按发布顺序处理
+func TestQueuedDeliveryOrder(t *testing.T) {
+	bus := NewEventBus()
+	var lock sync.Mutex
+	var got []int
+	bus.Subscribe("a", func(evt Event) {
+		lock.Lock()
+		got = append(got, evt.Data.(int))
+		lock.Unlock()
+	})
+	for i := 0; i < 200; i++ {
+		bus.Publish("a", i)
+	}
+	if err := bus.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if len(got) != 200 {
+		t.Fatalf("got %d deliveries, want 200", len(got))
+	}
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("delivery %d has data %d, events out of order", i, v)
+		}
+	}
+}
+
+func TestOverflowPolicies(t *testing.T) {
+	tests := []struct {
+		overflow OverflowPolicy
+		want     []int
+	}{
+		// 第一个事件已被处理协程取出，队列中保留 2 个
+		{DropOldest, []int{0, 3, 4}},
+		{DropNewest, []int{0, 1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.overflow.String(), func(t *testing.T) {
+			bus := NewEventBus()
+			bus.SetLogger(func(string, string) {})
+			var lock sync.Mutex
+			var got []int
+			started, release := make(chan struct{}), make(chan struct{})
+			bus.Subscribe("a", blockingHandler(started, release, &got, &lock), WithQueue(2, tt.overflow))
+			bus.Publish("a", 0)
+			<-started
+			for i := 1; i < 5; i++ {
+				bus.Publish("a", i)
+			}
+			close(release)
+			if err := bus.Drain(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 队列中的请求数
+func queuedRequests(sub Subscription) int {
+	q := sub.(*subscription).queue
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	n := 0
+	for _, evt := range q.items {
+		if evt.IsRequest() {
+			n++
+		}
+	}
+	return n
+}
+
+// DropOldest 策略不丢弃请求：丢弃最旧的普通事件，队列中全是请求时丢弃新的普通事件，新的请求等待空位
+func TestOverflowKeepsRequests(t *testing.T) {
+	bus := NewEventBus()
+	bus.SetLogger(func(string, string) {})
+	var lock sync.Mutex
+	var got []int
+	started, release := make(chan struct{}), make(chan struct{})
+	handle := blockingHandler(started, release, &got, &lock)
+	sub := bus.Subscribe("a", func(evt Event) {
+		handle(evt)
+		evt.Respond(evt.Data, nil)
+	}, WithQueue(2, DropOldest))
+	waitRequests := func(n int) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for queuedRequests(sub) != n {
+			if time.Now().After(deadline) {
+				t.Fatalf("queued requests = %d, want %d", queuedRequests(sub), n)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	replies := make(chan int, 3)
+	request := func(n int) {
+		go func() {
+			reply, err := bus.Request(context.Background(), "a", n)
+			if err != nil {
+				t.Errorf("Request(%d): %v", n, err)
+				reply = -1
+			}
+			replies <- reply.(int)
+		}()
+	}
+
+	bus.Publish("a", 0)
+	<-started
+	request(1)
+	waitRequests(1)
+	bus.Publish("a", 2)
+	bus.Publish("a", 3) // 丢弃 2
+	request(4)          // 丢弃 3
+	waitRequests(2)
+	bus.Publish("a", 5) // 队列中全是请求，丢弃 5
+	request(6)          // 等待空位
+	select {
+	case n := <-replies:
+		t.Fatalf("request %d answered before the handler was released", n)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	for i := 0; i < 3; i++ {
+		<-replies
+	}
+	if err := bus.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if fmt.Sprint(got) != "[0 1 4 6]" {
+		t.Fatalf("got %v, want [0 1 4 6]", got)
+	}
+}
+
+// Block 策略：队列满时发布方阻塞，不丢事件
+func TestOverflowBlock(t *testing.T) {
+	bus := NewEventBus()
+	var lock sync.Mutex
+	var got []int
+	started, release := make(chan struct{}), make(chan struct{})
+	bus.Subscribe("a", blockingHandler(started, release, &got, &lock), WithQueue(1, Block))
+	bus.Publish("a", 0)
+	<-started
+	bus.Publish("a", 1)
+	published := make(chan struct{})
+	go func() {
+		bus.Publish("a", 2)
+		close(published)
+	}()
+	select {
+	case <-published:
+		t.Fatal("Publish did not block on a full queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-published
+	if err := bus.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if fmt.Sprint(got) != "[0 1 2]" {
+		t.Fatalf("got %v, want [0 1 2]", got)
+	}
+}
+
+// 同步投递在 Publish 返回前执行完
+func TestSyncDelivery(t *testing.T) {
+	bus := NewEventBus()
+	var got atomic.Int64
+	bus.Subscribe("a", func(Event) { got.Add(1) }, WithSync())
+	bus.Publish("a", nil)
+	if got.Load() != 1 {
+		t.Fatalf("got %d deliveries after Publish returned, want 1", got.Load())
+	}
+}
+
+func TestDrainTimeout(t *testing.T) {
+	bus := NewEventBus()
+	release := make(chan struct{})
+	defer close(release)
+	bus.Subscribe("a", func(Event) { <-release })
+	bus.Publish("a", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := bus.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Drain = %v, want DeadlineExceeded", err)
+	}
+}
+
+// Close 处理完已发布的事件，之后的发布被丢弃
+func TestClose(t *testing.T) {
+	bus := NewEventBus()
+	var got atomic.Int64
+	bus.Subscribe("a", func(Event) {
+		time.Sleep(time.Millisecond)
+		got.Add(1)
+	})
+	for i := 0; i < 10; i++ {
+		bus.Publish("a", i)
+	}
+	if err := bus.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got.Load() != 10 {
+		t.Fatalf("got %d deliveries before Close returned, want 10", got.Load())
+	}
+	bus.Publish("a", nil)
+	bus.Subscribe("a", func(Event) { got.Add(1) })
+	bus.Publish("a", nil)
+	settle()
+	if got.Load() != 10 {
+		t.Fatalf("got %d deliveries after Close, want 10", got.Load())
+	}
+}
+
+// 元数据：发布模块、时间、ID，后续事件继承关联ID
+func TestEventMeta(t *testing.T) {
+	bus := NewEventBus()
+	events := make(chan Event, 3)
+	bus.Subscribe("a", func(evt Event) {
+		events <- evt
+		evt.FollowUp(bus.Owner("m2")).Publish("b", nil)
+	})
+	bus.Subscribe("b", func(evt Event) {
+		events <- evt
+		evt.FollowUp(bus).Request(context.Background(), "c", nil)
+	})
+	bus.Subscribe("c", func(evt Event) {
+		events <- evt
+		evt.Respond(nil, nil)
+	})
+	before := time.Now()
+	bus.Owner("m1").Publish("a", nil)
+	a, b, c := <-events, <-events, <-events
+	if a.Source != "m1" || b.Source != "m2" || c.Source != "" {
+		t.Fatalf("sources = %q, %q, %q; want m1, m2, empty", a.Source, b.Source, c.Source)
+	}
+	if a.ID == 0 || b.ID <= a.ID || c.ID <= b.ID {
+		t.Fatalf("ids = %d, %d, %d; want increasing", a.ID, b.ID, c.ID)
+	}
+	if a.Time.Before(before) || c.Time.Before(a.Time) {
+		t.Fatalf("times = %v, %v; want after %v and ordered", a.Time, c.Time, before)
+	}
+	if a.CorrelationID != a.ID || b.CorrelationID != a.ID || c.CorrelationID != a.ID {
+		t.Fatalf("correlation ids = %d, %d, %d; want %d", a.CorrelationID, b.CorrelationID, c.CorrelationID, a.ID)
+	}
+	if a.CausedBy != 0 || b.CausedBy != a.ID || c.CausedBy != b.ID {
+		t.Fatalf("caused by = %d, %d, %d; want 0, %d, %d", a.CausedBy, b.CausedBy, c.CausedBy, a.ID, b.ID)
+	}
+	if s := b.String(); s != fmt.Sprintf("b id=%d source=m2 corr=%d cause=%d", b.ID, a.ID, a.ID) {
+		t.Fatalf("String() = %q", s)
+	}
+}
@@ -0,0 +1,251 @@
+package modInterfaces
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// 事件目录：集中声明事件名、数据类型和说明。
+// 模块在包级变量中用 DeclareEvent / DeclareRequest 声明自己发布或处理的事件，
+// 再通过 Publish、Subscribe、Request、Handle 等泛型函数收发，数据类型由编译器检查。
+// 对已声明的事件，总线在发布时检查数据类型，不匹配时记录错误并丢弃事件（请求返回错误）。
+
+// 已声明事件的说明
+type EventDecl struct {
+	Name        string       // 事件名
+	Type        reflect.Type // 数据类型
+	ReplyType   reflect.Type // 请求的回复类型，普通事件为 nil
+	Description string       // 说明
+}
+
+// 是否为请求（需要回复）
+func (d EventDecl) IsRequest() bool {
+	return d.ReplyType != nil
+}
+
+// 普通事件，T 为数据类型
+type Topic[T any] struct {
+	name string
+}
+
+func (t Topic[T]) Name() string { return t.name }
+
+// 请求事件，Req 为请求数据类型，Resp 为回复类型
+type RequestTopic[Req, Resp any] struct {
+	name string
+}
+
+func (t RequestTopic[Req, Resp]) Name() string { return t.name }
+
+var (
+	catalog     = make(map[string]EventDecl)
+	catalogLock sync.RWMutex
+)
+
+// DeclareEvent 声明事件及其数据类型。
+// 只应在包级变量初始化时调用，事件名不能含通配符，重复声明视为编程错误直接 panic。
+func DeclareEvent[T any](name, description string) Topic[T] {
+	declare(EventDecl{Name: name, Type: typeOf[T](), Description: description})
+	return Topic[T]{name: name}
+}
+
+// DeclareRequest 声明请求事件及其请求、回复类型，规则同 DeclareEvent
+func DeclareRequest[Req, Resp any](name, description string) RequestTopic[Req, Resp] {
+	declare(EventDecl{Name: name, Type: typeOf[Req](), ReplyType: typeOf[Resp](), Description: description})
+	return RequestTopic[Req, Resp]{name: name}
+}
+
+func declare(decl EventDecl) {
+	if isPattern(decl.Name) {
+		panic(fmt.Sprintf("eventbus: event %s declared with a wildcard", decl.Name))
+	}
+	catalogLock.Lock()
+	defer catalogLock.Unlock()
+	if _, exists := catalog[decl.Name]; exists {
+		panic(fmt.Sprintf("eventbus: event %s declared twice", decl.Name))
+	}
+	catalog[decl.Name] = decl
+}
+
+// 获取类型参数对应的 reflect.Type（T 为接口类型时也能得到接口本身）
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// LookupEvent 查找已声明的事件
+func LookupEvent(name string) (EventDecl, bool) {
+	catalogLock.RLock()
+	defer catalogLock.RUnlock()
+	decl, ok := catalog[name]
+	return decl, ok
+}
+
+// EventCatalog 返回全部已声明的事件（按事件名排序）
+func EventCatalog() []EventDecl {
+	catalogLock.RLock()
+	defer catalogLock.RUnlock()
+	decls := make([]EventDecl, 0, len(catalog))
+	for _, decl := range catalog {
+		decls = append(decls, decl)
+	}
+	sort.Slice(decls, func(i, j int) bool { return decls[i].Name < decls[j].Name })
+	return decls
+}
+
+// WriteEventCatalog 以表格形式输出事件目录，用于文档和命令行查看
+func WriteEventCatalog(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "EVENT\tDATA\tREPLY\tDESCRIPTION")
+	for _, decl := range EventCatalog() {
+		reply := "-"
+		if decl.IsRequest() {
+			reply = decl.ReplyType.String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", decl.Name, decl.Type, reply, decl.Description)
+	}
+	return tw.Flush()
+}
+
+// 数据是否可作为类型 t 的值，nil 只匹配可为 nil 的类型
+func typeMatches(t reflect.Type, data interface{}) bool {
+	if data == nil {
+		switch t.Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+			return true
+		}
+		return false
+	}
+	return reflect.TypeOf(data).AssignableTo(t)
+}
+
+// CheckEventData 检查事件数据是否与声明的类型一致，未声明的事件不检查
+func CheckEventData(name string, data interface{}) error {
+	decl, ok := LookupEvent(name)
+	if !ok || typeMatches(decl.Type, data) {
+		return nil
+	}
+	return fmt.Errorf("eventbus: event %s expects %s, got %T", name, decl.Type, data)
+}
+
+// DecodeEventData 将 JSON 数据解码为事件声明的类型，未声明的事件解码为通用类型（map、[]interface{} 等）。
+// 用于接收跨进程（外部模块）发布的事件
+func DecodeEventData(name string, raw json.RawMessage) (interface{}, error) {
+	decl, ok := LookupEvent(name)
+	return decodeAs(decl.Type, ok, raw)
+}
+
+// DecodeReplyData 将 JSON 数据解码为请求声明的回复类型，规则同 DecodeEventData
+func DecodeReplyData(name string, raw json.RawMessage) (interface{}, error) {
+	decl, ok := LookupEvent(name)
+	return decodeAs(decl.ReplyType, ok && decl.IsRequest(), raw)
+}
+
+func decodeAs(t reflect.Type, typed bool, raw json.RawMessage) (interface{}, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		if typed {
+			return reflect.Zero(t).Interface(), nil
+		}
+		return nil, nil
+	}
+	if !typed {
+		var data interface{}
+		err := json.Unmarshal(raw, &data)
+		return data, err
+	}
+	ptr := reflect.New(t)
+	if err := json.Unmarshal(raw, ptr.Interface()); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", t, err)
+	}
+	return ptr.Elem().Interface(), nil
+}
+
+// 将事件数据转换为 T：类型一致时直接返回，否则按 JSON 转换（外部模块收到的事件为通用类型）
+func convertData[T any](data interface{}) (T, error) {
+	if v, ok := data.(T); ok {
+		return v, nil
+	}
+	var v T
+	if data == nil {
+		return v, nil
+	}
+	raw, err := json.Marshal(data)
+	if err == nil {
+		err = json.Unmarshal(raw, &v)
+	}
+	if err != nil {
+		return v, fmt.Errorf("eventbus: cannot convert %T to %s: %w", data, typeOf[T](), err)
+	}
+	return v, nil
+}
+
+// 可选接口：能记录自身日志的总线，类型化订阅在数据无法转换时通过它记录错误
+type LoggingBus interface {
+	Logf(level, format string, args ...interface{})
+}
+
+// 记录总线错误，总线不支持日志时输出到标准错误
+func busLogf(bus EventBus, level, format string, args ...interface{}) {
+	if lb, ok := bus.(LoggingBus); ok {
+		lb.Logf(level, format, args...)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "[%s] %s\n", strings.ToUpper(level), fmt.Sprintf(format, args...))
+}
+
+// Publish 发布类型化事件
+func Publish[T any](bus EventBus, topic Topic[T], data T) {
+	bus.Publish(topic.name, data)
+}
+
+// Subscribe 订阅类型化事件，数据无法转换为 T 时按处理函数失败上报并跳过
+func Subscribe[T any](bus EventBus, topic Topic[T], handler func(data T, evt Event), opts ...SubscribeOption) Subscription {
+	return bus.Subscribe(topic.name, func(evt Event) {
+		data, err := convertData[T](evt.Data)
+		if err != nil {
+			if !evt.Fail(err) {
+				busLogf(bus, "error", "Event %s skipped: %v", evt, err)
+			}
+			return
+		}
+		handler(data, evt)
+	}, opts...)
+}
+
+// Request 发送类型化请求并等待回复，规则同 EventBus.Request
+func Request[Req, Resp any](ctx context.Context, bus EventBus, topic RequestTopic[Req, Resp], data Req) (Resp, error) {
+	reply, err := bus.Request(ctx, topic.name, data)
+	if err != nil {
+		var zero Resp
+		return zero, err
+	}
+	return convertData[Resp](reply)
+}
+
+// Handle 处理类型化请求，以处理函数的返回值回复。
+// 以 Publish 发布的同名事件也会执行处理函数，返回值被忽略，返回错误时按处理函数失败上报；
+// 数据无法转换为 Req 时回复错误并按处理函数失败上报
+func Handle[Req, Resp any](bus EventBus, topic RequestTopic[Req, Resp], handler func(data Req, evt Event) (Resp, error), opts ...SubscribeOption) Subscription {
+	return bus.Subscribe(topic.name, func(evt Event) {
+		data, err := convertData[Req](evt.Data)
+		if err != nil {
+			if !evt.Fail(err) {
+				busLogf(bus, "error", "Request %s rejected: %v", evt, err)
+			}
+			evt.Respond(nil, err)
+			return
+		}
+		resp, err := handler(data, evt)
+		if !evt.IsRequest() && err != nil {
+			evt.Fail(err)
+		}
+		evt.Respond(resp, err)
+	}, opts...)
+}
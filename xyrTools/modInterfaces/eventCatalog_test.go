@@ -0,0 +1,134 @@
+package modInterfaces
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type testPayload struct {
+	Name  string
+	Count int
+}
+
+// 事件目录是全局的，测试使用独立的事件名
+var (
+	testTopic   = DeclareEvent[testPayload]("test:typed", "typed test event")
+	testRequest = DeclareRequest[testPayload, int]("test:request", "typed test request")
+)
+
+func TestTypedPublishSubscribe(t *testing.T) {
+	bus := NewEventBus()
+	got := make(chan testPayload, 1)
+	Subscribe(bus, testTopic, func(data testPayload, evt Event) { got <- data })
+	Publish(bus, testTopic, testPayload{Name: "a", Count: 1})
+	if data := <-got; data.Name != "a" || data.Count != 1 {
+		t.Fatalf("got %+v", data)
+	}
+}
+
+// 发布与声明不一致的数据：记录错误，不投递
+func TestPublishTypeMismatch(t *testing.T) {
+	bus := NewEventBus()
+	var lock sync.Mutex
+	var logs []string
+	bus.SetLogger(func(level, msg string) {
+		lock.Lock()
+		logs = append(logs, level+": "+msg)
+		lock.Unlock()
+	})
+	delivered := make(chan struct{}, 1)
+	bus.Subscribe(testTopic.Name(), func(Event) { delivered <- struct{}{} })
+	bus.Owner("m").Publish(testTopic.Name(), "wrong")
+	if err := bus.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-delivered:
+		t.Fatal("mismatched event was delivered")
+	default:
+	}
+	lock.Lock()
+	if len(logs) != 1 || !strings.HasPrefix(logs[0], "error: ") || !strings.Contains(logs[0], "source=m ") || !strings.Contains(logs[0], "string") {
+		t.Errorf("unexpected logs %q", logs)
+	}
+	lock.Unlock()
+	if _, err := bus.Request(context.Background(), testTopic.Name(), 1); err == nil {
+		t.Fatal("Request with mismatched data returned no error")
+	}
+}
+
+func TestTypedRequestHandle(t *testing.T) {
+	bus := NewEventBus()
+	Handle(bus, testRequest, func(data testPayload, _ Event) (int, error) {
+		if data.Count < 0 {
+			return 0, errors.New("negative")
+		}
+		return data.Count * 2, nil
+	})
+	n, err := Request(context.Background(), bus, testRequest, testPayload{Count: 21})
+	if err != nil || n != 42 {
+		t.Fatalf("Request = %d, %v; want 42, nil", n, err)
+	}
+	if _, err := Request(context.Background(), bus, testRequest, testPayload{Count: -1}); err == nil || err.Error() != "negative" {
+		t.Fatalf("Request error = %v, want negative", err)
+	}
+}
+
+// 外部模块经 JSON 传来的数据解码为声明的类型
+func TestDecodeEventData(t *testing.T) {
+	raw, _ := json.Marshal(testPayload{Name: "a", Count: 2})
+	data, err := DecodeEventData(testTopic.Name(), raw)
+	if err != nil || data != (testPayload{Name: "a", Count: 2}) {
+		t.Fatalf("DecodeEventData = %#v, %v", data, err)
+	}
+	if err := CheckEventData(testTopic.Name(), data); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := DecodeReplyData(testRequest.Name(), json.RawMessage("3"))
+	if err != nil || reply != 3 {
+		t.Fatalf("DecodeReplyData = %#v, %v", reply, err)
+	}
+	data, err = DecodeEventData("test:undeclared", json.RawMessage(`{"a":1}`))
+	if m, ok := data.(map[string]interface{}); err != nil || !ok || m["a"] != 1.0 {
+		t.Fatalf("DecodeEventData(undeclared) = %#v, %v", data, err)
+	}
+}
+
+// 通用类型的数据（如外部模块收到的事件）按 JSON 转换
+func TestSubscribeConvertsGenericData(t *testing.T) {
+	bus := NewEventBus()
+	got := make(chan testPayload, 1)
+	topic := Topic[testPayload]{name: "test:generic"}
+	Subscribe(bus, topic, func(data testPayload, evt Event) { got <- data })
+	bus.Publish("test:generic", map[string]interface{}{"Name": "b", "Count": 3})
+	if data := <-got; data.Name != "b" || data.Count != 3 {
+		t.Fatalf("got %+v", data)
+	}
+}
+
+func TestDeclareTwicePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("declaring an event twice did not panic")
+		}
+	}()
+	DeclareEvent[int](testTopic.Name(), "")
+}
+
+func TestWriteEventCatalog(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteEventCatalog(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"test:typed", "modInterfaces.testPayload", "test:request", "int", EventShutdownRequested} {
+		if !strings.Contains(out, want) {
+			t.Errorf("catalog output misses %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package modInterfaces
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 事件元数据，由总线在发布时填写，用于追踪一次操作引发的整串事件
+type EventMeta struct {
+	ID            uint64    // 事件ID，同一总线内唯一
+	Source        string    // 发布事件的模块ID，由模块上下文的总线自动填写；核心引擎发布的为 core
+	Time          time.Time // 发布时间，含单调时钟读数，同一进程内可直接比较先后、计算间隔
+	CorrelationID uint64    // 关联ID：由同一操作引发的事件相同，等于链条中第一个事件的ID
+	CausedBy      uint64    // 直接引发本事件的事件ID，链条中第一个事件为 0
+}
+
+// 元数据的简短描述，如 id=12 source=sysTray corr=10 cause=11
+func (m EventMeta) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "id=%d", m.ID)
+	if m.Source != "" {
+		fmt.Fprintf(&b, " source=%s", m.Source)
+	}
+	fmt.Fprintf(&b, " corr=%d", m.CorrelationID)
+	if m.CausedBy != 0 {
+		fmt.Fprintf(&b, " cause=%d", m.CausedBy)
+	}
+	return b.String()
+}
+
+// LogFields 返回用于结构化日志的键值对，如 ctx.Logger.With(evt.LogFields()...).Info("...")
+func (e Event) LogFields() []interface{} {
+	kv := []interface{}{"event", e.Name, "eventID", e.ID, "corr", e.CorrelationID}
+	if e.Source != "" {
+		kv = append(kv, "source", e.Source)
+	}
+	if e.CausedBy != 0 {
+		kv = append(kv, "cause", e.CausedBy)
+	}
+	return kv
+}
+
+// 事件名和元数据，用于日志
+func (e Event) String() string {
+	return e.Name + " " + e.EventMeta.String()
+}
+
+// 可选接口：发布时能继承起因事件关联ID的总线
+type CausalBus interface {
+	PublishCaused(cause EventMeta, event string, data interface{})
+	RequestCaused(ctx context.Context, cause EventMeta, event string, data interface{}) (interface{}, error)
+}
+
+// FollowUp 返回以本事件为起因的总线视图，处理函数通过它发布的后续事件和请求继承本事件的关联ID，
+// 如 modInterfaces.Publish(evt.FollowUp(m.ctx.Events), TopicDone, result)
+func (e Event) FollowUp(bus EventBus) EventBus {
+	return followBus{bus: bus, cause: e.EventMeta}
+}
+
+// 以某个事件为起因的总线视图
+type followBus struct {
+	bus   EventBus
+	cause EventMeta
+}
+
+func (b followBus) Subscribe(event string, handler func(Event), opts ...SubscribeOption) Subscription {
+	return b.bus.Subscribe(event, handler, opts...)
+}
+
+func (b followBus) Publish(event string, data interface{}) {
+	if cb, ok := b.bus.(CausalBus); ok {
+		cb.PublishCaused(b.cause, event, data)
+		return
+	}
+	b.bus.Publish(event, data)
+}
+
+func (b followBus) Request(ctx context.Context, event string, data interface{}) (interface{}, error) {
+	if cb, ok := b.bus.(CausalBus); ok {
+		return cb.RequestCaused(ctx, b.cause, event, data)
+	}
+	return b.bus.Request(ctx, event, data)
+}
+
+func (b followBus) Logf(level, format string, args ...interface{}) {
+	busLogf(b.bus, level, format, args...)
+}
+
+// 生成新事件的元数据：无起因时自身为链条起点，否则继承起因的关联ID
+func (bus *defaultEventBus) stamp(source string, cause *EventMeta) EventMeta {
+	meta := EventMeta{ID: bus.nextEventID.Add(1), Source: source, Time: time.Now()}
+	meta.CorrelationID = meta.ID
+	if cause != nil && cause.ID != 0 {
+		meta.CausedBy = cause.ID
+		meta.CorrelationID = cause.CorrelationID
+		if meta.CorrelationID == 0 {
+			meta.CorrelationID = cause.ID
+		}
+	}
+	return meta
+}
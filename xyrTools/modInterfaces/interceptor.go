@@ -0,0 +1,80 @@
+package modInterfaces
+
+import "errors"
+
+// 拦截器：包裹事件发布和处理函数调用，实现日志、统计、限流、发布权限等通用逻辑。
+// 拦截器按顺序组成链，前面的在外层；可在代码中通过 SetInterceptors 设置，
+// 也可在配置文件 eventBus 节中配置（见 interceptors.go 中的内置类型）。
+
+// 发布（或请求）的后续处理，返回错误表示事件未被投递
+type PublishFunc func(evt Event) error
+
+// 发布拦截器，调用 next 继续发布；不调用 next 并返回错误表示拒绝发布，
+// 普通事件被丢弃，请求返回该错误
+type PublishInterceptor func(evt Event, next PublishFunc) error
+
+// 订阅信息，供处理拦截器使用
+type HandlerInfo struct {
+	SubscriptionID uint64
+	Pattern        string // 订阅的事件名或通配符
+	Owner          string // 订阅所属模块
+}
+
+// 处理拦截器，调用 next 执行处理函数
+type HandlerInterceptor func(sub HandlerInfo, evt Event, next func(Event))
+
+// 拦截器，Publish、Handle 可只设置其中一个
+type Interceptor struct {
+	Name    string // 名称，用于日志
+	Publish PublishInterceptor
+	Handle  HandlerInterceptor
+}
+
+// 发布被拦截器或访问控制拒绝，拦截器返回的错误应包装它
+var ErrRejected = errors.New("eventbus: event rejected")
+
+// 设置拦截器链，替换原有的全部拦截器
+func (bus *defaultEventBus) SetInterceptors(ics ...Interceptor) {
+	chain := append([]Interceptor(nil), ics...)
+	bus.interceptors.Store(&chain)
+}
+
+func (bus *defaultEventBus) chain() []Interceptor {
+	if p := bus.interceptors.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// 经过发布拦截器链后执行 final
+func (bus *defaultEventBus) interceptPublish(evt Event, final PublishFunc) error {
+	next := final
+	chain := bus.chain()
+	for i := len(chain) - 1; i >= 0; i-- {
+		if chain[i].Publish == nil {
+			continue
+		}
+		ic, inner := chain[i].Publish, next
+		next = func(evt Event) error { return ic(evt, inner) }
+	}
+	return next(evt)
+}
+
+// 经过处理拦截器链后执行处理函数
+func (s *subscription) interceptHandle(evt Event) {
+	next := s.handler
+	chain := s.bus.chain()
+	if len(chain) == 0 {
+		next(evt)
+		return
+	}
+	info := HandlerInfo{SubscriptionID: s.id, Pattern: s.event, Owner: s.owner}
+	for i := len(chain) - 1; i >= 0; i-- {
+		if chain[i].Handle == nil {
+			continue
+		}
+		ic, inner := chain[i].Handle, next
+		next = func(evt Event) { ic(info, evt, inner) }
+	}
+	next(evt)
+}
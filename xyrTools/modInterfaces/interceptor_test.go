@@ -0,0 +1,114 @@
+package modInterfaces
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func mustInterceptor(t *testing.T, typ string, cfg map[string]interface{}, env InterceptorEnv) Interceptor {
+	t.Helper()
+	ic, err := NewInterceptor(typ, cfg, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ic
+}
+
+// 拦截器按顺序执行，前面的在外层
+func TestInterceptorOrder(t *testing.T) {
+	bus := NewEventBus()
+	var lock sync.Mutex
+	var calls []string
+	record := func(s string) {
+		lock.Lock()
+		calls = append(calls, s)
+		lock.Unlock()
+	}
+	wrap := func(name string) Interceptor {
+		return Interceptor{
+			Name: name,
+			Publish: func(evt Event, next PublishFunc) error {
+				record(name + ":publish")
+				return next(evt)
+			},
+			Handle: func(sub HandlerInfo, evt Event, next func(Event)) {
+				record(name + ":handle")
+				next(evt)
+			},
+		}
+	}
+	bus.SetInterceptors(wrap("a"), wrap("b"))
+	bus.Subscribe("e", func(Event) { record("handler") }, WithSync())
+	bus.Publish("e", nil)
+
+	want := "a:publish b:publish a:handle b:handle handler"
+	if got := strings.Join(calls, " "); got != want {
+		t.Fatalf("calls = %q, want %q", got, want)
+	}
+}
+
+func TestDenyInterceptor(t *testing.T) {
+	bus := NewEventBus()
+	var logs atomic.Int64
+	env := InterceptorEnv{Log: func(level, msg string) { logs.Add(1) }}
+	bus.SetInterceptors(mustInterceptor(t, "deny", map[string]interface{}{"source": "fileMonitor", "events": "core:**"}, env))
+	var got atomic.Int64
+	bus.Subscribe("core:**", func(Event) { got.Add(1) }, WithSync())
+	bus.Subscribe("core:ping", func(evt Event) { evt.Respond("pong", nil) })
+
+	bus.Owner("fileMonitor").Publish("core:moduleStarted", nil)
+	bus.Owner("memopt").Publish("core:moduleStarted", nil)
+	if got.Load() != 1 {
+		t.Fatalf("delivered %d events, want 1", got.Load())
+	}
+	_, err := bus.Owner("fileMonitor").Request(context.Background(), "core:ping", nil)
+	if !errors.Is(err, ErrRejected) {
+		t.Fatalf("Request = %v, want ErrRejected", err)
+	}
+	if logs.Load() != 2 {
+		t.Fatalf("logged %d denials, want 2", logs.Load())
+	}
+}
+
+func TestRateLimitInterceptor(t *testing.T) {
+	bus := NewEventBus()
+	metrics := NewEventMetrics()
+	env := InterceptorEnv{Metrics: metrics}
+	bus.SetInterceptors(
+		mustInterceptor(t, "metrics", nil, env),
+		mustInterceptor(t, "rateLimit", map[string]interface{}{"events": "fileMonitor:*", "rate": 0.001, "burst": 3}, env),
+	)
+	var got atomic.Int64
+	bus.Subscribe("**", func(Event) { got.Add(1) }, WithSync())
+	for i := 0; i < 10; i++ {
+		bus.Publish("fileMonitor:changed", i)
+		bus.Publish("other:event", i)
+	}
+	// 每个事件名单独计算，未匹配的事件不限流
+	if got.Load() != 13 {
+		t.Fatalf("delivered %d events, want 13", got.Load())
+	}
+	stats := metrics.Snapshot()
+	if s := stats["fileMonitor:changed"]; s.Published != 3 || s.Rejected != 7 || s.Handled != 3 {
+		t.Fatalf("fileMonitor:changed stats %+v", s)
+	}
+	if s := stats["other:event"]; s.Published != 10 || s.Handled != 10 {
+		t.Fatalf("other:event stats %+v", s)
+	}
+}
+
+func TestNewInterceptorErrors(t *testing.T) {
+	if _, err := NewInterceptor("nope", nil, InterceptorEnv{}); err == nil {
+		t.Fatal("unknown type accepted")
+	}
+	if _, err := NewInterceptor("rateLimit", map[string]interface{}{"events": "a"}, InterceptorEnv{}); err == nil {
+		t.Fatal("rateLimit without rate accepted")
+	}
+	if _, err := NewInterceptor("deny", map[string]interface{}{"source": "a", "typo": 1}, InterceptorEnv{}); err == nil {
+		t.Fatal("unknown key accepted")
+	}
+}
@@ -0,0 +1,312 @@
+package modInterfaces
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 内置拦截器和拦截器类型注册表。配置文件 eventBus 节按顺序列出拦截器，例如：
+//
+//	eventBus:
+//	  interceptors:
+//	    - type: deny        # 禁止模块发布某些事件
+//	      source: fileMonitor
+//	      events: core:**
+//	    - type: rateLimit   # 按事件名限流，超出的事件被丢弃
+//	      events: fileMonitor:*
+//	      rate: 20
+//	      burst: 50
+//	    - type: log         # 记录发布（和处理函数调用）
+//	      events: "**"
+//	      level: debug
+//	    - type: metrics     # 统计发布、处理次数和耗时
+//
+// 每个拦截器只处理 events 匹配的事件，其余事件直接放行。
+
+// 拦截器工厂，cfg 为配置文件中该拦截器的配置（不含 type）
+type InterceptorFactory func(cfg map[string]interface{}, env InterceptorEnv) (Interceptor, error)
+
+// 创建拦截器时可使用的环境
+type InterceptorEnv struct {
+	Log     func(level, msg string) // 总线日志
+	Metrics *EventMetrics           // metrics 拦截器写入的统计，为 nil 时 metrics 拦截器不可用
+}
+
+var (
+	interceptorTypes = make(map[string]InterceptorFactory)
+	interceptorLock  sync.RWMutex
+)
+
+func init() {
+	RegisterInterceptor("log", newLogInterceptor)
+	RegisterInterceptor("metrics", newMetricsInterceptor)
+	RegisterInterceptor("rateLimit", newRateLimitInterceptor)
+	RegisterInterceptor("deny", newDenyInterceptor)
+}
+
+// RegisterInterceptor 注册拦截器类型，供配置文件使用。应在 init() 中调用，重复注册直接 panic
+func RegisterInterceptor(typ string, factory InterceptorFactory) {
+	interceptorLock.Lock()
+	defer interceptorLock.Unlock()
+	if factory == nil {
+		panic(fmt.Sprintf("eventbus: interceptor factory %s is nil", typ))
+	}
+	if _, exists := interceptorTypes[typ]; exists {
+		panic(fmt.Sprintf("eventbus: interceptor %s registered twice", typ))
+	}
+	interceptorTypes[typ] = factory
+}
+
+// InterceptorTypes 返回已注册的拦截器类型（已排序）
+func InterceptorTypes() []string {
+	interceptorLock.RLock()
+	defer interceptorLock.RUnlock()
+	types := make([]string, 0, len(interceptorTypes))
+	for typ := range interceptorTypes {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// NewInterceptor 按类型和配置创建拦截器
+func NewInterceptor(typ string, cfg map[string]interface{}, env InterceptorEnv) (Interceptor, error) {
+	interceptorLock.RLock()
+	factory, ok := interceptorTypes[typ]
+	interceptorLock.RUnlock()
+	if !ok {
+		return Interceptor{}, fmt.Errorf("unknown interceptor type %q", typ)
+	}
+	if env.Log == nil {
+		env.Log = func(string, string) {}
+	}
+	ic, err := factory(cfg, env)
+	if err != nil {
+		return Interceptor{}, fmt.Errorf("interceptor %s: %w", typ, err)
+	}
+	if ic.Name == "" {
+		ic.Name = typ
+	}
+	return ic, nil
+}
+
+// --- log：记录发布和处理函数调用 ---
+
+type logInterceptorConfig struct {
+	Events   string `yaml:"events" default:"**" desc:"记录的事件名或通配符"`
+	Level    string `yaml:"level" default:"debug" desc:"日志级别：debug、info、warn、error"`
+	Handlers bool   `yaml:"handlers" default:"false" desc:"是否同时记录每次处理函数调用及耗时"`
+}
+
+func newLogInterceptor(cfg map[string]interface{}, env InterceptorEnv) (Interceptor, error) {
+	var c logInterceptorConfig
+	if err := DecodeConfig(cfg, &c); err != nil {
+		return Interceptor{}, err
+	}
+	ic := Interceptor{
+		Publish: func(evt Event, next PublishFunc) error {
+			if !MatchTopic(c.Events, evt.Name) {
+				return next(evt)
+			}
+			err := next(evt)
+			if err != nil {
+				env.Log(c.Level, fmt.Sprintf("Publish %s not delivered: %v", evt, err))
+			} else {
+				env.Log(c.Level, fmt.Sprintf("Publish %s", evt))
+			}
+			return err
+		},
+	}
+	if c.Handlers {
+		ic.Handle = func(sub HandlerInfo, evt Event, next func(Event)) {
+			if !MatchTopic(c.Events, evt.Name) {
+				next(evt)
+				return
+			}
+			start := time.Now()
+			next(evt)
+			env.Log(c.Level, fmt.Sprintf("Handled %s by subscription %d (%s, owner %q) in %s",
+				evt, sub.SubscriptionID, sub.Pattern, sub.Owner, time.Since(start)))
+		}
+	}
+	return ic, nil
+}
+
+// --- metrics：按事件名统计 ---
+
+// 一个事件名的统计
+type EventStats struct {
+	Published     uint64        // 成功投递的发布（含请求）次数
+	Rejected      uint64        // 被拦截器拒绝或没有处理函数的次数
+	Handled       uint64        // 处理函数调用次数
+	HandleTime    time.Duration // 处理函数总耗时
+	MaxHandleTime time.Duration // 处理函数最长耗时
+}
+
+// 事件统计，配置变更重建拦截器链后统计不清零
+type EventMetrics struct {
+	lock  sync.Mutex
+	stats map[string]*EventStats
+}
+
+func NewEventMetrics() *EventMetrics {
+	return &EventMetrics{stats: make(map[string]*EventStats)}
+}
+
+// Snapshot 返回当前统计的副本，键为事件名
+func (m *EventMetrics) Snapshot() map[string]EventStats {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	out := make(map[string]EventStats, len(m.stats))
+	for name, s := range m.stats {
+		out[name] = *s
+	}
+	return out
+}
+
+func (m *EventMetrics) update(name string, fn func(s *EventStats)) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	s, ok := m.stats[name]
+	if !ok {
+		s = &EventStats{}
+		m.stats[name] = s
+	}
+	fn(s)
+}
+
+type metricsInterceptorConfig struct {
+	Events string `yaml:"events" default:"**" desc:"统计的事件名或通配符"`
+}
+
+func newMetricsInterceptor(cfg map[string]interface{}, env InterceptorEnv) (Interceptor, error) {
+	var c metricsInterceptorConfig
+	if err := DecodeConfig(cfg, &c); err != nil {
+		return Interceptor{}, err
+	}
+	m := env.Metrics
+	if m == nil {
+		return Interceptor{}, fmt.Errorf("no metrics store")
+	}
+	return Interceptor{
+		Publish: func(evt Event, next PublishFunc) error {
+			err := next(evt)
+			if MatchTopic(c.Events, evt.Name) {
+				m.update(evt.Name, func(s *EventStats) {
+					if err != nil {
+						s.Rejected++
+					} else {
+						s.Published++
+					}
+				})
+			}
+			return err
+		},
+		Handle: func(sub HandlerInfo, evt Event, next func(Event)) {
+			if !MatchTopic(c.Events, evt.Name) {
+				next(evt)
+				return
+			}
+			start := time.Now()
+			// 处理函数 panic 时也计入
+			defer func() {
+				d := time.Since(start)
+				m.update(evt.Name, func(s *EventStats) {
+					s.Handled++
+					s.HandleTime += d
+					if d > s.MaxHandleTime {
+						s.MaxHandleTime = d
+					}
+				})
+			}()
+			next(evt)
+		},
+	}, nil
+}
+
+// --- rateLimit：按事件名限流 ---
+
+type rateLimitConfig struct {
+	Events string  `yaml:"events" default:"**" desc:"限流的事件名或通配符，每个事件名单独计算"`
+	Rate   float64 `yaml:"rate" required:"true" min:"0.001" desc:"每个事件名每秒允许发布的次数"`
+	Burst  int     `yaml:"burst" default:"0" min:"0" desc:"允许的突发次数，0 表示等于 rate（向上取整）"`
+}
+
+// 令牌桶
+type tokenBucket struct {
+	tokens  float64
+	last    time.Time
+	dropped uint64
+}
+
+func newRateLimitInterceptor(cfg map[string]interface{}, env InterceptorEnv) (Interceptor, error) {
+	var c rateLimitConfig
+	if err := DecodeConfig(cfg, &c); err != nil {
+		return Interceptor{}, err
+	}
+	burst := float64(c.Burst)
+	if burst == 0 {
+		burst = math.Ceil(c.Rate)
+	}
+	var lock sync.Mutex
+	buckets := make(map[string]*tokenBucket)
+	allow := func(name string, now time.Time) (bool, uint64) {
+		lock.Lock()
+		defer lock.Unlock()
+		b, ok := buckets[name]
+		if !ok {
+			b = &tokenBucket{tokens: burst, last: now}
+			buckets[name] = b
+		}
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*c.Rate)
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			return true, 0
+		}
+		b.dropped++
+		return false, b.dropped
+	}
+	return Interceptor{
+		Publish: func(evt Event, next PublishFunc) error {
+			if !MatchTopic(c.Events, evt.Name) {
+				return next(evt)
+			}
+			ok, dropped := allow(evt.Name, time.Now())
+			if ok {
+				return next(evt)
+			}
+			// 首次丢弃和之后每 1000 次记录一次日志，避免刷屏
+			if dropped == 1 || dropped%1000 == 0 {
+				env.Log("warn", fmt.Sprintf("Event %s exceeds the rate limit of %g/s, %d events dropped so far", evt, c.Rate, dropped))
+			}
+			return fmt.Errorf("%w: rate limit of %s exceeded", ErrRejected, evt.Name)
+		},
+	}, nil
+}
+
+// --- deny：禁止模块发布事件 ---
+
+type denyConfig struct {
+	Source string `yaml:"source" required:"true" desc:"禁止发布的模块ID，* 表示所有模块"`
+	Events string `yaml:"events" default:"**" desc:"禁止发布的事件名或通配符"`
+}
+
+func newDenyInterceptor(cfg map[string]interface{}, env InterceptorEnv) (Interceptor, error) {
+	var c denyConfig
+	if err := DecodeConfig(cfg, &c); err != nil {
+		return Interceptor{}, err
+	}
+	return Interceptor{
+		Publish: func(evt Event, next PublishFunc) error {
+			if (c.Source != "*" && evt.Source != c.Source) || !MatchTopic(c.Events, evt.Name) {
+				return next(evt)
+			}
+			env.Log("warn", fmt.Sprintf("Event %s denied: module %s may not publish %s", evt, evt.Source, c.Events))
+			return fmt.Errorf("%w: module %s may not publish %s", ErrRejected, evt.Source, evt.Name)
+		},
+	}, nil
+}
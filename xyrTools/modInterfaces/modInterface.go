@@ -1,9 +1,13 @@
 package modInterfaces
 
 import (
+	"context"
 	"fmt"
+	"runtime/debug"
 	"sync"
 	"time"
+
+	"myMod/xlog"
 )
 
 // --- 模块封装体（注册后管理状态） ---
@@ -22,100 +26,110 @@ type Module interface {
 	Version() string     // 版本号
 	Author() string      // 作者信息
 
-	Init(ctx Context) error // 初始化模块，注入上下文
-	Start() error           // 启动模块运行逻辑
-	Stop() error            // 停止模块运行逻辑
-	Status() ModuleStatus   // 获取当前状态
+	Init(ctx Context) error         // 初始化模块，注入上下文
+	Start() error                   // 启动模块运行逻辑
+	Stop(ctx context.Context) error // 停止模块运行逻辑，ctx 到期（停止超时）后应尽快返回
+	Status() ModuleStatus           // 获取当前状态
 
-	Reload() error // 重新加载
+	Reload(ctx Context) error // 重新加载，注入新的上下文（配置变更时由引擎调用），模块运行中时应按新配置重启
 }
 
-// 模块上下文信息（启动时注入）
-type Context struct {
-	Config map[string]interface{}         // 模块独立配置
-	Log    func(level string, msg string) // 日志函数
-	Events EventBus                       // 模块间通信事件总线
+// 可选接口：声明模块依赖
+// 实现该接口的模块会在其依赖模块启动之后再启动，并在依赖模块停止之前停止
+type DependentModule interface {
+	Dependencies() []string // 依赖的模块ID列表（与配置文件中的模块名一致）
 }
 
-// --- 模块运行状态 ---
-type ModuleStatus struct {
-	Running   bool
-	LastError error
-	StartTime time.Time
-	EndTime   time.Time
+// 可选接口：声明模块发布（含请求）和订阅的事件名或通配符。
+// 事件总线开启访问控制（配置文件 eventBus 节的 acl）后，模块只能发布、订阅声明过的事件；
+// 模块配置中的 publishes、subscribes 会覆盖对应的声明。两者都返回 nil 表示未声明，不受限制
+type EventDeclarer interface {
+	PublishesEvents() []string
+	SubscribesEvents() []string
 }
 
-// --- 事件结构体 ---
-type Event struct {
-	Name string
-	Data interface{}
+// 可选接口：启动前的准备，如拉起外部模块进程并完成握手。
+// 引擎每次调用 Start 前先调用 Prepare，成功后按模块此时的事件声明重新设置访问控制
+type Preparer interface {
+	Prepare() error
 }
 
-// --- 事件总线接口 ---
-type EventBus interface {
-	Subscribe(event string, handler func(Event))   // 订阅事件
-	Unsubscribe(event string, handler func(Event)) // 取消订阅
-	Publish(event string, data interface{})        // 发布事件
+// 模块上下文信息（启动时注入）
+type Context struct {
+	Config   map[string]interface{}         // 模块独立配置
+	Log      func(level string, msg string) // 日志函数，已带模块ID并按模块日志级别过滤
+	Logger   *xlog.Logger                   // 结构化日志，如 ctx.Logger.Info("msg", "key", value)，未注入时调用无效果
+	Events   EventBus                       // 模块间通信事件总线，发布的事件自动带上模块ID；模块停止时引擎会取消其全部订阅，订阅应在 Start 中进行
+	Failures FailureReporter                // 异步故障上报，由核心引擎的监管器注入
 }
 
-// --- 默认事件总线实现 ---
-type defaultEventBus struct {
-	subscribers map[string][]func(Event)
-	lock        sync.RWMutex
+// 模块异步故障上报接口，核心引擎据此记录故障并按重启策略重启模块
+type FailureReporter interface {
+	ReportFailure(err error, stack string)
 }
 
-func NewEventBus() EventBus {
-	return &defaultEventBus{
-		subscribers: make(map[string][]func(Event)),
+// ReportError 上报模块运行中出现的异步错误（Start 返回之后发生的、导致模块无法继续工作的错误）
+func (c Context) ReportError(err error) {
+	if err == nil {
+		return
+	}
+	if c.Failures != nil {
+		c.Failures.ReportFailure(err, "")
+		return
+	}
+	if c.Log != nil {
+		c.Log("error", fmt.Sprintf("Module failure: %v", err))
 	}
 }
 
-func (bus *defaultEventBus) Subscribe(event string, handler func(Event)) {
-	bus.lock.Lock()
-	defer bus.lock.Unlock()
-	bus.subscribers[event] = append(bus.subscribers[event], handler)
+// Go 启动受监管的协程，协程内的 panic 会被捕获并连同调用栈一起上报，不会导致整个进程退出
+func (c Context) Go(fn func()) {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err := fmt.Errorf("panic: %v", r)
+				if c.Failures != nil {
+					c.Failures.ReportFailure(err, string(debug.Stack()))
+				} else if c.Log != nil {
+					c.Log("error", fmt.Sprintf("Module goroutine %v\n%s", err, debug.Stack()))
+				}
+			}
+		}()
+		fn()
+	}()
 }
 
-func (bus *defaultEventBus) Unsubscribe(event string, handler func(Event)) {
-	bus.lock.Lock()
-	defer bus.lock.Unlock()
-	handlers := bus.subscribers[event]
-	for i, h := range handlers {
-		if &h == &handler {
-			// 从订阅者列表中移除指定的 handler
-			bus.subscribers[event] = append(handlers[:i], handlers[i+1:]...)
-			break
-		}
-	}
+// --- 模块运行状态 ---
+type ModuleStatus struct {
+	Running    bool
+	LastError  error
+	StartTime  time.Time
+	EndTime    time.Time
+	Skipped    bool   // 因依赖模块未能启动而被跳过
+	SkipReason string // 跳过原因
+
+	Restarts       int       // 监管器自动重启次数
+	LastCrashTime  time.Time // 最近一次异常退出时间
+	LastCrashStack string    // 最近一次 panic 的调用栈
+
+	HandlerFailures uint64 // 本次启动以来事件处理函数失败（panic 或上报错误）的次数
 }
 
-// Publish 方法用于发布一个事件，会触发所有订阅该事件的处理函数。
-// event 为要发布的事件名称。
-// data 为事件携带的数据，可传递任意类型的数据。
-func (bus *defaultEventBus) Publish(event string, data interface{}) {
-	// 使用读锁，允许多个 goroutine 同时读取订阅者列表，提高并发性能
-	bus.lock.RLock()
-	// 获取订阅该事件的所有处理函数
-	handlers := bus.subscribers[event]
-	// 释放读锁
-	bus.lock.RUnlock()
-
-	// 遍历所有订阅该事件的处理函数
-	for _, handler := range handlers {
-		// 为每个处理函数启动一个新的 goroutine 来执行，避免阻塞当前 goroutine
-		go func(h func(Event)) {
-			// 使用 defer 和 recover 捕获可能出现的 panic，防止一个处理函数的 panic 影响其他处理函数
-			defer func() {
-				if r := recover(); r != nil {
-					// log打印错误信息
-					logFunc := func(level, msg string) {
-						fmt.Printf("[%s] %s\n", level, msg)
-					}
-					logFunc("error", fmt.Sprintf("Event handler panic: %v", r))
-				}
-			}()
-			// 调用处理函数，传入包含事件名称和数据的 Event 结构体
-			h(Event{Name: event, Data: data})
-		}(handler)
-	}
+// --- 模块信息（供托盘、命令行等查询模块列表） ---
+type ModuleInfo struct {
+	ID           string
+	Name         string
+	Description  string
+	Version      string
+	Author       string
+	Enabled      bool          // 配置文件中是否开启
+	Dependencies []string      // 依赖的模块ID
+	ConfigSchema []ConfigField // 模块配置说明，模块未实现 ConfigSchemaProvider 时为空
+	Status       ModuleStatus
+}
+
+// --- 模块生命周期事件数据（core:moduleStarted 等事件携带） ---
+type ModuleLifecycle struct {
+	ID    string // 模块ID
+	Error string // 失败原因，成功时为空
 }
@@ -0,0 +1,40 @@
+package modInterfaces
+
+import (
+	"context"
+	"sync"
+)
+
+// 请求退出程序的事件，数据为 ShutdownRequest。核心引擎收到后按依赖逆序停止所有模块，再由主程序退出。
+const EventShutdownRequested = "core:shutdownRequested"
+
+// 退出请求
+type ShutdownRequest struct {
+	Source string // 发起退出的模块ID
+	Reason string // 退出原因
+}
+
+var TopicShutdownRequested = DeclareEvent[ShutdownRequest](EventShutdownRequested, "请求退出程序，核心引擎停止所有模块后退出")
+
+// RequestShutdown 请求退出程序，模块不应自行调用 os.Exit
+func (c Context) RequestShutdown(source, reason string) {
+	if c.Events == nil {
+		return
+	}
+	Publish(c.Events, TopicShutdownRequested, ShutdownRequest{Source: source, Reason: reason})
+}
+
+// WaitContext 等待 wg 完成，ctx 先到期时返回 ctx.Err()，用于 Stop 中等待模块协程退出
+func WaitContext(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,135 @@
+package modInterfaces
+
+import "strings"
+
+// 事件名按 ':' 分段，如 memory:optimized。订阅时可使用通配符：
+//
+//   - 匹配一段，如 memory:* 匹配 memory:optimized，*:alert 匹配 fileMonitor:alert
+//     **  只能作为最后一段，匹配之后的一段或多段，如 core:** 匹配 core:moduleStarted；单独的 ** 匹配所有事件
+//
+// 不含通配符的订阅按事件名精确匹配。不在末尾的 ** 按 * 处理。
+const (
+	topicSeparator  = ":"
+	wildcardOne     = "*"
+	wildcardSegment = "**"
+)
+
+// 是否为通配符订阅
+func isPattern(event string) bool {
+	return strings.Contains(event, wildcardOne)
+}
+
+// MatchTopic 判断事件名是否匹配订阅的事件名或通配符，规则与订阅相同
+func MatchTopic(pattern, event string) bool {
+	if !isPattern(pattern) {
+		return pattern == event
+	}
+	return matchSegments(strings.Split(pattern, topicSeparator), strings.Split(event, topicSeparator))
+}
+
+func matchSegments(pattern, segs []string) bool {
+	for i, p := range pattern {
+		if p == wildcardSegment && i == len(pattern)-1 {
+			return len(segs) > i
+		}
+		if i >= len(segs) || (p != wildcardOne && p != wildcardSegment && p != segs[i]) {
+			return false
+		}
+	}
+	return len(pattern) == len(segs)
+}
+
+// 通配符订阅前缀树，每层对应事件名的一段，匹配耗时与订阅数量无关，只与事件名段数有关
+type topicNode struct {
+	children map[string]*topicNode // 普通段
+	any      *topicNode            // * 段
+	rest     []*subscription       // 以 ** 结尾的订阅
+	subs     []*subscription       // 在此结束的订阅
+}
+
+func newTopicNode() *topicNode {
+	return &topicNode{children: make(map[string]*topicNode)}
+}
+
+// 插入通配符订阅
+func (n *topicNode) insert(sub *subscription) {
+	segs := strings.Split(sub.event, topicSeparator)
+	node := n
+	for i, seg := range segs {
+		if seg == wildcardSegment && i == len(segs)-1 {
+			node.rest = append(node.rest, sub)
+			return
+		}
+		if seg == wildcardOne || seg == wildcardSegment {
+			if node.any == nil {
+				node.any = newTopicNode()
+			}
+			node = node.any
+			continue
+		}
+		child, ok := node.children[seg]
+		if !ok {
+			child = newTopicNode()
+			node.children[seg] = child
+		}
+		node = child
+	}
+	node.subs = append(node.subs, sub)
+}
+
+// 删除通配符订阅，并清理空节点。返回节点是否已空。
+func (n *topicNode) remove(sub *subscription, segs []string) bool {
+	if len(segs) == 0 {
+		n.subs = without(n.subs, sub)
+		return n.empty()
+	}
+	seg := segs[0]
+	if seg == wildcardSegment && len(segs) == 1 {
+		n.rest = without(n.rest, sub)
+		return n.empty()
+	}
+	if seg == wildcardOne || seg == wildcardSegment {
+		if n.any != nil && n.any.remove(sub, segs[1:]) {
+			n.any = nil
+		}
+		return n.empty()
+	}
+	if child, ok := n.children[seg]; ok && child.remove(sub, segs[1:]) {
+		delete(n.children, seg)
+	}
+	return n.empty()
+}
+
+func (n *topicNode) empty() bool {
+	return len(n.children) == 0 && n.any == nil && len(n.rest) == 0 && len(n.subs) == 0
+}
+
+// 收集匹配事件名各段的订阅
+func (n *topicNode) match(segs []string, out []*subscription) []*subscription {
+	if len(segs) == 0 {
+		return append(out, n.subs...)
+	}
+	out = append(out, n.rest...)
+	if child, ok := n.children[segs[0]]; ok {
+		out = child.match(segs[1:], out)
+	}
+	if n.any != nil {
+		out = n.any.match(segs[1:], out)
+	}
+	return out
+}
+
+// 返回去掉 sub 后的新切片，不修改原切片（Publish 可能正在使用）
+func without(subs []*subscription, sub *subscription) []*subscription {
+	for i, s := range subs {
+		if s == sub {
+			if len(subs) == 1 {
+				return nil
+			}
+			rest := make([]*subscription, 0, len(subs)-1)
+			rest = append(rest, subs[:i]...)
+			return append(rest, subs[i+1:]...)
+		}
+	}
+	return subs
+}
@@ -0,0 +1,396 @@
+// 事件总线桥接模块，通过本地套接字（Linux 为 Unix 套接字，Windows 为命名管道）向脚本等本机程序开放事件总线：
+// 客户端认证后可以发布事件、发送请求、按事件名或通配符订阅事件。协议见 protocol.go。
+package bridge
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"xyrTools/xyrTools/modInterfaces"
+	"xyrTools/xyrTools/registry"
+)
+
+func init() {
+	registry.Register("bridge", New)
+}
+
+// --- 模块配置 ---
+type bridgeConfig struct {
+	Address    string   `yaml:"address" desc:"监听地址：Linux 为 Unix 套接字路径（默认 xyrTools.sock），Windows 为命名管道（默认 \\\\.\\pipe\\xyrToolsBus）"`
+	Token      string   `yaml:"token" desc:"认证令牌，为空时使用 tokenFile 中的令牌，文件不存在时自动生成"`
+	TokenFile  string   `yaml:"tokenFile" default:"bridge.token" desc:"令牌文件，只有当前用户可读，客户端从中读取令牌"`
+	Publish    []string `yaml:"publish" default:"" desc:"允许客户端发布（含请求）的事件名或通配符，默认不允许"`
+	Subscribe  []string `yaml:"subscribe" default:"**" desc:"允许客户端订阅的事件名或通配符"`
+	MaxClients int      `yaml:"maxClients" default:"16" min:"1" desc:"最大同时连接的客户端数"`
+	QueueSize  int      `yaml:"queueSize" default:"1024" min:"1" desc:"每个订阅待发送事件的队列长度，客户端读取太慢时丢弃最早的事件"`
+}
+
+const (
+	authTimeout  = 5 * time.Second  // 连接后必须在该时间内完成认证
+	writeTimeout = 5 * time.Second  // 向客户端写一条消息的超时时间，超时断开连接
+	maxLineSize  = 16 << 20         // 客户端单条消息的最大长度
+	maxTimeout   = 10 * time.Minute // 客户端请求的最长超时时间
+)
+
+type BridgeModule struct {
+	status modInterfaces.ModuleStatus
+	ctx    modInterfaces.Context
+	cfg    bridgeConfig
+	token  string
+	wg     sync.WaitGroup
+
+	lock     sync.Mutex
+	ln       net.Listener
+	clients  map[*client]struct{}
+	stopping bool
+}
+
+func New() modInterfaces.Module {
+	return &BridgeModule{}
+}
+
+func (m *BridgeModule) ID() string   { return "bridge" }
+func (m *BridgeModule) Name() string { return "事件总线桥接模块" }
+func (m *BridgeModule) Description() string {
+	return "通过本地套接字向脚本等本机程序开放事件总线"
+}
+func (m *BridgeModule) Version() string { return "1.0.0" }
+func (m *BridgeModule) Author() string  { return "小鱼" }
+
+// 事件声明，供事件总线访问控制使用，与客户端可发布、订阅的事件一致
+func (m *BridgeModule) PublishesEvents() []string  { return m.cfg.Publish }
+func (m *BridgeModule) SubscribesEvents() []string { return m.cfg.Subscribe }
+
+func (m *BridgeModule) Init(ctx modInterfaces.Context) error {
+	if err := ctx.DecodeConfig(&m.cfg); err != nil {
+		return err
+	}
+	m.ctx = ctx
+	m.ctx.Log("info", "事件总线桥接模块已初始化")
+	return nil
+}
+
+func (m *BridgeModule) Start() error {
+	token, err := m.loadToken()
+	if err != nil {
+		return err
+	}
+	address := m.cfg.Address
+	if address == "" {
+		address = defaultAddress
+	}
+	ln, err := listen(address)
+	if err != nil {
+		return fmt.Errorf("监听 %s 失败: %w", address, err)
+	}
+	m.token = token
+	m.lock.Lock()
+	m.ln = ln
+	m.clients = make(map[*client]struct{})
+	m.stopping = false
+	m.lock.Unlock()
+	m.status.Running = true
+	m.status.StartTime = time.Now()
+
+	m.wg.Add(1)
+	m.ctx.Go(func() {
+		defer m.wg.Done()
+		m.accept(ln)
+	})
+	m.ctx.Log("info", "事件总线桥接模块启动，监听: "+address)
+	return nil
+}
+
+func (m *BridgeModule) Stop(ctx context.Context) error {
+	if !m.status.Running {
+		return nil
+	}
+	m.status.Running = false
+	m.status.EndTime = time.Now()
+	m.lock.Lock()
+	m.stopping = true
+	err := m.ln.Close()
+	for c := range m.clients {
+		c.conn.Close()
+	}
+	m.lock.Unlock()
+	if waitErr := modInterfaces.WaitContext(ctx, &m.wg); waitErr != nil {
+		return waitErr
+	}
+	return err
+}
+
+func (m *BridgeModule) Status() modInterfaces.ModuleStatus {
+	return m.status
+}
+
+func (m *BridgeModule) ConfigSchema() []modInterfaces.ConfigField {
+	return modInterfaces.ConfigSchema(bridgeConfig{})
+}
+
+func (m *BridgeModule) Reload(ctx modInterfaces.Context) error {
+	var cfg bridgeConfig
+	if err := ctx.DecodeConfig(&cfg); err != nil {
+		return err
+	}
+	m.cfg = cfg
+	m.ctx = ctx
+	m.ctx.Log("info", "事件总线桥接模块重新加载配置")
+	if !m.status.Running {
+		return nil
+	}
+	_ = m.Stop(context.Background())
+	return m.Start()
+}
+
+// 读取认证令牌：优先使用配置的令牌，其次为令牌文件，都没有时生成新令牌并写入令牌文件
+func (m *BridgeModule) loadToken() (string, error) {
+	if m.cfg.Token != "" {
+		return m.cfg.Token, nil
+	}
+	if data, err := os.ReadFile(m.cfg.TokenFile); err == nil {
+		if token := strings.TrimSpace(string(data)); token != "" {
+			return token, nil
+		}
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成认证令牌失败: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+	if dir := filepath.Dir(m.cfg.TokenFile); dir != "." {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return "", fmt.Errorf("保存认证令牌失败: %w", err)
+		}
+	}
+	if err := os.WriteFile(m.cfg.TokenFile, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("保存认证令牌失败: %w", err)
+	}
+	m.ctx.Log("info", "已生成认证令牌，保存在: "+m.cfg.TokenFile)
+	return token, nil
+}
+
+// 客户端可以发布、订阅的事件
+func (m *BridgeModule) acl() modInterfaces.EventACL {
+	return modInterfaces.EventACL{Publish: m.cfg.Publish, Subscribe: m.cfg.Subscribe}
+}
+
+// 接受客户端连接，监听关闭后返回
+func (m *BridgeModule) accept(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			m.lock.Lock()
+			stopping := m.stopping
+			m.lock.Unlock()
+			if stopping {
+				return
+			}
+			m.ctx.Log("warn", "接受客户端连接失败: "+err.Error())
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		c := newClient(m, conn)
+		m.lock.Lock()
+		full := len(m.clients) >= m.cfg.MaxClients
+		if !full && !m.stopping {
+			m.clients[c] = struct{}{}
+		}
+		stopping := m.stopping
+		m.lock.Unlock()
+		if full || stopping {
+			_ = c.send(errReply(request{}, errors.New("too many clients")))
+			conn.Close()
+			continue
+		}
+		m.wg.Add(1)
+		m.ctx.Go(func() {
+			defer m.wg.Done()
+			c.serve()
+		})
+	}
+}
+
+// --- 客户端连接 ---
+type client struct {
+	m      *BridgeModule
+	conn   net.Conn
+	ctx    context.Context // 连接断开时取消，结束等待中的请求
+	cancel context.CancelFunc
+	wLock  sync.Mutex
+
+	lock    sync.Mutex
+	nextSub uint64
+	subs    map[uint64]modInterfaces.Subscription
+}
+
+func newClient(m *BridgeModule, conn net.Conn) *client {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &client{m: m, conn: conn, ctx: ctx, cancel: cancel, subs: make(map[uint64]modInterfaces.Subscription)}
+}
+
+// 写一条消息
+func (c *client) send(resp response) error {
+	line, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	return c.write(line)
+}
+
+// 写一行，写入失败（包括超时）说明连接已不可用
+func (c *client) write(line []byte) error {
+	line = append(line, '\n')
+	c.wLock.Lock()
+	defer c.wLock.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err := c.conn.Write(line)
+	return err
+}
+
+// 处理客户端消息直到连接断开
+func (c *client) serve() {
+	defer c.close()
+	_ = c.conn.SetReadDeadline(time.Now().Add(authTimeout))
+	scanner := bufio.NewScanner(c.conn)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	authed := false
+	for scanner.Scan() {
+		var req request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			if c.send(errReply(req, fmt.Errorf("invalid message: %w", err))) != nil {
+				return
+			}
+			continue
+		}
+		if !authed {
+			if req.Op != "auth" || subtle.ConstantTimeCompare([]byte(req.Token), []byte(c.m.token)) != 1 {
+				c.m.ctx.Log("warn", "客户端认证失败，已断开连接")
+				_ = c.send(errReply(req, errors.New("authentication failed")))
+				return
+			}
+			authed = true
+			_ = c.conn.SetReadDeadline(time.Time{})
+			c.m.ctx.Log("info", "客户端已连接")
+		}
+		line, err := json.Marshal(c.handle(req))
+		if err != nil {
+			// 请求的回复数据无法编码
+			line, _ = json.Marshal(errReply(req, err))
+		}
+		if c.write(line) != nil {
+			return
+		}
+	}
+}
+
+// 处理一条已认证的消息，返回回复。请求在此等待回复，期间不读取该客户端的后续消息
+func (c *client) handle(req request) response {
+	acl := c.m.acl()
+	switch req.Op {
+	case "auth":
+		return okReply(req)
+
+	case "publish":
+		if !acl.CanPublish(req.Event) {
+			return errReply(req, fmt.Errorf("publishing %q is not permitted", req.Event))
+		}
+		data, err := modInterfaces.DecodeEventData(req.Event, req.Data)
+		if err != nil {
+			return errReply(req, err)
+		}
+		c.m.ctx.Events.Publish(req.Event, data)
+		return okReply(req)
+
+	case "request":
+		if !acl.CanPublish(req.Event) {
+			return errReply(req, fmt.Errorf("requesting %q is not permitted", req.Event))
+		}
+		data, err := modInterfaces.DecodeEventData(req.Event, req.Data)
+		if err != nil {
+			return errReply(req, err)
+		}
+		timeout := time.Duration(req.Timeout) * time.Millisecond
+		if timeout <= 0 || timeout > maxTimeout {
+			timeout = modInterfaces.DefaultRequestTimeout
+		}
+		ctx, cancel := context.WithTimeout(c.ctx, timeout)
+		defer cancel()
+		reply, err := c.m.ctx.Events.Request(ctx, req.Event, data)
+		if err != nil {
+			return errReply(req, err)
+		}
+		resp := okReply(req)
+		resp.Data = reply
+		return resp
+
+	case "subscribe":
+		if req.Event == "" {
+			return errReply(req, errors.New("event is required"))
+		}
+		if !acl.CanSubscribe(req.Event) {
+			return errReply(req, fmt.Errorf("subscribing %q is not permitted", req.Event))
+		}
+		c.lock.Lock()
+		c.nextSub++
+		id := c.nextSub
+		// 旁观者订阅不算作请求的处理函数；客户端读取太慢时丢弃最早的事件，不影响发布方
+		c.subs[id] = c.m.ctx.Events.Subscribe(req.Event, func(evt modInterfaces.Event) { c.forward(id, evt) },
+			modInterfaces.AsObserver(), modInterfaces.WithQueue(c.m.cfg.QueueSize, modInterfaces.DropOldest))
+		c.lock.Unlock()
+		resp := okReply(req)
+		resp.Subscription = id
+		return resp
+
+	case "unsubscribe":
+		c.lock.Lock()
+		sub, ok := c.subs[req.Subscription]
+		delete(c.subs, req.Subscription)
+		c.lock.Unlock()
+		if !ok {
+			return errReply(req, fmt.Errorf("subscription %d not found", req.Subscription))
+		}
+		sub.Cancel()
+		return okReply(req)
+	}
+	return errReply(req, fmt.Errorf("unknown op %q", req.Op))
+}
+
+// 将订阅的事件发给客户端，写入失败时断开连接
+func (c *client) forward(id uint64, evt modInterfaces.Event) {
+	line, err := json.Marshal(response{Op: "event", Subscription: id, Event: evt.Name, Data: evt.Data, Meta: toMeta(evt.EventMeta)})
+	if err != nil {
+		c.m.ctx.Log("warn", fmt.Sprintf("事件 %s 无法发送给客户端: %v", evt, err))
+		return
+	}
+	if c.write(line) != nil {
+		c.conn.Close()
+	}
+}
+
+// 断开连接，取消客户端的全部订阅
+func (c *client) close() {
+	c.cancel()
+	c.conn.Close()
+	c.lock.Lock()
+	for id, sub := range c.subs {
+		sub.Cancel()
+		delete(c.subs, id)
+	}
+	c.lock.Unlock()
+	c.m.lock.Lock()
+	delete(c.m.clients, c)
+	c.m.lock.Unlock()
+}
@@ -0,0 +1,188 @@
+//go:build !windows
+
+package bridge
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"xyrTools/xyrTools/modInterfaces"
+)
+
+// 启动桥接模块，返回模块、总线和套接字路径
+func startBridge(t *testing.T, cfg map[string]interface{}) (*BridgeModule, modInterfaces.ScopedEventBus, string) {
+	t.Helper()
+	dir := t.TempDir()
+	sock := filepath.Join(dir, "bus.sock")
+	cfg["address"] = sock
+	cfg["tokenFile"] = filepath.Join(dir, "bridge.token")
+	bus := modInterfaces.NewEventBus()
+	m := New().(*BridgeModule)
+	ctx := modInterfaces.Context{
+		Config: cfg,
+		Log:    func(level, msg string) { t.Logf("[%s] %s", level, msg) },
+		Events: bus.Owner("bridge"),
+	}
+	if err := m.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = m.Stop(context.Background()) })
+	return m, bus, sock
+}
+
+// 测试客户端
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	in   *bufio.Scanner
+}
+
+func dial(t *testing.T, sock string) *testClient {
+	t.Helper()
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testClient{t: t, conn: conn, in: bufio.NewScanner(conn)}
+}
+
+func (c *testClient) send(msg string) {
+	c.t.Helper()
+	if _, err := c.conn.Write([]byte(msg + "\n")); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *testClient) recv() map[string]interface{} {
+	c.t.Helper()
+	_ = c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if !c.in.Scan() {
+		c.t.Fatalf("connection closed: %v", c.in.Err())
+	}
+	var msg map[string]interface{}
+	if err := json.Unmarshal(c.in.Bytes(), &msg); err != nil {
+		c.t.Fatal(err)
+	}
+	return msg
+}
+
+// 发送消息并检查回复是否成功
+func (c *testClient) call(msg string, wantOK bool) map[string]interface{} {
+	c.t.Helper()
+	c.send(msg)
+	reply := c.recv()
+	if reply["op"] != "reply" || reply["ok"] != wantOK {
+		c.t.Fatalf("%s: reply %v, want ok=%v", msg, reply, wantOK)
+	}
+	return reply
+}
+
+func TestBridgePublishSubscribe(t *testing.T) {
+	m, bus, sock := startBridge(t, map[string]interface{}{
+		"publish":   []interface{}{"backup:*"},
+		"subscribe": []interface{}{"*:alert"},
+	})
+	raw, err := os.ReadFile(m.cfg.TokenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := strings.TrimSpace(string(raw))
+
+	c := dial(t, sock)
+	c.call(`{"op":"auth","token":"`+token+`","id":1}`, true)
+
+	// 订阅告警
+	reply := c.call(`{"op":"subscribe","event":"*:alert","id":"s"}`, true)
+	if reply["id"] != "s" || reply["subscription"] != float64(1) {
+		t.Fatalf("subscribe reply %v", reply)
+	}
+	c.call(`{"op":"subscribe","event":"**"}`, false)
+	bus.Owner("fileMonitor").Publish("fileMonitor:alert", map[string]interface{}{"count": 3})
+	evt := c.recv()
+	if evt["op"] != "event" || evt["event"] != "fileMonitor:alert" || evt["data"].(map[string]interface{})["count"] != float64(3) {
+		t.Fatalf("unexpected event %v", evt)
+	}
+	if meta := evt["meta"].(map[string]interface{}); meta["source"] != "fileMonitor" {
+		t.Fatalf("unexpected meta %v", meta)
+	}
+
+	// 发布事件，以 bridge 模块的名义
+	got := make(chan modInterfaces.Event, 1)
+	bus.Subscribe("backup:done", func(evt modInterfaces.Event) { got <- evt }, modInterfaces.WithSync())
+	c.call(`{"op":"publish","event":"backup:done","data":{"files":10}}`, true)
+	if e := <-got; e.Source != "bridge" || e.Data.(map[string]interface{})["files"] != float64(10) {
+		t.Fatalf("published event %+v", e)
+	}
+	c.call(`{"op":"publish","event":"memory:optimized"}`, false)
+
+	// 请求
+	bus.Subscribe("backup:status", func(evt modInterfaces.Event) { evt.Respond("idle", nil) })
+	if reply := c.call(`{"op":"request","event":"backup:status","timeout":1000}`, true); reply["data"] != "idle" {
+		t.Fatalf("request reply %v", reply)
+	}
+
+	c.call(`{"op":"unsubscribe","subscription":1}`, true)
+	c.call(`{"op":"unsubscribe","subscription":1}`, false)
+}
+
+func TestBridgeAuth(t *testing.T) {
+	_, _, sock := startBridge(t, map[string]interface{}{"token": "secret"})
+	c := dial(t, sock)
+	c.call(`{"op":"subscribe","event":"a"}`, false)
+	// 认证失败后断开连接
+	_ = c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if c.in.Scan() {
+		t.Fatalf("connection still open, got %s", c.in.Bytes())
+	}
+
+	c = dial(t, sock)
+	c.call(`{"op":"auth","token":"wrong"}`, false)
+
+	c = dial(t, sock)
+	c.call(`{"op":"auth","token":"secret"}`, true)
+	c.call(`{"op":"nope"}`, false)
+	c.send(`not json`)
+	if reply := c.recv(); reply["ok"] != false {
+		t.Fatalf("invalid message reply %v", reply)
+	}
+}
+
+// 套接字只有当前用户可访问，不残留临时目录，停止后删除套接字文件
+func TestBridgeSocketPermissions(t *testing.T) {
+	m, _, sock := startBridge(t, map[string]interface{}{})
+	info, err := os.Stat(sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSocket == 0 || info.Mode().Perm() != 0600 {
+		t.Fatalf("socket mode = %s, want 0600 socket", info.Mode())
+	}
+	entries, err := os.ReadDir(filepath.Dir(sock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".xyrTools-") {
+			t.Fatalf("temporary directory %s left behind", e.Name())
+		}
+	}
+	dial(t, sock)
+
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(sock); !os.IsNotExist(err) {
+		t.Fatalf("socket not removed after stop: %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+//go:build !windows
+
+package bridge
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// 默认监听的 Unix 套接字，相对于程序工作目录
+const defaultAddress = "xyrTools.sock"
+
+// 监听 Unix 套接字，只有当前用户可连接。上次异常退出残留的套接字文件会被删除。
+// 套接字先在只有当前用户可访问的临时目录中创建并设置权限，再移动到监听地址，
+// 其他用户不会在创建和设置权限之间连接上
+func listen(address string) (net.Listener, error) {
+	if conn, err := net.Dial("unix", address); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("%s is in use by another process", address)
+	}
+	if err := os.Remove(address); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp(filepath.Dir(address), ".xyrTools-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	tmp := filepath.Join(dir, "sock")
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmp, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	// 关闭时删除的是移动后的套接字文件
+	ln.SetUnlinkOnClose(false)
+	if err := os.Chmod(tmp, 0600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	if err := os.Rename(tmp, address); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return &unixListener{UnixListener: ln, path: address}, nil
+}
+
+// 关闭时删除监听地址上的套接字文件
+type unixListener struct {
+	*net.UnixListener
+	path   string
+	unlink sync.Once
+}
+
+func (l *unixListener) Close() error {
+	err := l.UnixListener.Close()
+	l.unlink.Do(func() { os.Remove(l.path) })
+	return err
+}
@@ -0,0 +1,20 @@
+package bridge
+
+import (
+	"net"
+
+	"github.com/Microsoft/go-winio"
+)
+
+// 默认监听的命名管道
+const defaultAddress = `\\.\pipe\xyrToolsBus`
+
+// 只允许管道创建者（当前用户）、SYSTEM 和管理员连接，其余本机程序还需通过令牌认证
+const pipeSecurityDescriptor = "D:P(A;;GA;;;OW)(A;;GA;;;SY)(A;;GA;;;BA)"
+
+// 监听命名管道
+func listen(address string) (net.Listener, error) {
+	return winio.ListenPipe(address, &winio.PipeConfig{
+		SecurityDescriptor: pipeSecurityDescriptor,
+	})
+}
@@ -0,0 +1,79 @@
+package bridge
+
+import (
+	"encoding/json"
+	"time"
+
+	"xyrTools/xyrTools/modInterfaces"
+)
+
+// 桥接协议：客户端与主程序通过本地套接字（Linux 为 Unix 套接字，Windows 为命名管道）交换按行分隔的 JSON，
+// 每行一条消息。客户端的每条消息都会收到一条 reply，id 为客户端消息中的 id（任意 JSON 值，可省略）。
+//
+// 客户端 -> 主程序：
+//
+//	{"op":"auth","token":"..."}                        认证，必须是第一条消息，令牌见模块配置 token、tokenFile
+//	{"op":"publish","event":"memory:optimized","data":{...}}      发布事件
+//	{"op":"request","event":"...","data":{...},"timeout":5000}   发送请求并等待回复，timeout 为毫秒
+//	{"op":"subscribe","event":"*:alert"}               订阅事件名或通配符，reply 中返回 subscription
+//	{"op":"unsubscribe","subscription":1}              取消订阅
+//
+// 主程序 -> 客户端：
+//
+//	{"op":"reply","id":...,"ok":true,"subscription":1,"data":...}  对客户端消息的回复，失败时 ok 为 false 并带 error
+//	{"op":"event","subscription":1,"event":"fileMonitor:alert","data":{...},"meta":{...}}  已订阅的事件
+//
+// 例如监控脚本订阅所有告警：
+//
+//	{"op":"auth","token":"<token>"}
+//	{"op":"subscribe","event":"*:alert"}
+//
+// 客户端发布的事件以 bridge 模块的名义发布，能发布、订阅哪些事件由模块配置 publish、subscribe 限制。
+
+// 客户端消息
+type request struct {
+	Op           string          `json:"op"`
+	ID           json.RawMessage `json:"id,omitempty"`
+	Token        string          `json:"token,omitempty"`
+	Event        string          `json:"event,omitempty"`
+	Data         json.RawMessage `json:"data,omitempty"`
+	Timeout      int64           `json:"timeout,omitempty"`
+	Subscription uint64          `json:"subscription,omitempty"`
+}
+
+// 主程序发给客户端的消息
+type response struct {
+	Op           string          `json:"op"` // reply 或 event
+	ID           json.RawMessage `json:"id,omitempty"`
+	OK           *bool           `json:"ok,omitempty"`
+	Error        string          `json:"error,omitempty"`
+	Subscription uint64          `json:"subscription,omitempty"`
+	Event        string          `json:"event,omitempty"`
+	Data         interface{}     `json:"data,omitempty"`
+	Meta         *eventMeta      `json:"meta,omitempty"`
+}
+
+// 事件元数据
+type eventMeta struct {
+	ID            uint64    `json:"id"`
+	Source        string    `json:"source,omitempty"`
+	Time          time.Time `json:"time"`
+	CorrelationID uint64    `json:"correlationId,omitempty"`
+	CausedBy      uint64    `json:"causedBy,omitempty"`
+}
+
+func toMeta(m modInterfaces.EventMeta) *eventMeta {
+	return &eventMeta{ID: m.ID, Source: m.Source, Time: m.Time, CorrelationID: m.CorrelationID, CausedBy: m.CausedBy}
+}
+
+// 成功的回复
+func okReply(req request) response {
+	ok := true
+	return response{Op: "reply", ID: req.ID, OK: &ok}
+}
+
+// 失败的回复
+func errReply(req request, err error) response {
+	ok := false
+	return response{Op: "reply", ID: req.ID, OK: &ok, Error: err.Error()}
+}
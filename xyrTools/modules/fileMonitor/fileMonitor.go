@@ -0,0 +1,210 @@
+// 文件监控模块，监控目录内的文件变动，可疑扩展名（如勒索病毒加密后缀）的变动达到阈值时告警
+package fileMonitor
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"xyrTools/xyrTools/modInterfaces"
+	"xyrTools/xyrTools/registry"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func init() {
+	registry.Register("fileMonitor", New)
+}
+
+// --- 模块配置 ---
+type fileMonitorConfig struct {
+	Interval     time.Duration `yaml:"interval" default:"20s" min:"1s" max:"24h" desc:"可疑变动的统计周期，纯数字按秒计算"`
+	FilePath     string        `yaml:"file_path" desc:"监控的目录，不配置时使用系统默认目录"`
+	BlacklistExt []string      `yaml:"blacklist_ext" desc:"可疑的文件扩展名，如 .locked、.encrypted"`
+	Threshold    int           `yaml:"threshold" default:"1" min:"1" desc:"统计周期内可疑变动达到该次数时告警"`
+}
+
+// 文件变动事件数据（fileMonitor:changed）
+type FileChange struct {
+	Path string    // 变动的文件
+	Op   string    // 操作类型：CREATE、WRITE、REMOVE、RENAME、CHMOD
+	Time time.Time // 变动时间
+}
+
+// 可疑变动告警数据（fileMonitor:alert）
+type FileAlert struct {
+	Dir    string        // 监控的目录
+	Count  int           // 统计周期内可疑变动次数
+	Files  []string      // 可疑文件（最多记录 maxAlertFiles 个）
+	Window time.Duration // 统计周期
+}
+
+var (
+	TopicChanged = modInterfaces.DeclareEvent[FileChange]("fileMonitor:changed", "监控目录内的文件变动")
+	TopicAlert   = modInterfaces.DeclareEvent[FileAlert]("fileMonitor:alert", "统计周期内可疑文件变动达到阈值")
+)
+
+// 告警中最多列出的文件数
+const maxAlertFiles = 20
+
+type FileMonitorModule struct {
+	status modInterfaces.ModuleStatus
+	ctx    modInterfaces.Context
+	cfg    fileMonitorConfig
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+
+	watcher    *fsnotify.Watcher
+	suspicious []string // 当前统计周期内的可疑文件
+}
+
+func New() modInterfaces.Module {
+	return &FileMonitorModule{}
+}
+
+func (m *FileMonitorModule) ID() string          { return "fileMonitor" }
+func (m *FileMonitorModule) Name() string        { return "文件监控模块" }
+func (m *FileMonitorModule) Description() string { return platformDesc }
+func (m *FileMonitorModule) Version() string     { return "1.0.0" }
+func (m *FileMonitorModule) Author() string      { return "小鱼" }
+
+// 事件声明，供事件总线访问控制使用
+func (m *FileMonitorModule) PublishesEvents() []string {
+	return []string{TopicChanged.Name(), TopicAlert.Name()}
+}
+func (m *FileMonitorModule) SubscribesEvents() []string { return []string{} }
+
+func (m *FileMonitorModule) Init(ctx modInterfaces.Context) error {
+	if err := ctx.DecodeConfig(&m.cfg); err != nil {
+		return err
+	}
+	m.ctx = ctx
+	m.ctx.Log("info", "文件监控模块已初始化")
+	return nil
+}
+
+func (m *FileMonitorModule) Start() error {
+	dir := m.cfg.FilePath
+	if dir == "" {
+		dir = defaultWatchPath()
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return fmt.Errorf("监控目录 %s 失败: %w", dir, err)
+	}
+	m.watcher = watcher
+	m.suspicious = nil
+	m.stopCh = make(chan struct{})
+	m.status.Running = true
+	m.status.StartTime = time.Now()
+
+	m.wg.Add(1)
+	m.ctx.Go(func() {
+		defer m.wg.Done()
+		m.watchEvents(dir)
+	})
+	m.ctx.Log("info", "文件监控模块启动，监控目录: "+dir)
+	return nil
+}
+
+func (m *FileMonitorModule) Stop(ctx context.Context) error {
+	if !m.status.Running {
+		return nil
+	}
+	close(m.stopCh)
+	m.status.Running = false
+	m.status.EndTime = time.Now()
+	// 先关闭监听器，正在阻塞读取事件的协程随之退出
+	closeErr := m.watcher.Close()
+	if err := modInterfaces.WaitContext(ctx, &m.wg); err != nil {
+		return err
+	}
+	return closeErr
+}
+
+func (m *FileMonitorModule) Status() modInterfaces.ModuleStatus {
+	return m.status
+}
+
+func (m *FileMonitorModule) Reload(ctx modInterfaces.Context) error {
+	var cfg fileMonitorConfig
+	if err := ctx.DecodeConfig(&cfg); err != nil {
+		return err
+	}
+	m.cfg = cfg
+	m.ctx = ctx
+	m.ctx.Log("info", "文件监控模块重新加载配置")
+	if !m.status.Running {
+		return nil
+	}
+	_ = m.Stop(context.Background())
+	return m.Start()
+}
+
+func (m *FileMonitorModule) ConfigSchema() []modInterfaces.ConfigField {
+	return modInterfaces.ConfigSchema(fileMonitorConfig{})
+}
+
+// 处理文件变动事件，并按统计周期汇总可疑变动
+func (m *FileMonitorModule) watchEvents(dir string) {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stopCh:
+			return
+		case event, ok := <-m.watcher.Events:
+			if !ok {
+				return
+			}
+			modInterfaces.Publish(m.ctx.Events, TopicChanged, FileChange{
+				Path: event.Name,
+				Op:   event.Op.String(),
+				Time: time.Now(),
+			})
+			if m.isSuspicious(event) {
+				m.suspicious = append(m.suspicious, event.Name)
+			}
+		case err, ok := <-m.watcher.Errors:
+			if !ok {
+				return
+			}
+			m.ctx.Log("error", "文件监控出错: "+err.Error())
+		case <-ticker.C:
+			if len(m.suspicious) >= m.cfg.Threshold {
+				files := m.suspicious
+				if len(files) > maxAlertFiles {
+					files = files[:maxAlertFiles]
+				}
+				m.ctx.Log("warn", fmt.Sprintf("%s 内检测到 %d 次可疑文件变动", m.cfg.Interval, len(m.suspicious)))
+				modInterfaces.Publish(m.ctx.Events, TopicAlert, FileAlert{
+					Dir:    dir,
+					Count:  len(m.suspicious),
+					Files:  files,
+					Window: m.cfg.Interval,
+				})
+			}
+			m.suspicious = nil
+		}
+	}
+}
+
+// 判断是否可疑：新建、写入或重命名为黑名单扩展名的文件
+func (m *FileMonitorModule) isSuspicious(event fsnotify.Event) bool {
+	if event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Rename) == 0 {
+		return false
+	}
+	ext := filepath.Ext(event.Name)
+	for _, black := range m.cfg.BlacklistExt {
+		if sameExt(ext, black) {
+			return true
+		}
+	}
+	return false
+}
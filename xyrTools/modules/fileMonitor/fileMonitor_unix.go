@@ -0,0 +1,20 @@
+//go:build !windows
+
+package fileMonitor
+
+import "os"
+
+const platformDesc = "Linux/macOS 文件变动监控与告警"
+
+// 默认监控用户目录
+func defaultWatchPath() string {
+	if dir, err := os.UserHomeDir(); err == nil {
+		return dir
+	}
+	return "/"
+}
+
+// 类 Unix 系统文件名区分大小写
+func sameExt(a, b string) bool {
+	return a == b
+}
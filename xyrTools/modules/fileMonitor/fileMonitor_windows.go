@@ -0,0 +1,21 @@
+package fileMonitor
+
+import (
+	"os"
+	"strings"
+)
+
+const platformDesc = "Windows 文件变动监控与告警"
+
+// 默认监控用户目录
+func defaultWatchPath() string {
+	if dir := os.Getenv("USERPROFILE"); dir != "" {
+		return dir
+	}
+	return `C:\`
+}
+
+// Windows 文件名不区分大小写
+func sameExt(a, b string) bool {
+	return strings.EqualFold(a, b)
+}
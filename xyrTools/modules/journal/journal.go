@@ -0,0 +1,172 @@
+// 事件日志模块，将事件总线上的事件逐条追加到 JSONL 文件（每行一个 Record），作为安全告警等事件的审计记录。
+// 记录可用 Query 按时间、事件名和发布模块查询，用 Replay 重放到新的事件总线中离线复现模块行为。
+package journal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"xyrTools/xyrTools/modInterfaces"
+	"xyrTools/xyrTools/registry"
+
+	"myMod/xlog"
+)
+
+func init() {
+	registry.Register("journal", New)
+}
+
+// --- 模块配置 ---
+type journalConfig struct {
+	File       string   `yaml:"file" default:"logs/events.jsonl" desc:"事件日志文件，切分后的历史文件为 events-20060102-150405.jsonl"`
+	MaxSize    int      `yaml:"maxSize" default:"10" min:"0" desc:"单个文件最大大小（MB），0 表示不按大小切分"`
+	Daily      bool     `yaml:"daily" default:"false" desc:"是否按天切分"`
+	MaxBackups int      `yaml:"maxBackups" default:"10" min:"0" desc:"最多保留的历史文件数，0 表示不限"`
+	MaxAge     int      `yaml:"maxAge" default:"90" min:"0" desc:"历史文件保留天数，0 表示不限"`
+	Events     []string `yaml:"events" default:"**" desc:"记录的事件名或通配符，默认记录全部事件"`
+	Sync       []string `yaml:"sync" default:"fileMonitor:alert" desc:"写入后立即刷盘的事件名或通配符，用于不能丢失的告警"`
+	QueueSize  int      `yaml:"queueSize" default:"4096" min:"1" desc:"待写入事件的队列长度，队列满时发布方等待"`
+}
+
+type JournalModule struct {
+	status modInterfaces.ModuleStatus
+	ctx    modInterfaces.Context
+	cfg    journalConfig
+
+	lock       sync.Mutex
+	file       *xlog.RotatingFile
+	syncEvents []string // 写入后立即刷盘的事件，与 file 一起在 Start 中设置
+	subs       []modInterfaces.Subscription
+}
+
+func New() modInterfaces.Module {
+	return &JournalModule{}
+}
+
+func (m *JournalModule) ID() string   { return "journal" }
+func (m *JournalModule) Name() string { return "事件日志模块" }
+func (m *JournalModule) Description() string {
+	return "记录事件总线上的事件，支持查询和重放"
+}
+func (m *JournalModule) Version() string { return "1.0.0" }
+func (m *JournalModule) Author() string  { return "小鱼" }
+
+// 事件声明，供事件总线访问控制使用：只记录，不发布；记录哪些事件由配置决定，因此声明订阅全部事件
+func (m *JournalModule) PublishesEvents() []string  { return []string{} }
+func (m *JournalModule) SubscribesEvents() []string { return []string{"**"} }
+
+func (m *JournalModule) Init(ctx modInterfaces.Context) error {
+	if err := ctx.DecodeConfig(&m.cfg); err != nil {
+		return err
+	}
+	m.ctx = ctx
+	m.ctx.Log("info", "事件日志模块已初始化")
+	return nil
+}
+
+func (m *JournalModule) Start() error {
+	file, err := xlog.OpenRotatingFile(m.cfg.File, xlog.RotateOptions{
+		MaxSize:    int64(m.cfg.MaxSize) << 20,
+		Daily:      m.cfg.Daily,
+		MaxBackups: m.cfg.MaxBackups,
+		MaxAge:     time.Duration(m.cfg.MaxAge) * 24 * time.Hour,
+	})
+	if err != nil {
+		return err
+	}
+	m.lock.Lock()
+	m.file = file
+	m.syncEvents = m.cfg.Sync
+	m.lock.Unlock()
+	// 以旁观者订阅，不影响请求是否有处理函数；审计记录不能丢，队列满时让发布方等待
+	for _, pattern := range m.cfg.Events {
+		sub := m.ctx.Events.Subscribe(pattern, m.record, modInterfaces.AsObserver(), modInterfaces.WithQueue(m.cfg.QueueSize, modInterfaces.Block))
+		m.subs = append(m.subs, sub)
+	}
+	m.status.Running = true
+	m.status.StartTime = time.Now()
+	m.ctx.Log("info", "事件日志模块启动，记录到: "+m.cfg.File)
+	return nil
+}
+
+func (m *JournalModule) Stop(ctx context.Context) error {
+	if !m.status.Running {
+		return nil
+	}
+	for _, sub := range m.subs {
+		sub.Cancel()
+	}
+	m.subs = nil
+	m.status.Running = false
+	m.status.EndTime = time.Now()
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	err := m.file.Close()
+	m.file = nil
+	return err
+}
+
+func (m *JournalModule) Status() modInterfaces.ModuleStatus {
+	return m.status
+}
+
+func (m *JournalModule) ConfigSchema() []modInterfaces.ConfigField {
+	return modInterfaces.ConfigSchema(journalConfig{})
+}
+
+func (m *JournalModule) Reload(ctx modInterfaces.Context) error {
+	var cfg journalConfig
+	if err := ctx.DecodeConfig(&cfg); err != nil {
+		return err
+	}
+	m.cfg = cfg
+	m.ctx = ctx
+	m.ctx.Log("info", "事件日志模块重新加载配置")
+	if !m.status.Running {
+		return nil
+	}
+	_ = m.Stop(context.Background())
+	return m.Start()
+}
+
+// Query 查询本模块配置的事件日志文件
+func (m *JournalModule) Query(f Filter) ([]Record, error) {
+	return Query(m.cfg.File, f)
+}
+
+// 写入一条事件记录
+func (m *JournalModule) record(evt modInterfaces.Event) {
+	rec, err := NewRecord(evt)
+	if err != nil {
+		m.ctx.Log("warn", fmt.Sprintf("事件 %s 未能写入事件日志: %v", evt, err))
+		return
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		m.ctx.Log("warn", fmt.Sprintf("事件 %s 未能写入事件日志: %v", evt, err))
+		return
+	}
+	line = append(line, '\n')
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	// 模块已停止
+	if m.file == nil {
+		return
+	}
+	if _, err := m.file.Write(line); err != nil {
+		m.ctx.Log("error", fmt.Sprintf("事件 %s 未能写入事件日志: %v", evt, err))
+		return
+	}
+	for _, pattern := range m.syncEvents {
+		if modInterfaces.MatchTopic(pattern, evt.Name) {
+			if err := m.file.Sync(); err != nil {
+				m.ctx.Log("error", "事件日志刷盘失败: "+err.Error())
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package journal
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"xyrTools/xyrTools/modInterfaces"
+)
+
+type testLogin struct {
+	User     string
+	Password string
+}
+
+var testLoginTopic = modInterfaces.DeclareEvent[testLogin]("journalTest:login", "journal test event")
+
+func init() {
+	RegisterRedactor("journalTest:login", RedactFields("Password"))
+}
+
+// 启动事件日志模块，返回模块和它所在的总线
+func startJournal(t *testing.T, cfg map[string]interface{}) (*JournalModule, modInterfaces.ScopedEventBus) {
+	t.Helper()
+	bus := modInterfaces.NewEventBus()
+	m := New().(*JournalModule)
+	ctx := modInterfaces.Context{
+		Config: cfg,
+		Log:    func(level, msg string) { t.Logf("[%s] %s", level, msg) },
+		Events: bus.Owner("journal"),
+	}
+	if err := m.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return m, bus
+}
+
+func TestJournalQueryReplay(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "events.jsonl")
+	m, bus := startJournal(t, map[string]interface{}{"file": file})
+
+	start := time.Now()
+	modInterfaces.Publish(bus.Owner("auth"), testLoginTopic, testLogin{User: "xiaoyu", Password: "secret"})
+	bus.Owner("fileMonitor").Publish("journalTest:alert", map[string]interface{}{"count": 3})
+	bus.Publish("other:event", nil)
+	if err := bus.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "secret") {
+		t.Fatalf("password written to journal:\n%s", raw)
+	}
+
+	all, err := Query(file, Filter{From: start})
+	if err != nil || len(all) != 3 {
+		t.Fatalf("Query = %d records, %v; want 3", len(all), err)
+	}
+	if all[0].Source != "auth" || all[0].CorrelationID != all[0].ID {
+		t.Fatalf("unexpected first record %+v", all[0])
+	}
+	bySource, _ := m.Query(Filter{Source: "fileMonitor"})
+	if len(bySource) != 1 || bySource[0].Name != "journalTest:alert" {
+		t.Fatalf("Query by source = %+v", bySource)
+	}
+	byName, _ := Query(file, Filter{Event: "journalTest:*"})
+	if len(byName) != 2 {
+		t.Fatalf("Query by pattern = %d records, want 2", len(byName))
+	}
+	none, _ := Query(file, Filter{To: start})
+	if len(none) != 0 {
+		t.Fatalf("Query before start = %d records, want 0", len(none))
+	}
+
+	// 重放到新的总线，数据解码为声明的类型，发布模块保持不变
+	replay := modInterfaces.NewEventBus()
+	got := make(chan modInterfaces.Event, 1)
+	modInterfaces.Subscribe(replay, testLoginTopic, func(data testLogin, evt modInterfaces.Event) {
+		if data.User != "xiaoyu" || data.Password != "***" {
+			t.Errorf("replayed data %+v", data)
+		}
+		got <- evt
+	})
+	if err := Replay(context.Background(), replay, byName); err != nil {
+		t.Fatal(err)
+	}
+	if evt := <-got; evt.Source != "auth" {
+		t.Fatalf("replayed event source %q, want auth", evt.Source)
+	}
+}
+
+// 处理失败事件中的原事件数据同样脱敏
+func TestJournalRedactsHandlerFailure(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "events.jsonl")
+	m, bus := startJournal(t, map[string]interface{}{"file": file})
+	bus.SetLogger(func(string, string) {})
+	modInterfaces.Subscribe(bus.Owner("auth"), testLoginTopic, func(testLogin, modInterfaces.Event) { panic("boom") })
+	modInterfaces.Publish(bus, testLoginTopic, testLogin{User: "xiaoyu", Password: "secret"})
+	// 失败事件在处理函数所在队列中发布，第二次 Drain 等待它写入
+	for i := 0; i < 2; i++ {
+		if err := bus.Drain(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	records, err := Query(file, Filter{Event: modInterfaces.TopicHandlerFailed.Name()})
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Query = %d records, %v; want 1", len(records), err)
+	}
+	if strings.Contains(string(records[0].Data), "secret") {
+		t.Fatalf("password written to journal: %s", records[0].Data)
+	}
+}
+
+// 旁观者订阅不影响请求是否有处理函数
+func TestJournalDoesNotHandleRequests(t *testing.T) {
+	_, bus := startJournal(t, map[string]interface{}{"file": filepath.Join(t.TempDir(), "events.jsonl")})
+	_, err := bus.Request(context.Background(), "nobody:handles", nil)
+	if err == nil || !strings.Contains(err.Error(), modInterfaces.ErrNoHandler.Error()) {
+		t.Fatalf("Request = %v, want ErrNoHandler", err)
+	}
+}
+
+// 切分后的历史文件按时间顺序查询
+func TestQueryRotatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "events.jsonl")
+	write := func(name string, ids ...uint64) {
+		var lines []string
+		for _, id := range ids {
+			line, _ := json.Marshal(Record{ID: id, Name: "a"})
+			lines = append(lines, string(line))
+		}
+		// 末尾的半行（写入中断）应被跳过
+		lines = append(lines, `{"id": 9`)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(strings.Join(lines, "\n")), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("events-20260102-030405.1.jsonl", 3)
+	write("events-20260102-030405.jsonl", 2)
+	write("events-20260101-000000.jsonl", 1)
+	write("events.jsonl", 4)
+	records, err := Query(file, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []uint64
+	for _, r := range records {
+		ids = append(ids, r.ID)
+	}
+	if len(ids) != 4 || ids[0] != 1 || ids[1] != 2 || ids[2] != 3 || ids[3] != 4 {
+		t.Fatalf("ids = %v, want [1 2 3 4]", ids)
+	}
+}
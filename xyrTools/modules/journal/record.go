@@ -0,0 +1,226 @@
+package journal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"xyrTools/xyrTools/modInterfaces"
+
+	"myMod/xlog"
+)
+
+// 事件日志中的一条记录，对应事件日志文件中的一行
+type Record struct {
+	ID            uint64          `json:"id"`
+	Name          string          `json:"name"`
+	Source        string          `json:"source,omitempty"`
+	Time          time.Time       `json:"time"`
+	CorrelationID uint64          `json:"corr"`
+	CausedBy      uint64          `json:"cause,omitempty"`
+	Request       bool            `json:"request,omitempty"` // 是否为 Request 发出的请求
+	Data          json.RawMessage `json:"data,omitempty"`    // 经脱敏处理后的事件数据
+}
+
+// NewRecord 由事件生成记录，事件数据先经过该事件注册的脱敏函数处理
+func NewRecord(evt modInterfaces.Event) (Record, error) {
+	rec := Record{
+		ID:            evt.ID,
+		Name:          evt.Name,
+		Source:        evt.Source,
+		Time:          evt.Time,
+		CorrelationID: evt.CorrelationID,
+		CausedBy:      evt.CausedBy,
+		Request:       evt.IsRequest(),
+	}
+	data := redact(evt.Name, evt.Data)
+	if data == nil {
+		return rec, nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return rec, fmt.Errorf("marshal data: %w", err)
+	}
+	rec.Data = raw
+	return rec, nil
+}
+
+// 记录的元数据
+func (r Record) Meta() modInterfaces.EventMeta {
+	return modInterfaces.EventMeta{ID: r.ID, Source: r.Source, Time: r.Time, CorrelationID: r.CorrelationID, CausedBy: r.CausedBy}
+}
+
+// --- 脱敏 ---
+
+// 脱敏函数，返回写入事件日志的数据，不能修改传入的数据（其他订阅者共用）
+type Redactor func(data interface{}) interface{}
+
+var (
+	redactors   = make(map[string]Redactor)
+	redactorsMu sync.RWMutex
+)
+
+// RegisterRedactor 为事件注册脱敏函数，事件名可使用通配符，一个事件匹配多个脱敏函数时全部执行。
+// 应在包的 init() 中调用，重复注册视为编程错误直接 panic
+func RegisterRedactor(event string, fn Redactor) {
+	redactorsMu.Lock()
+	defer redactorsMu.Unlock()
+	if fn == nil {
+		panic(fmt.Sprintf("journal: redactor for %s is nil", event))
+	}
+	if _, exists := redactors[event]; exists {
+		panic(fmt.Sprintf("journal: redactor for %s registered twice", event))
+	}
+	redactors[event] = fn
+}
+
+func init() {
+	// 处理失败事件中的原事件数据按原事件的脱敏函数处理
+	RegisterRedactor(modInterfaces.TopicHandlerFailed.Name(), func(data interface{}) interface{} {
+		f, ok := data.(modInterfaces.HandlerFailure)
+		if !ok {
+			return data
+		}
+		f.Event.Data = redact(f.Event.Name, f.Event.Data)
+		return f
+	})
+}
+
+func redact(event string, data interface{}) interface{} {
+	// 在锁外执行脱敏函数，脱敏函数可能再次调用 redact
+	var fns []Redactor
+	redactorsMu.RLock()
+	for pattern, fn := range redactors {
+		if modInterfaces.MatchTopic(pattern, event) {
+			fns = append(fns, fn)
+		}
+	}
+	redactorsMu.RUnlock()
+	for _, fn := range fns {
+		data = fn(data)
+	}
+	return data
+}
+
+// 脱敏后字段的值
+const redactedValue = "***"
+
+// RedactFields 返回将指定字段替换为 *** 的脱敏函数，字段名为 JSON 中的键（结构体未加 json 标签时即字段名），
+// 只处理第一层字段
+func RedactFields(fields ...string) Redactor {
+	return func(data interface{}) interface{} {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return data
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(raw, &m); err != nil {
+			// 不是对象，整体脱敏
+			return redactedValue
+		}
+		for _, field := range fields {
+			if _, ok := m[field]; ok {
+				m[field] = redactedValue
+			}
+		}
+		return m
+	}
+}
+
+// --- 查询 ---
+
+// 查询条件，零值的条件不过滤
+type Filter struct {
+	From          time.Time // 起始时间（含）
+	To            time.Time // 结束时间（不含）
+	Event         string    // 事件名或通配符，如 fileMonitor:**
+	Source        string    // 发布事件的模块ID
+	CorrelationID uint64    // 关联ID，查询由同一操作引发的全部事件
+}
+
+// 记录是否满足条件
+func (f Filter) Match(r Record) bool {
+	if !f.From.IsZero() && r.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !r.Time.Before(f.To) {
+		return false
+	}
+	if f.Event != "" && !modInterfaces.MatchTopic(f.Event, r.Name) {
+		return false
+	}
+	if f.Source != "" && r.Source != f.Source {
+		return false
+	}
+	return f.CorrelationID == 0 || r.CorrelationID == f.CorrelationID
+}
+
+// Query 按时间顺序读取事件日志文件 path 及其切分后的历史文件，返回满足条件的记录。
+// 无法解析的行（如写入中断留下的半行）会被跳过
+func Query(path string, f Filter) ([]Record, error) {
+	files, err := xlog.RotatedFiles(path)
+	if err != nil {
+		return nil, fmt.Errorf("journal: list files: %w", err)
+	}
+	var records []Record
+	for _, name := range files {
+		if records, err = queryFile(name, f, records); err != nil {
+			return records, err
+		}
+	}
+	return records, nil
+}
+
+func queryFile(path string, f Filter, out []Record) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return out, fmt.Errorf("journal: %w", err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for scanner.Scan() {
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		if f.Match(rec) {
+			out = append(out, rec)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return out, fmt.Errorf("journal: read %s: %w", path, err)
+	}
+	return out, nil
+}
+
+// --- 重放 ---
+
+// Replay 按顺序将记录重新发布到 bus，数据按事件目录解码为声明的类型。
+// bus 支持 Owner（如 modInterfaces.NewEventBus()）时以原发布模块的名义发布。
+// 请求按普通事件发布，不等待回复；新事件的 ID、时间由 bus 重新生成。
+// ctx 取消时停止重放并返回 ctx 的错误
+func Replay(ctx context.Context, bus modInterfaces.EventBus, records []Record) error {
+	owners, _ := bus.(interface {
+		Owner(owner string) modInterfaces.EventBus
+	})
+	for _, rec := range records {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		data, err := modInterfaces.DecodeEventData(rec.Name, rec.Data)
+		if err != nil {
+			return fmt.Errorf("journal: replay event %d (%s): %w", rec.ID, rec.Name, err)
+		}
+		target := bus
+		if owners != nil && rec.Source != "" {
+			target = owners.Owner(rec.Source)
+		}
+		target.Publish(rec.Name, data)
+	}
+	return nil
+}
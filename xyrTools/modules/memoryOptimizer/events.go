@@ -0,0 +1,36 @@
+package memopt
+
+import (
+	"fmt"
+
+	"xyrTools/xyrTools/modInterfaces"
+)
+
+// 内存优化模块的事件，不带构建标签，其他模块（如托盘）和工具在任何系统上都能引用
+
+// 优化所有进程的请求（memory:optimized）
+type OptimizeRequest struct {
+	Source string // 发起请求的模块ID
+}
+
+// 优化指定进程的请求（memory:optimizeByNames）
+type OptimizeByNamesRequest struct {
+	Source string   // 发起请求的模块ID
+	Names  []string // 进程名，如 xyrTools.exe，不区分大小写
+}
+
+// 内存优化结果，作为优化请求的回复和 memory:optimizeDone 事件的数据
+type OptimizeResult struct {
+	Processes int    // 成功优化的进程数
+	Freed     uint64 // 优化后增加的可用内存（字节）
+}
+
+func (r OptimizeResult) String() string {
+	return fmt.Sprintf("已优化 %d 个进程，释放内存 %.1f MB", r.Processes, float64(r.Freed)/(1<<20))
+}
+
+var (
+	TopicOptimize        = modInterfaces.DeclareRequest[OptimizeRequest, OptimizeResult]("memory:optimized", "优化所有进程的内存")
+	TopicOptimizeByNames = modInterfaces.DeclareRequest[OptimizeByNamesRequest, OptimizeResult]("memory:optimizeByNames", "优化指定进程的内存")
+	TopicOptimizeDone    = modInterfaces.DeclareEvent[OptimizeResult]("memory:optimizeDone", "定时内存优化完成")
+)
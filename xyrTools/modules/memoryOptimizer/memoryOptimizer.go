@@ -1,30 +1,44 @@
+//go:build windows
+
 package memopt
 
 // --- 内存优化模块 ---
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
-	"xyrTools/xyrTools/extendFunc"
 	"xyrTools/xyrTools/modInterfaces"
+	"xyrTools/xyrTools/registry"
 
+	"github.com/shirou/gopsutil/v3/mem"
 	"golang.org/x/sys/windows"
 )
 
+func init() {
+	registry.Register("memopt", New)
+}
+
 // --- 接口依赖 ---
 type MemOptModule struct {
-	status modInterfaces.ModuleStatus // 模块状态
-	ctx    modInterfaces.Context      // 模块上下文
-	stopCh chan struct{}              // 停止信号通道，stop时通知模块退出
-	wg     sync.WaitGroup             // 等待组、确保模块退出时所有 goroutine 都已退出
+	status modInterfaces.ModuleStatus   // 模块状态
+	ctx    modInterfaces.Context        // 模块上下文
+	cfg    memOptConfig                 // 模块配置
+	stopCh chan struct{}                // 停止信号通道，stop时通知模块退出
+	wg     sync.WaitGroup               // 等待组、确保模块退出时所有 goroutine 都已退出
+	subs   []modInterfaces.Subscription // 事件订阅，停止时取消
+}
+
+// --- 模块配置 ---
+type memOptConfig struct {
+	Interval time.Duration `yaml:"interval" default:"30s" min:"1s" max:"24h" desc:"内存优化运行间隔，支持 30s、5m 等写法，纯数字按秒计算"`
 }
 
 func New() modInterfaces.Module {
-	return &MemOptModule{
-		stopCh: make(chan struct{}),
-	}
+	return &MemOptModule{}
 }
 
 func (m *MemOptModule) ID() string          { return "memopt" }
@@ -33,75 +47,104 @@ func (m *MemOptModule) Description() string { return "定期优化系统内存
 func (m *MemOptModule) Version() string     { return "1.0.0" }
 func (m *MemOptModule) Author() string      { return "小鱼" }
 
+// 事件声明，供事件总线访问控制使用
+func (m *MemOptModule) PublishesEvents() []string { return []string{TopicOptimizeDone.Name()} }
+func (m *MemOptModule) SubscribesEvents() []string {
+	return []string{TopicOptimize.Name(), TopicOptimizeByNames.Name()}
+}
+
 func (m *MemOptModule) Init(ctx modInterfaces.Context) error {
+	if err := ctx.DecodeConfig(&m.cfg); err != nil {
+		return err
+	}
 	m.ctx = ctx
 	m.ctx.Log("info", "内存优化模块已初始化")
-	// 订阅事件，优化所有进程
-	m.ctx.Events.Subscribe("memory:optimized", func(evt modInterfaces.Event) {
-		m.ctx.Log("info", fmt.Sprintf("收到事件 memory:optimized => %v", evt.Data))
-		message, ok := evt.Data.(string)
-		// 如果收到的信息是内存优化，则启动优化
-		if ok && message == "运行内存优化任务" {
-			m.ctx.Log("info", "收到内存优化任务，开始优化")
-			m.Optimize()
-		}
+	return nil
+}
+
+// 处理内存优化请求，模块停止时取消
+func (m *MemOptModule) subscribe() {
+	// 优化所有进程
+	optimizeAll := modInterfaces.Handle(m.ctx.Events, TopicOptimize, func(req OptimizeRequest, evt modInterfaces.Event) (OptimizeResult, error) {
+		m.ctx.Logger.With(evt.LogFields()...).Info("收到内存优化请求，开始优化")
+		return m.Optimize()
 	})
-	// 订阅事件，优化指定进程
-	m.ctx.Events.Subscribe("memory:optimizeByNames", func(evt modInterfaces.Event) {
-		m.ctx.Log("info", fmt.Sprintf("收到事件 memory:optimizeByNames => %v", evt.Data))
-		names, ok := evt.Data.([]string)
-		if ok && len(names) > 0 {
-			m.ctx.Log("info", "收到指定进程名称列表，开始优化")
-			m.OptimizeByNames(names...)
-		}
+	// 优化指定进程
+	optimizeByNames := modInterfaces.Handle(m.ctx.Events, TopicOptimizeByNames, func(req OptimizeByNamesRequest, evt modInterfaces.Event) (OptimizeResult, error) {
+		m.ctx.Logger.With(evt.LogFields()...).Info("收到指定进程内存优化请求", "names", req.Names)
+		return m.OptimizeByNames(req.Names...)
 	})
-	return nil
+	m.subs = []modInterfaces.Subscription{optimizeAll, optimizeByNames}
 }
 
 func (m *MemOptModule) Start() error {
+	// 每次启动重新创建停止信号通道，支持运行时反复启停
+	m.stopCh = make(chan struct{})
 	m.status.Running = true
 	m.status.StartTime = time.Now()
+	m.subscribe()
 
-	interval := 30 // 默认 30 秒执行一次
-	if val, ok := m.ctx.Config["interval"].(int); ok {
-		interval = val
-	}
+	interval := m.cfg.Interval
 	m.wg.Add(1)
 
-	go func() {
+	// 受监管的协程，panic 时由引擎按重启策略处理
+	m.ctx.Go(func() {
 		defer m.wg.Done()
-		ticker := time.NewTicker(time.Duration(interval) * time.Second)
+		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
-		m.ctx.Log("info", "内存优化模块启动，间隔秒数: "+fmt.Sprint(interval))
+		m.ctx.Log("info", "内存优化模块启动，运行间隔: "+interval.String())
 		for {
 			select {
 			case <-ticker.C:
-				m.Optimize()
-				m.ctx.Events.Publish("memory:optimized", "内存优化完成。")
+				// 定时优化完成后发布结果，与 memory:optimized 请求区分开
+				if result, err := m.Optimize(); err == nil {
+					modInterfaces.Publish(m.ctx.Events, TopicOptimizeDone, result)
+				}
 			case <-m.stopCh:
 				m.ctx.Log("info", "内存优化模块停止")
 				return
 			}
 		}
-	}()
+	})
 	return nil
 }
 
-func (m *MemOptModule) Stop() error {
+func (m *MemOptModule) Stop(ctx context.Context) error {
+	if !m.status.Running {
+		return nil
+	}
 	close(m.stopCh)
-	m.wg.Wait()
+	for _, sub := range m.subs {
+		sub.Cancel()
+	}
+	m.subs = nil
 	m.status.Running = false
-	return nil
+	m.status.EndTime = time.Now()
+	return modInterfaces.WaitContext(ctx, &m.wg)
 }
 
 func (m *MemOptModule) Status() modInterfaces.ModuleStatus {
 	return m.status
 }
 
-func (m *MemOptModule) Reload() error {
+func (m *MemOptModule) ConfigSchema() []modInterfaces.ConfigField {
+	return modInterfaces.ConfigSchema(memOptConfig{})
+}
+
+func (m *MemOptModule) Reload(ctx modInterfaces.Context) error {
+	// 新配置校验失败时保留旧配置继续运行
+	var cfg memOptConfig
+	if err := ctx.DecodeConfig(&cfg); err != nil {
+		return err
+	}
+	m.cfg = cfg
+	m.ctx = ctx
 	m.ctx.Log("info", "内存优化模块重新加载配置")
-	_ = m.Stop()
-	m.stopCh = make(chan struct{})
+	// 未运行时只更新配置，下次启动生效
+	if !m.status.Running {
+		return nil
+	}
+	_ = m.Stop(context.Background())
 	return m.Start()
 }
 
@@ -112,20 +155,42 @@ var (
 	procEmptyWS = modpsapi.NewProc("EmptyWorkingSet")
 )
 
-func emptyWorkingSet(handle windows.Handle) {
-	_, _, _ = procEmptyWS.Call(uintptr(handle))
+// 清空进程工作集，成功返回 true
+func emptyWorkingSet(handle windows.Handle) bool {
+	r, _, _ := procEmptyWS.Call(uintptr(handle))
+	return r != 0
 }
 
-func (m *MemOptModule) Optimize() {
+// 当前可用内存，获取失败时为 0
+func availableMemory() uint64 {
+	vm, err := mem.VirtualMemory()
+	if err != nil {
+		return 0
+	}
+	return vm.Available
+}
+
+// 与优化前相比增加的可用内存
+func freedMemory(before uint64) uint64 {
+	after := availableMemory()
+	if before == 0 || after <= before {
+		return 0
+	}
+	return after - before
+}
+
+// 优化所有进程内存
+func (m *MemOptModule) Optimize() (OptimizeResult, error) {
+	before := availableMemory()
 	pids := make([]uint32, 1024)
 	var needed uint32
 
 	if err := windows.EnumProcesses(pids, &needed); err != nil {
 		m.ctx.Log("error", "无法枚举进程: "+err.Error())
-		extendFunc.MessageBox("提示", "无法枚举进程: "+err.Error())
-		return
+		return OptimizeResult{}, fmt.Errorf("无法枚举进程: %w", err)
 	}
 
+	var result OptimizeResult
 	numProcs := needed / 4
 	for i := 0; i < int(numProcs); i++ {
 		pid := pids[i]
@@ -137,19 +202,21 @@ func (m *MemOptModule) Optimize() {
 		if err != nil {
 			continue
 		}
-		emptyWorkingSet(hProcess)
+		if emptyWorkingSet(hProcess) {
+			result.Processes++
+		}
 		_ = windows.CloseHandle(hProcess)
 	}
-	m.ctx.Log("info", "内存优化完成")
-	extendFunc.MessageBox("提示", "内存优化完成")
+	result.Freed = freedMemory(before)
+	m.ctx.Log("info", "内存优化完成: "+result.String())
+	return result, nil
 }
 
 // 优化指定进程内存
-func (m *MemOptModule) OptimizeByNames(procNames ...string) {
+func (m *MemOptModule) OptimizeByNames(procNames ...string) (OptimizeResult, error) {
 	if len(procNames) == 0 {
 		m.ctx.Log("warn", "未指定进程名称")
-		extendFunc.MessageBox("提示", "未指定进程名称")
-		return
+		return OptimizeResult{}, errors.New("未指定进程名称")
 	}
 
 	nameSet := make(map[string]struct{})
@@ -157,13 +224,15 @@ func (m *MemOptModule) OptimizeByNames(procNames ...string) {
 		nameSet[strings.ToLower(name)] = struct{}{}
 	}
 
+	before := availableMemory()
 	pids := make([]uint32, 1024)
 	var needed uint32
 	if err := windows.EnumProcesses(pids, &needed); err != nil {
 		m.ctx.Log("error", "无法枚举进程: "+err.Error())
-		return
+		return OptimizeResult{}, fmt.Errorf("无法枚举进程: %w", err)
 	}
 
+	var result OptimizeResult
 	numProcs := int(needed / 4)
 	for i := 0; i < numProcs; i++ {
 		pid := pids[i]
@@ -188,9 +257,12 @@ func (m *MemOptModule) OptimizeByNames(procNames ...string) {
 			windows.CloseHandle(hProcess)
 			continue
 		}
-		emptyWorkingSet(hProcess)
-		//m.ctx.Log("info", fmt.Sprintf("已优化进程: %s (PID %d)", processName, pid))
-		extendFunc.MessageBox("提示", fmt.Sprintf("已优化进程: %s (PID %d)", processName, pid))
+		if emptyWorkingSet(hProcess) {
+			result.Processes++
+			m.ctx.Log("info", fmt.Sprintf("已优化进程: %s (PID %d)", processName, pid))
+		}
 		windows.CloseHandle(hProcess)
 	}
+	result.Freed = freedMemory(before)
+	return result, nil
 }
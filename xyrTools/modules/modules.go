@@ -0,0 +1,9 @@
+// 汇总所有模块包，主程序导入本包即可让各模块完成注册。
+// 新增模块时在此（或对应平台的 modules_<os>.go）添加导入，无需修改 initSys。
+package modules
+
+import (
+	_ "xyrTools/xyrTools/modules/bridge"
+	_ "xyrTools/xyrTools/modules/fileMonitor"
+	_ "xyrTools/xyrTools/modules/journal"
+)
@@ -0,0 +1,7 @@
+package modules
+
+// 仅 Windows 可用的模块
+import (
+	_ "xyrTools/xyrTools/modules/memoryOptimizer"
+	_ "xyrTools/xyrTools/modules/tray"
+)
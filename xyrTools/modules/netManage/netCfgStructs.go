@@ -3,6 +3,8 @@ package netManage
 import (
 	"os"
 
+	"myMod/netcfg"
+
 	"gopkg.in/yaml.v2"
 )
 
@@ -10,21 +12,8 @@ type ConfigFile struct {
 	Configs []NetConfig `yaml:"configs"`
 }
 
-type NetConfig struct {
-	Name    string `yaml:"name"`    // 配置名称 自定义
-	Desc    string `yaml:"desc"`    // 配置描述 自定义
-	Adapter string `yaml:"adapter"` // 网卡名称
-	DHCP    bool   `yaml:"dhcp"`    // 是否使用DHCP
-	DNSdhcp bool   `yaml:"dnsdhcp"` // 是否使用DHCP获取DNS
-	IP      string `yaml:"ip"`      // IP地址
-	Netmask string `yaml:"netmask"` // 子网掩码
-	Gateway string `yaml:"gateway"` // 网关
-
-	DNS      []string `yaml:"dns"`      // DNS服务器
-	MTU      int      `yaml:"mtu"`      // MTU大小
-	Metric   int      `yaml:"metric"`   // 跃点数
-	FlushDNS bool     `yaml:"flushDNS"` // 是否刷新DNS缓存
-}
+// NetConfig 网卡配置方案，与网卡配置服务共用
+type NetConfig = netcfg.Profile
 
 func LoadConfigFromFile(path string) ([]NetConfig, error) {
 	data, err := os.ReadFile(path)
@@ -35,3 +24,11 @@ func LoadConfigFromFile(path string) ([]NetConfig, error) {
 	err = yaml.Unmarshal(data, &cfg)
 	return cfg.Configs, err
 }
+
+func SaveConfigToFile(path string, configs []NetConfig) error {
+	data, err := yaml.Marshal(ConfigFile{Configs: configs})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -6,129 +6,144 @@ import (
 	"fmt"
 	"io"
 	"net"
-	"os/exec"
-	"strconv"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 	"xyrTools/xyrTools/extendFunc"
 
-	"github.com/Microsoft/go-winio"
+	"myMod/netcfg"
 )
 
+// ApplyNetConfig1 不经过网卡配置服务，直接用当前系统的后端应用配置（需要管理员权限）
 func ApplyNetConfig1(cfg NetConfig) error {
-	// 1. 检查配置的有效性
 	if err := validateNetConfig(cfg); err != nil {
 		return err
 	}
-
-	// 2. 应用 DHCP 设置
-	if cfg.DHCP {
-		if err := applyDHCP(cfg.Adapter); err != nil {
-			return err
-		}
-
-		// 如果需要通过 DHCP 获取 DNS
-		if cfg.DNSdhcp {
-			if err := applyDNSDHCP(cfg.Adapter); err != nil {
-				return err
-			}
-		} else if len(cfg.DNS) > 0 {
-			// 设置静态 DNS
-			if err := applyStaticDNS(cfg.Adapter, cfg.DNS); err != nil {
-				return err
-			}
-		}
-	} else {
-		// 配置静态 IP
-		if err := applyStaticIP(cfg.Adapter, cfg.IP, cfg.Netmask, cfg.Gateway); err != nil {
-			return err
-		}
-
-		// 配置静态 DNS
-		if len(cfg.DNS) > 0 {
-			if err := applyStaticDNS(cfg.Adapter, cfg.DNS); err != nil {
-				return err
-			}
-		}
+	backend, err := netcfg.New(netcfg.DefaultKind(), netcfg.ExecRunner{})
+	if err != nil {
+		return err
 	}
+	return netcfg.Switch(backend, cfg, netcfg.NewRouteState(routeStateFile()))
+}
 
-	// 3. 应用 MTU 配置
-	if cfg.MTU > 0 {
-		if err := applyMTU(cfg.Adapter, cfg.MTU); err != nil {
-			return err
-		}
+// 直接应用配置时的路由记录文件，位于用户配置目录
+func routeStateFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
 	}
+	return filepath.Join(dir, "xyrTools", "netRoutes.json")
+}
 
-	// 4. 应用 Metric 配置
-	if cfg.Metric > 0 {
-		if err := applyMetric(cfg.Adapter, cfg.Metric); err != nil {
-			return err
-		}
+// PreviewNetConfig 返回应用配置时将执行的命令，每行一条，不执行任何命令
+func PreviewNetConfig(cfg NetConfig) (string, error) {
+	cmds, err := netcfg.Preview(netcfg.DefaultKind(), cfg)
+	if err != nil {
+		return "", err
+	}
+	lines := make([]string, len(cmds))
+	for i, c := range cmds {
+		lines[i] = c.String()
 	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// 确认请求需在服务的确认时间结束前留出的余量，用于发送请求，超过后不再发送确认
+const confirmMargin = 2 * time.Second
+
+// 网卡配置服务的返回结果，与 netSetService 的 ResultMessage 一致
+type serviceResult struct {
+	Success bool
+	Details string
+	Other   string
+	Pending string // 等待确认的配置ID
+	Timeout int    // 等待确认的时间（秒）
+}
 
-	// 5. 刷新 DNS 缓存
-	if cfg.FlushDNS {
-		if err := flushDNSCache(); err != nil {
+// ApplyNetConfig 通过网卡配置服务应用配置。服务应用前保存快照，失败时自动恢复原配置；
+// 配置了 checks.confirm 时询问是否保留新配置，未及时确认（包括本程序崩溃）时由服务恢复原配置
+func ApplyNetConfig(cfg NetConfig) error {
+	result, err := sendToNetService(cfg)
+	if err != nil {
+		return err
+	}
+	if result.Pending != "" {
+		// 确认框没有超时，用户回答时服务可能已恢复原配置，此时不再发送过期的确认
+		deadline := time.Now().Add(time.Duration(result.Timeout)*time.Second - confirmMargin)
+		action := "rollback"
+		text := fmt.Sprintf("已应用配置 %s，是否保留？\n%d 秒内未确认将自动恢复原配置", cfg.Name, result.Timeout)
+		if extendFunc.MessageBoxYesNo("确认网络配置", text) {
+			action = "confirm"
+		}
+		if time.Now().After(deadline) {
+			msg := fmt.Sprintf("%d 秒内未确认，网卡配置服务已恢复原配置", result.Timeout)
+			extendFunc.MessageBox("提示", msg)
+			return errors.New(msg)
+		}
+		result, err = sendToNetService(map[string]string{"Action": action, "ID": result.Pending})
+		if err != nil {
 			return err
 		}
 	}
-
+	text := result.Details
+	if !result.Success && result.Other != "" {
+		text += "\n" + result.Other
+	}
+	extendFunc.MessageBox("提示", text)
+	if !result.Success {
+		return errors.New(result.Details)
+	}
 	return nil
 }
-func ApplyNetConfig(cfg NetConfig) error {
-	// 创建一次性命名管道
-	pipePath := `\\.\pipe\netCfgPipe`
-	timeout := time.Second * 10
-	// 尝试连接命名管道（最多等待10秒）
-	conn, err := winio.DialPipe(pipePath, &timeout)
+
+// 将请求序列化为 JSON 发送给网卡配置服务，返回服务的处理结果
+func sendToNetService(request interface{}) (serviceResult, error) {
+	var result serviceResult
+	// 尝试连接网卡配置服务（最多等待10秒）
+	conn, err := dialNetService(time.Second * 10)
 	if err != nil {
 		extendFunc.MessageBox("提示", "Failed to connect to pipe:"+err.Error())
-		return err
+		return result, err
 	}
 	defer conn.Close()
-	// 将 cfg 序列化为 JSON 字节切片
-	cfgData, err := json.Marshal(cfg)
+	// 将请求序列化为 JSON 字节切片
+	cfgData, err := json.Marshal(request)
 	if err != nil {
 		extendFunc.MessageBox("提示", "Failed to marshal config:"+err.Error())
-		return err
+		return result, err
 	}
 	packageData, packageErr := extendFunc.PackageData(string(cfgData), "#")
 	if packageErr != nil {
-		//fmt.Println("Failed to package config:", packageErr)
 		extendFunc.MessageBox("提示", "Failed to package config:"+packageErr.Error())
-		return packageErr
+		return result, packageErr
 	}
 
 	// 发送数据到命名管道
 	_, err = conn.Write(packageData)
 	if err != nil {
-		//fmt.Println("Failed to write to pipe:", err)
 		extendFunc.MessageBox("提示", "Failed to write to pipe:"+err.Error())
-		return err
+		return result, err
 	}
-	fmt.Println("Sent:", string(packageData))
-	//extendFunc.MessageBox("提示", string(packageData))
-	// 接收服务端回应
-	buf := make([]byte, 2048)
+	// 接收服务端回应，服务端执行连通性检查时可能需要等待较长时间
+	buf := make([]byte, 8192)
 	n, err := conn.Read(buf)
 	if err != nil && err != io.EOF {
-		fmt.Println("Read error:", err)
-		return err
+		return result, fmt.Errorf("读取网卡配置服务的回应失败: %w", err)
 	}
-	if n > 0 {
-		unpackateData, isUnpackage, unpackageErr := extendFunc.UnpackageData(buf[:n], "#")
-		if !isUnpackage {
-			fmt.Println("Error unpackage data:", unpackageErr)
-			fmt.Println("Received:", string(buf[:n]))
-			return unpackageErr
-		}
-		//fmt.Println("Received:", unpackateData)
-		extendFunc.MessageBox("提示", unpackateData)
-	} else {
-		//fmt.Println("No response received.")
+	if n == 0 {
 		extendFunc.MessageBox("提示", "No response received.")
+		return result, errors.New("no response received")
 	}
-
-	return nil
+	unpackateData, isUnpackage, unpackageErr := extendFunc.UnpackageData(buf[:n], "#")
+	if !isUnpackage {
+		return result, fmt.Errorf("解析网卡配置服务的回应失败: %v（收到 %q）", unpackageErr, buf[:n])
+	}
+	if err := json.Unmarshal([]byte(unpackateData), &result); err != nil {
+		extendFunc.MessageBox("提示", unpackateData)
+		return result, err
+	}
+	return result, nil
 }
 
 // 验证配置的合法性
@@ -138,96 +153,7 @@ func validateNetConfig(cfg NetConfig) error {
 	if err := checkAdapterExistence(cfg.Adapter); err != nil {
 		return err
 	}
-	if !cfg.DHCP && cfg.DNSdhcp {
-		return errors.New("非法配置：静态 IP 模式下不能使用 DNS DHCP")
-	}
-
-	if net.ParseIP(cfg.IP) == nil && !cfg.DHCP {
-		return fmt.Errorf("无效 IP 地址: %s", cfg.IP)
-	}
-
-	if net.ParseIP(cfg.Netmask) == nil && !cfg.DHCP {
-		return fmt.Errorf("无效子网掩码: %s", cfg.Netmask)
-	}
-
-	if net.ParseIP(cfg.Gateway) == nil && !cfg.DHCP {
-		return fmt.Errorf("无效网关: %s", cfg.Gateway)
-	}
-
-	for _, dns := range cfg.DNS {
-		if net.ParseIP(dns) == nil {
-			return fmt.Errorf("无效 DNS: %s", dns)
-		}
-	}
-
-	if cfg.MTU < 576 || cfg.MTU > 9000 {
-		return fmt.Errorf("MTU 不在合理范围: %d", cfg.MTU)
-	}
-
-	return nil
-}
-
-// 应用 DHCP 设置
-func applyDHCP(adapter string) error {
-	cmd := exec.Command("netsh", "interface", "ip", "set", "address", "name="+adapter, "source=dhcp")
-	err := cmd.Run()
-
-	if err != nil {
-		exec.Command("powershell", "-Command", "Disable-NetAdapter -Name '"+adapter+"' -Confirm:$false; Start-Sleep -Seconds 1; Enable-NetAdapter -Name '"+adapter+"' -Confirm:$false").Run()
-		exec.Command("ipconfig", "/release").Run()
-		exec.Command("ipconfig", "/renew").Run()
-		return err
-	}
-	exec.Command("powershell", "-Command", "Disable-NetAdapter -Name '"+adapter+"' -Confirm:$false; Start-Sleep -Seconds 1; Enable-NetAdapter -Name '"+adapter+"' -Confirm:$false").Run()
-	exec.Command("ipconfig", "/release").Run()
-	exec.Command("ipconfig", "/renew").Run()
-	return nil
-}
-
-// 应用 DHCP DNS 设置
-func applyDNSDHCP(adapter string) error {
-	cmd := exec.Command("netsh", "interface", "ip", "set", "dns", "name="+adapter, "source=dhcp")
-	return cmd.Run()
-}
-
-// 应用静态 DNS 设置
-func applyStaticDNS(adapter string, dns []string) error {
-	cmd := exec.Command("netsh", "interface", "ip", "set", "dns", "name="+adapter, "static", dns[0])
-	err := cmd.Run()
-	if err != nil {
-		return err
-	}
-	for i := 1; i < len(dns); i++ {
-		cmd := exec.Command("netsh", "interface", "ip", "add", "dns", "name="+adapter, dns[i], "index="+strconv.Itoa(i+1))
-		if err := cmd.Run(); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-// 应用静态 IP 配置
-func applyStaticIP(adapter, ip, netmask, gateway string) error {
-	cmd := exec.Command("netsh", "interface", "ip", "set", "address", "name="+adapter, "static", ip, netmask, gateway)
-	return cmd.Run()
-}
-
-// 应用 MTU 设置
-func applyMTU(adapter string, mtu int) error {
-	cmd := exec.Command("netsh", "interface", "ip", "set", "interface", "name="+adapter, "mtu="+strconv.Itoa(mtu))
-	return cmd.Run()
-}
-
-// 应用 Metric 设置
-func applyMetric(adapter string, metric int) error {
-	cmd := exec.Command("netsh", "interface", "ip", "set", "interface", "name="+adapter, "metric="+strconv.Itoa(metric))
-	return cmd.Run()
-}
-
-// 刷新 DNS 缓存
-func flushDNSCache() error {
-	cmd := exec.Command("ipconfig", "/flushdns")
-	return cmd.Run()
+	return cfg.Validate()
 }
 
 //	辅助函数
@@ -272,14 +198,42 @@ func checkAdapterExistence(adapter string) error {
 	return fmt.Errorf("网卡 %s 不存在", adapter)
 }
 
-// 获取指定网卡当前配置信息
-func getAdapterConfig(adapter string) (NetConfig, error) {
-	// 检查网卡是否存在
+// 读取网卡当前配置，测试时可替换为 netcfg.FakeReader
+var adapterReader netcfg.Reader
+
+func init() {
+	reader, _ := netcfg.NewReader(netcfg.DefaultKind(), netcfg.ExecRunner{})
+	adapterReader = localAdapterReader{reader}
+}
+
+// 读取前先检查网卡是否存在，避免把系统命令的报错直接交给用户
+type localAdapterReader struct {
+	netcfg.Reader
+}
+
+func (r localAdapterReader) Read(adapter string) (NetConfig, error) {
 	if err := checkAdapterExistence(adapter); err != nil {
 		return NetConfig{}, err
 	}
-	var cfg NetConfig
-	var err error
+	return r.Reader.Read(adapter)
+}
+
+// 获取指定网卡当前配置信息
+func getAdapterConfig(adapter string) (NetConfig, error) {
+	return adapterReader.Read(adapter)
+}
 
-	return cfg, err
+// SaveCurrentAsProfile 读取网卡当前配置，以 name 为配置名追加到配置文件 path
+func SaveCurrentAsProfile(path, adapter, name string) (NetConfig, error) {
+	cfg, err := getAdapterConfig(adapter)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Name = name
+	cfg.Desc = "读取自网卡 " + adapter + " 当前配置"
+	configs, err := LoadConfigFromFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return cfg, err
+	}
+	return cfg, SaveConfigToFile(path, append(configs, cfg))
 }
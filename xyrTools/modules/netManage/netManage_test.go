@@ -0,0 +1,57 @@
+package netManage
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"myMod/netcfg"
+)
+
+func TestSaveCurrentAsProfile(t *testing.T) {
+	old := adapterReader
+	defer func() { adapterReader = old }()
+	current := netcfg.Profile{
+		IP:          "192.168.1.10",
+		Netmask:     "255.255.255.0",
+		Gateway:     "192.168.1.1",
+		DNS:         []string{"223.5.5.5"},
+		MTU:         1500,
+		Addresses:   []string{"10.10.0.5/24"},
+		IPv6Mode:    netcfg.IPv6Static,
+		IPv6:        []string{"2001:db8::10/64"},
+		IPv6Gateway: "2001:db8::1",
+		IPv6DNS:     []string{"2001:db8::53"},
+		Routes:      []netcfg.Route{{Dest: "10.0.0.0/8", Gateway: "192.168.1.254", Metric: 10}},
+	}
+	adapterReader = netcfg.FakeReader{"eth-test": current}
+
+	path := filepath.Join(t.TempDir(), "netConfig.yaml")
+	if _, err := SaveCurrentAsProfile(path, "eth-test", "当前配置"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SaveCurrentAsProfile(path, "eth-test", "当前配置2"); err != nil {
+		t.Fatal(err)
+	}
+	configs, err := LoadConfigFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != 2 || configs[1].Name != "当前配置2" {
+		t.Fatalf("configs = %+v", configs)
+	}
+	// 保存后读回的配置方案与读取到的当前配置一致，IPv6 和静态路由不丢失
+	got := configs[0]
+	want := current
+	want.Name, want.Desc, want.Adapter = "当前配置", got.Desc, "eth-test"
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("saved profile = %+v\nwant %+v", got, want)
+	}
+	if err := got.Validate(); err != nil {
+		t.Fatalf("saved profile invalid: %v", err)
+	}
+
+	if _, err := SaveCurrentAsProfile(path, "no-such-adapter", "x"); err == nil {
+		t.Fatal("expected error for unknown adapter")
+	}
+}
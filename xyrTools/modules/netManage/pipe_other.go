@@ -0,0 +1,14 @@
+//go:build !windows
+
+package netManage
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// 网卡配置服务（netSetService）仅支持 Windows
+func dialNetService(timeout time.Duration) (net.Conn, error) {
+	return nil, errors.New("网卡配置服务仅支持 Windows")
+}
@@ -0,0 +1,16 @@
+package netManage
+
+import (
+	"net"
+	"time"
+
+	"github.com/Microsoft/go-winio"
+)
+
+// 网卡配置服务监听的命名管道
+const netCfgPipePath = `\\.\pipe\netCfgPipe`
+
+// 连接网卡配置服务
+func dialNetService(timeout time.Duration) (net.Conn, error) {
+	return winio.DialPipe(netCfgPipePath, &timeout)
+}
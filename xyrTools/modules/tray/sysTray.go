@@ -1,3 +1,5 @@
+//go:build windows
+
 // 托盘模块，整个系统的托盘模块，用于管理系统托盘图标
 package sysTray
 
@@ -6,8 +8,10 @@ import (
 	"context"
 	"crypto/sha256"
 	_ "embed"
+	"errors"
 	"fmt"
 	"image/png"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -15,9 +19,10 @@ import (
 	"sync"
 	"syscall"
 	"time"
-	"xyrTools/xyrTools/extendFunc"
 	"xyrTools/xyrTools/modInterfaces"
+	memopt "xyrTools/xyrTools/modules/memoryOptimizer"
 	"xyrTools/xyrTools/modules/netManage"
+	"xyrTools/xyrTools/registry"
 
 	"github.com/gen2brain/beeep"
 
@@ -46,19 +51,31 @@ type SysTrayModule struct {
 	wg             sync.WaitGroup
 	netConfigItems []*systray.MenuItem
 	cancelFuncs    []context.CancelFunc
+	netCfgSub      modInterfaces.Subscription // 网卡配置变更事件订阅
+}
+
+// 网卡配置文件变动事件数据（sysTray:netCfgChanged）
+type NetCfgChanged struct {
+	Path string // 变动的配置文件
 }
 
-// 图标路径、网络配置文件路径、子菜单处理函数映射
+var topicNetCfgChanged = modInterfaces.DeclareEvent[NetCfgChanged]("sysTray:netCfgChanged", "网卡配置文件变动，托盘重新加载网卡配置菜单")
+
+// 系统托盘模块配置，目前除 enabled 外没有其他配置项
+type sysTrayConfig struct{}
+
+// 图标路径、网络配置文件路径
 var (
-	iconPath    string
-	netCfgPath  string
-	subHandlers = make(map[string]func(modInterfaces.Event))
+	iconPath   string
+	netCfgPath string
 )
 
+func init() {
+	registry.Register("sysTray", New)
+}
+
 func New() modInterfaces.Module {
-	return &SysTrayModule{
-		stopCh: make(chan struct{}),
-	}
+	return &SysTrayModule{}
 }
 
 func (s *SysTrayModule) ID() string          { return "systray" }
@@ -67,8 +84,23 @@ func (s *SysTrayModule) Description() string { return "托盘模块" }
 func (s *SysTrayModule) Version() string     { return "1.0.0" }
 func (s *SysTrayModule) Author() string      { return "小鱼" }
 
+// 事件声明，供事件总线访问控制使用
+func (s *SysTrayModule) PublishesEvents() []string {
+	return []string{
+		topicNetCfgChanged.Name(),
+		memopt.TopicOptimize.Name(),
+		memopt.TopicOptimizeByNames.Name(),
+		modInterfaces.TopicShutdownRequested.Name(),
+	}
+}
+func (s *SysTrayModule) SubscribesEvents() []string { return []string{topicNetCfgChanged.Name()} }
+
 // 初始化 SysTray 模块
 func (s *SysTrayModule) Init(ctx modInterfaces.Context) error {
+	var cfg sysTrayConfig
+	if err := ctx.DecodeConfig(&cfg); err != nil {
+		return err
+	}
 	s.ctx = ctx
 	s.ctx.Log("info", "系统托盘模块已初始化")
 	return nil
@@ -77,6 +109,7 @@ func (s *SysTrayModule) Init(ctx modInterfaces.Context) error {
 // 启动系统托盘
 func (s *SysTrayModule) Start() error {
 	s.ctx.Log("info", "SysTray 模块启动中")
+	s.stopCh = make(chan struct{})
 
 	projectDir, err := os.Getwd()
 	if err != nil {
@@ -85,21 +118,32 @@ func (s *SysTrayModule) Start() error {
 	iconPath = filepath.Join(projectDir, "icon", "cat.png")
 	netCfgPath = filepath.Join(projectDir, "config", "netConfig.yaml")
 
-	go func() {
+	s.status.Running = true
+	s.status.StartTime = time.Now()
+	s.ctx.Go(func() {
 		runtime.LockOSThread()
 		s.ctx.Log("info", "启动系统托盘模块")
 		s.startSysTray()
 		runtime.UnlockOSThread()
-	}()
+	})
 	return nil
 }
 
 // 停止系统托盘
-func (s *SysTrayModule) Stop() error {
+func (s *SysTrayModule) Stop(ctx context.Context) error {
+	if !s.status.Running {
+		return nil
+	}
 	s.status.Running = false
+	s.status.EndTime = time.Now()
 	close(s.stopCh)
-	s.wg.Wait()
-	return nil
+	if s.netCfgSub != nil {
+		s.netCfgSub.Cancel()
+		s.netCfgSub = nil
+	}
+	// 退出托盘消息循环，移除托盘图标
+	systray.Quit()
+	return modInterfaces.WaitContext(ctx, &s.wg)
 }
 
 // 获取模块当前状态
@@ -107,11 +151,24 @@ func (s *SysTrayModule) Status() modInterfaces.ModuleStatus {
 	return s.status
 }
 
+// 获取模块配置说明
+func (s *SysTrayModule) ConfigSchema() []modInterfaces.ConfigField {
+	return modInterfaces.ConfigSchema(sysTrayConfig{})
+}
+
 // 重载模块
-func (s *SysTrayModule) Reload() error {
+func (s *SysTrayModule) Reload(ctx modInterfaces.Context) error {
+	var cfg sysTrayConfig
+	if err := ctx.DecodeConfig(&cfg); err != nil {
+		return err
+	}
+	s.ctx = ctx
 	s.ctx.Log("info", "系统托盘模块重新加载")
-	_ = s.Stop()
-	s.stopCh = make(chan struct{})
+	// 未运行时只更新配置，下次启动生效
+	if !s.status.Running {
+		return nil
+	}
+	_ = s.Stop(context.Background())
 	return s.Start()
 }
 
@@ -131,6 +188,7 @@ func (s *SysTrayModule) onReady() {
 	netMenu := systray.AddMenuItem("网络配置", "打开网络配置面板")
 	localNetMenu := systray.AddMenuItem("适配器管理", "本地适配器设置")
 	netSwitchMenu := systray.AddMenuItem("切换配置", "应用预设网络配置")
+	netSaveMenu := systray.AddMenuItem("保存当前配置", "将网卡当前配置保存为新的网络配置")
 	memoptThisMenu := systray.AddMenuItem("优化本进程内存", "运行内存优化任务")
 	systray.AddSeparator()
 	memOptMenu := systray.AddMenuItem("内存优化", "释放内存资源")
@@ -144,6 +202,7 @@ func (s *SysTrayModule) onReady() {
 	s.bindMenuEvents(netMenu, localNetMenu, infoMenu, memOptMenu, openConsole, exitSys, memoptThisMenu)
 	// 动态加载网络配置子菜单
 	s.loadNetConfigs(netSwitchMenu)
+	s.loadAdapterItems(netSaveMenu)
 
 	// 订阅配置更新事件
 	s.subscribeNetCfgChange(netSwitchMenu)
@@ -156,7 +215,7 @@ func (s *SysTrayModule) onReady() {
 	netCfgPath := filepath.Join(projectDir, "config", "netConfig.yaml")
 	go s.watchConfigFile([]string{netCfgPath}, func(path string) {
 		s.ctx.Log("info", fmt.Sprintf("配置文件 %s 变动，触发菜单更新", path))
-		s.ctx.Events.Publish("sysTray:netCfgChanged", "netCfgChanged")
+		modInterfaces.Publish(s.ctx.Events, topicNetCfgChanged, NetCfgChanged{Path: path})
 	})
 }
 
@@ -190,20 +249,57 @@ func (s *SysTrayModule) loadNetConfigs(parent *systray.MenuItem) {
 
 		ctx, cancel := context.WithCancel(context.Background())
 		s.cancelFuncs = append(s.cancelFuncs, cancel)
-		go func(c netManage.NetConfig, m *systray.MenuItem, ctx context.Context) {
+		s.ctx.Go(func() {
 			for {
 				select {
 				case <-ctx.Done():
 					return
-				case <-m.ClickedCh:
-					s.ctx.Log("info", "应用配置: "+c.Name)
+				case <-item.ClickedCh:
+					s.ctx.Log("info", "应用配置: "+cfg.Name)
+					if plan, err := netManage.PreviewNetConfig(cfg); err == nil {
+						s.ctx.Log("debug", "执行计划:\n"+plan)
+					}
 					err := netManage.ApplyNetConfig(cfg)
 					if err != nil {
 						s.ctx.Log("error", "应用配置失败: "+err.Error())
 					}
 				}
 			}
-		}(cfg, item, ctx)
+		})
+	}
+}
+
+// 显示网卡子菜单，点击后将该网卡当前配置保存到网络配置文件
+func (s *SysTrayModule) loadAdapterItems(parent *systray.MenuItem) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		s.ctx.Log("error", "获取网卡列表失败: "+err.Error())
+		return
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		adapter := iface.Name
+		item := parent.AddSubMenuItem(adapter, fmt.Sprintf("保存 %s 当前配置", adapter))
+		s.ctx.Go(func() {
+			for {
+				select {
+				case <-s.stopCh:
+					return
+				case <-item.ClickedCh:
+					name := fmt.Sprintf("%s %s", adapter, time.Now().Format("2006-01-02 15:04"))
+					// 保存后配置文件变动，菜单随之刷新
+					if _, err := netManage.SaveCurrentAsProfile(netCfgPath, adapter, name); err != nil {
+						s.ctx.Log("error", "保存当前配置失败: "+err.Error())
+						notify.NotifyError(err, "保存当前配置失败")
+						continue
+					}
+					s.ctx.Log("info", "已保存当前配置: "+name)
+					notify.NotifyInfo("已保存当前配置：" + name)
+				}
+			}
+		})
 	}
 }
 
@@ -220,20 +316,23 @@ func (s *SysTrayModule) clearSubMenuItems() {
 
 }
 
+// 订阅网卡配置变更事件，每次变更都重新加载子菜单，托盘停止时取消订阅
 func (s *SysTrayModule) subscribeNetCfgChange(parent *systray.MenuItem) {
-	handler := func(evt modInterfaces.Event) {
-		s.ctx.Log("info", fmt.Sprintf("配置变更事件: %v", evt.Data))
-		s.loadNetConfigs(parent)
-		s.ctx.Events.Unsubscribe("sysTray:netCfgChanged", subHandlers["netCfg"])
+	if s.netCfgSub != nil {
+		s.netCfgSub.Cancel()
 	}
-	subHandlers["netCfg"] = handler
-	s.ctx.Events.Subscribe("sysTray:netCfgChanged", handler)
+	s.netCfgSub = modInterfaces.Subscribe(s.ctx.Events, topicNetCfgChanged, func(change NetCfgChanged, _ modInterfaces.Event) {
+		s.ctx.Log("info", "网卡配置变更: "+change.Path)
+		s.loadNetConfigs(parent)
+	})
 }
 
 func (s *SysTrayModule) bindMenuEvents(net, local, info, mem, openConsole, exitOs, memoptThis *systray.MenuItem) {
-	go func() {
+	s.ctx.Go(func() {
 		for {
 			select {
+			case <-s.stopCh:
+				return
 			case <-net.ClickedCh:
 				s.openNetworkConfigWindow()
 			case <-local.ClickedCh:
@@ -241,19 +340,23 @@ func (s *SysTrayModule) bindMenuEvents(net, local, info, mem, openConsole, exitO
 			case <-info.ClickedCh:
 				s.showSystemInfo()
 			case <-mem.ClickedCh:
-				s.ctx.Events.Publish("memory:optimized", "运行内存优化任务")
+				s.requestOptimize(func(ctx context.Context) (memopt.OptimizeResult, error) {
+					return modInterfaces.Request(ctx, s.ctx.Events, memopt.TopicOptimize, memopt.OptimizeRequest{Source: s.ID()})
+				})
 			case <-openConsole.ClickedCh:
 				//consoleutil.CreateConsole()
 				notify.NotifyInfo("控制台待开发！")
 			case <-exitOs.ClickedCh:
-				extendFunc.RemoveLockFile()
-				os.Exit(0)
+				// 由核心引擎停止所有模块后退出，锁文件由主程序最后删除
+				s.ctx.RequestShutdown("sysTray", "托盘菜单退出系统")
 			case <-memoptThis.ClickedCh:
-				processes := []string{"xyrTools.exe"}
-				s.ctx.Events.Publish("memory:optimizeByNames", processes)
+				req := memopt.OptimizeByNamesRequest{Source: s.ID(), Names: []string{"xyrTools.exe"}}
+				s.requestOptimize(func(ctx context.Context) (memopt.OptimizeResult, error) {
+					return modInterfaces.Request(ctx, s.ctx.Events, memopt.TopicOptimizeByNames, req)
+				})
 			}
 		}
-	}()
+	})
 }
 
 // 打开网络连接属性窗口
@@ -297,7 +400,7 @@ func (s *SysTrayModule) openNcpa() {
 
 // 显示系统基本信息
 func (s *SysTrayModule) showSystemInfo() {
-	go func() {
+	s.ctx.Go(func() {
 		s.ctx.Log("info", "显示系统基本信息")
 
 		// 收集数据
@@ -343,7 +446,25 @@ func (s *SysTrayModule) showSystemInfo() {
 		if err != nil {
 			s.ctx.Log("error", "发送系统通知失败: "+err.Error())
 		}
-	}()
+	})
+}
+
+// 请求内存优化模块执行优化，并以系统通知显示结果
+func (s *SysTrayModule) requestOptimize(request func(ctx context.Context) (memopt.OptimizeResult, error)) {
+	s.ctx.Go(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		reply, err := request(ctx)
+		switch {
+		case errors.Is(err, modInterfaces.ErrNoHandler):
+			notify.NotifyInfo("内存优化模块未启动")
+		case err != nil:
+			s.ctx.Log("error", "内存优化失败: "+err.Error())
+			notify.NotifyError(err, "内存优化失败")
+		default:
+			notify.NotifyInfo(fmt.Sprintf("内存优化完成：%v", reply))
+		}
+	})
 }
 
 // 打开网络管理窗口
@@ -397,7 +518,7 @@ func (s *SysTrayModule) watchConfigFile(filePaths []string, onChange func(path s
 		}
 	}
 
-	go func() {
+	s.ctx.Go(func() {
 		defer watcher.Close()
 		for {
 			select {
@@ -421,7 +542,7 @@ func (s *SysTrayModule) watchConfigFile(filePaths []string, onChange func(path s
 				_ = err // 可记录日志
 			}
 		}
-	}()
+	})
 
 	return nil
 }
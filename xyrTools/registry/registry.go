@@ -0,0 +1,59 @@
+// 模块注册表，各模块在 init() 中注册自己的工厂函数，
+// 平台相关的模块通过构建标签（build tags）决定是否编译进当前系统的程序。
+package registry
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+
+	"xyrTools/xyrTools/modInterfaces"
+)
+
+// 模块工厂函数
+type Factory func() modInterfaces.Module
+
+var (
+	factories = make(map[string]Factory)
+	lock      sync.RWMutex
+)
+
+// Register 注册模块工厂，模块名与配置文件中的模块名一致。
+// 只应在模块包的 init() 中调用，重复注册视为编程错误直接 panic。
+func Register(id string, factory Factory) {
+	lock.Lock()
+	defer lock.Unlock()
+	if factory == nil {
+		panic(fmt.Sprintf("registry: factory for module %s is nil", id))
+	}
+	if _, exists := factories[id]; exists {
+		panic(fmt.Sprintf("registry: module %s registered twice", id))
+	}
+	factories[id] = factory
+}
+
+// Lookup 查找模块工厂
+func Lookup(id string) (Factory, bool) {
+	lock.RLock()
+	defer lock.RUnlock()
+	factory, ok := factories[id]
+	return factory, ok
+}
+
+// Available 返回当前系统可用的模块名（已排序）
+func Available() []string {
+	lock.RLock()
+	defer lock.RUnlock()
+	ids := make([]string, 0, len(factories))
+	for id := range factories {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// OS 返回当前程序编译的目标系统，即 Available 结果对应的系统
+func OS() string {
+	return runtime.GOOS
+}